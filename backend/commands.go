@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/actions"
+	reporttypes "github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types/report_types"
+)
+
+// subcommands act on the JSON report of an earlier scan, or on files it moved, instead of scanning.
+// Each returns the exit code of the program.
+var subcommands = map[string]func(args []string) int{
+	"delete":       runDeleteCommand,
+	"link":         runLinkCommand,
+	"dedupe":       runDedupeCommand,
+	"quarantine":   runQuarantineCommand,
+	"restore":      runRestoreCommand,
+	"mark":         runMarkCommand,
+	"script":       runScriptCommand,
+	"ignore":       runIgnoreCommand,
+	"verify-audit": runVerifyAuditCommand,
+}
+
+// loadReport reads a report written with --json, from standard input if path is "-".
+func loadReport(path string) (reporttypes.ReportOutput, error) {
+	var report reporttypes.ReportOutput
+	var input io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return report, err
+		}
+		defer file.Close()
+		input = file
+	}
+	if err := json.NewDecoder(input).Decode(&report); err != nil {
+		return report, fmt.Errorf("reading report %s: %w", path, err)
+	}
+	return report, nil
+}
+
+// enableAuditLog opens the audit log the actions record every operation in before a command
+// touches any file. It prints the error and returns false if the log can't be written, so the
+// command stops with one error instead of having each of its operations refused.
+func enableAuditLog() bool {
+	log, err := actions.OpenAuditLogFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: can't write the audit log: %s\n", err.Error())
+		return false
+	}
+	actions.SetAuditLog(log)
+	return true
+}
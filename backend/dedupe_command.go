@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/actions"
+)
+
+// runDedupeCommand makes the copies of every file set in a report share their extents.
+func runDedupeCommand(args []string) int {
+	var reportPath string
+	var options actions.DedupeOptions
+
+	for _, arg := range args {
+		if name, value, found := strings.Cut(arg, "="); found && strings.HasPrefix(name, "--") {
+			switch name {
+			case "--report":
+				reportPath = value
+			default:
+				fmt.Fprintf(os.Stderr, "Error: unknown option %s\n", name)
+				return 1
+			}
+			continue
+		}
+
+		switch arg {
+		case "--dry-run", "-n":
+			options.DryRun = true
+		case "--help", "-h":
+			printDedupeUsage()
+			return 0
+		default:
+			fmt.Fprintf(os.Stderr, "Error: unknown option %s\n", arg)
+			return 1
+		}
+	}
+
+	if reportPath == "" {
+		printDedupeUsage()
+		return 1
+	}
+
+	if !options.DryRun && !enableAuditLog() {
+		return 1
+	}
+	report, err := loadReport(reportPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+		return 1
+	}
+
+	exitCode := 0
+	var totalReclaimed int64
+	for i, result := range actions.Dedupe(actions.FileSetsFromReport(report), options) {
+		totalReclaimed += result.ReclaimedBytes
+		if allUnsupported(result.Outcomes) {
+			fmt.Printf("Set %d: %s (unsupported)\n", i+1, result.Source)
+			exitCode = 1
+			continue
+		}
+		fmt.Printf("Set %d: %s (%d bytes reclaimed)\n", i+1, result.Source, result.ReclaimedBytes)
+		for _, outcome := range result.Outcomes {
+			switch {
+			case outcome.Err != nil:
+				fmt.Printf("  Skipped %s: %s\n", outcome.Path, outcome.Err.Error())
+				exitCode = 1
+			case options.DryRun:
+				fmt.Printf("  Would share %s\n", outcome.Path)
+			default:
+				fmt.Printf("  Shared %s\n", outcome.Path)
+			}
+		}
+	}
+	if options.DryRun {
+		fmt.Printf("\nUp to %d bytes could be reclaimed\n", totalReclaimed)
+	} else {
+		fmt.Printf("\nTotal reclaimed: %d bytes\n", totalReclaimed)
+	}
+	return exitCode
+}
+
+// allUnsupported reports whether the filesystem refused every copy of a set.
+func allUnsupported(outcomes []actions.Outcome) bool {
+	for _, outcome := range outcomes {
+		if outcome.Err != actions.ErrDedupeUnsupported {
+			return false
+		}
+	}
+	return len(outcomes) > 0
+}
+
+func printDedupeUsage() {
+	fmt.Printf(`Usage: %s dedupe --report=FILE [OPTIONS]
+
+Makes the copies of every file set in a report written with --json share their data on
+copy-on-write filesystems (btrfs, XFS) using the FIDEDUPERANGE ioctl. The kernel verifies
+the bytes first; paths, metadata and content stay unchanged. Other filesystems, such as
+ext4 or tmpfs, report the copies as unsupported.
+
+OPTIONS:
+      --report=FILE  Report of the scan ("-" reads it from standard input)
+  -n, --dry-run      Show how much could be reclaimed without changing anything
+  -h, --help         Show this help message
+`, os.Args[0])
+}
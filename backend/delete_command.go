@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/actions"
+)
+
+// runDeleteCommand removes paths listed in a report, keeping at least one copy of every set.
+func runDeleteCommand(args []string) int {
+	var reportPath string
+	var paths []string
+	marked := false
+	options := actions.Options{Method: actions.MethodDelete}
+
+	for _, arg := range args {
+		if name, value, found := strings.Cut(arg, "="); found && strings.HasPrefix(name, "--") {
+			switch name {
+			case "--report":
+				reportPath = value
+			default:
+				fmt.Fprintf(os.Stderr, "Error: unknown option %s\n", name)
+				return 1
+			}
+			continue
+		}
+
+		switch arg {
+		case "--trash":
+			options.Method = actions.MethodTrash
+		case "--marked":
+			marked = true
+		case "--dry-run", "-n":
+			options.DryRun = true
+		case "--help", "-h":
+			printDeleteUsage()
+			return 0
+		default:
+			if strings.HasPrefix(arg, "-") {
+				fmt.Fprintf(os.Stderr, "Error: unknown option %s\n", arg)
+				return 1
+			}
+			paths = append(paths, arg)
+		}
+	}
+
+	if reportPath == "" || len(paths) == 0 && !marked {
+		printDeleteUsage()
+		return 1
+	}
+
+	if !options.DryRun && !enableAuditLog() {
+		return 1
+	}
+	report, err := loadReport(reportPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+		return 1
+	}
+
+	if len(paths) == 0 {
+		// Only explicit marks select files for permanent removal.
+		paths = actions.MarkedPaths(report)
+		if len(paths) == 0 {
+			fmt.Println("No files are marked for removal in the report (see --keep and mark)")
+			return 0
+		}
+	}
+
+	exitCode := 0
+	for _, outcome := range actions.RemoveFromReport(report, paths, options) {
+		switch {
+		case outcome.Err != nil:
+			fmt.Printf("Skipped %s: %s\n", outcome.Path, outcome.Err.Error())
+			exitCode = 1
+		case options.DryRun && options.Method == actions.MethodTrash:
+			fmt.Printf("Would move to trash: %s\n", outcome.Path)
+		case options.DryRun:
+			fmt.Printf("Would delete: %s\n", outcome.Path)
+		case options.Method == actions.MethodTrash:
+			fmt.Printf("Moved to trash: %s -> %s\n", outcome.Path, outcome.Destination)
+		default:
+			fmt.Printf("Deleted: %s\n", outcome.Path)
+		}
+	}
+	return exitCode
+}
+
+func printDeleteUsage() {
+	fmt.Printf(`Usage: %[1]s delete --report=FILE [OPTIONS] <path>...
+       %[1]s delete --report=FILE --marked [OPTIONS]
+
+Removes duplicate copies listed in a report written with --json. Every path must belong
+to a duplicate set, and at least one copy of each set is always kept on disk. Sets whose
+copies changed since the scan (size, modification time or content) are skipped. Each
+removal is recorded in the audit log (see "%[1]s verify-audit --help").
+
+OPTIONS:
+      --report=FILE  Report of the scan ("-" reads it from standard input)
+      --trash        Move to the freedesktop.org trash (Linux, BSD) instead of deleting permanently
+      --marked       Remove the files the report marks for removal (see --keep and mark);
+                     sets without marks are left alone
+  -n, --dry-run      Show what would be removed without changing anything
+  -h, --help         Show this help message
+`, os.Args[0])
+}
@@ -9,4 +9,4 @@ require (
 	github.com/cespare/xxhash/v2 v2.3.0
 )
 
-require golang.org/x/sync v0.16.0 // indirect
+require golang.org/x/sync v0.16.0
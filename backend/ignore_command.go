@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/ignore"
+	reporttypes "github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types/report_types"
+)
+
+// runIgnoreCommand adds, lists or removes entries of the ignore database, whose sets are left out of reports.
+func runIgnoreCommand(args []string) int {
+	if len(args) == 0 {
+		printIgnoreUsage()
+		return 1
+	}
+	action := args[0]
+	if action == "--help" || action == "-h" {
+		printIgnoreUsage()
+		return 0
+	}
+	if action != "add" && action != "list" && action != "remove" {
+		fmt.Fprintf(os.Stderr, "Error: unknown ignore action %s\n", action)
+		return 1
+	}
+
+	var databasePath, reportPath, pattern, note string
+	var values []string
+	jsonMode := false
+	for _, arg := range args[1:] {
+		if name, value, found := strings.Cut(arg, "="); found && strings.HasPrefix(name, "--") {
+			switch name {
+			case "--db":
+				databasePath = value
+			case "--report":
+				reportPath = value
+			case "--pattern":
+				pattern = value
+			case "--note":
+				note = value
+			default:
+				fmt.Fprintf(os.Stderr, "Error: unknown option %s\n", name)
+				return 1
+			}
+			continue
+		}
+
+		switch arg {
+		case "--json", "-j":
+			jsonMode = true
+		case "--help", "-h":
+			printIgnoreUsage()
+			return 0
+		default:
+			if strings.HasPrefix(arg, "-") {
+				fmt.Fprintf(os.Stderr, "Error: unknown option %s\n", arg)
+				return 1
+			}
+			values = append(values, arg)
+		}
+	}
+
+	if databasePath == "" {
+		path, err := ignore.DefaultPath()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+			return 1
+		}
+		databasePath = path
+	}
+	database, err := ignore.Load(databasePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+		return 1
+	}
+
+	switch action {
+	case "list":
+		return listIgnoreEntries(database, jsonMode)
+	case "remove":
+		if len(values) == 0 {
+			printIgnoreUsage()
+			return 1
+		}
+		exitCode := 0
+		for _, value := range database.Remove(values) {
+			fmt.Fprintf(os.Stderr, "Error: no entry matches %s\n", value)
+			exitCode = 1
+		}
+		if err := database.Save(databasePath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+			return 1
+		}
+		return exitCode
+	}
+
+	entries, err := ignoreEntriesToAdd(reportPath, values, pattern, note)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+		return 1
+	}
+	if len(entries) == 0 {
+		printIgnoreUsage()
+		return 1
+	}
+	for _, entry := range entries {
+		added, err := database.Add(entry)
+		switch {
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+			return 1
+		case added:
+			fmt.Printf("Ignoring %s\n", describeIgnoreEntry(entry))
+		default:
+			fmt.Printf("Already ignored: %s\n", describeIgnoreEntry(entry))
+		}
+	}
+	if err := database.Save(databasePath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+		return 1
+	}
+	return 0
+}
+
+// ignoreEntriesToAdd builds the entries of "ignore add". With a report, every file and folder set
+// of it is added, or only those whose hash or signature is listed in hashes; the note defaults to
+// the first path of each set. Without a report, one entry is added per hash, or a single one for
+// the pattern. The pattern applies to every entry.
+func ignoreEntriesToAdd(reportPath string, hashes []string, pattern string, note string) ([]ignore.Entry, error) {
+	if reportPath == "" {
+		var entries []ignore.Entry
+		for _, hash := range hashes {
+			entries = append(entries, ignore.Entry{Hash: hash, Pattern: pattern, Note: note})
+		}
+		if len(hashes) == 0 && pattern != "" {
+			entries = append(entries, ignore.Entry{Pattern: pattern, Note: note})
+		}
+		return entries, nil
+	}
+
+	report, err := loadReport(reportPath)
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]bool)
+	for _, hash := range hashes {
+		wanted[hash] = true
+	}
+	found := make(map[string]bool)
+	var entries []ignore.Entry
+	addSet := func(hash string, paths []string) {
+		if len(wanted) > 0 && !wanted[hash] {
+			return
+		}
+		found[hash] = true
+		entry := ignore.Entry{Hash: hash, Pattern: pattern, Note: note}
+		if entry.Note == "" && len(paths) > 0 {
+			entry.Note = paths[0]
+		}
+		entries = append(entries, entry)
+	}
+	for _, set := range report.FileDuplicates {
+		addSet(set.Hash, set.Paths)
+	}
+	for _, set := range report.FolderDuplicates {
+		addSet(set.Signature, set.Paths)
+	}
+	for _, hash := range hashes {
+		if !found[hash] {
+			return nil, fmt.Errorf("no set with hash %s in %s", hash, reportPath)
+		}
+	}
+	return entries, nil
+}
+
+// listIgnoreEntries prints the entries of the database, numbered for "ignore remove".
+func listIgnoreEntries(database *ignore.Database, jsonMode bool) int {
+	if jsonMode {
+		data, err := json.MarshalIndent(database, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+			return 1
+		}
+		fmt.Println(string(data))
+		return 0
+	}
+	if len(database.Entries) == 0 {
+		fmt.Println("The ignore database is empty.")
+		return 0
+	}
+	for i, entry := range database.Entries {
+		fmt.Printf("%d. %s (added %s)\n", i+1, describeIgnoreEntry(entry), entry.AddedAt.Format("2006-01-02 15:04"))
+		if entry.Note != "" {
+			fmt.Printf("   %s\n", entry.Note)
+		}
+	}
+	return 0
+}
+
+// describeIgnoreEntry returns the hash and pattern of an entry.
+func describeIgnoreEntry(entry ignore.Entry) string {
+	var parts []string
+	if entry.Hash != "" {
+		parts = append(parts, "hash "+entry.Hash)
+	}
+	if entry.Pattern != "" {
+		parts = append(parts, fmt.Sprintf("paths matching %q", entry.Pattern))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// loadIgnoreDatabase loads the ignore database applied to scans: the one at path, or the default
+// one if path is empty.
+func loadIgnoreDatabase(path string) (*ignore.Database, error) {
+	if path == "" {
+		defaultPath, err := ignore.DefaultPath()
+		if err != nil {
+			return nil, err
+		}
+		path = defaultPath
+	}
+	return ignore.Load(path)
+}
+
+// suppressedSummary describes the sets a report left out, or returns "" if there are none.
+func suppressedSummary(summary reporttypes.SummaryInfo) string {
+	if summary.SuppressedSets == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\n%d duplicate sets listed in the ignore database were left out (see \"%s ignore list\").\n", summary.SuppressedSets, os.Args[0])
+}
+
+func printIgnoreUsage() {
+	fmt.Printf(`Usage: %[1]s ignore add [--db=FILE] [--report=FILE] [--pattern=REGEX] [--note=TEXT] [<hash>...]
+       %[1]s ignore list [--db=FILE] [--json]
+       %[1]s ignore remove [--db=FILE] (<number> | <hash> | <pattern>)...
+
+Keeps a database of sets that are duplicated on purpose, such as vendored libraries or test
+fixtures. Scans leave out the file and folder sets matching an entry, nested sets included,
+and count them as suppressed in the summary.
+
+An entry matches a set by hash (or folder signature) and/or by a regular expression that every
+path of the set must match. Hashes are given as reports list them, at least 12 hex digits. "add" with a report written with --json adds each of its sets, or
+those with the given hashes; without a report it adds the given hashes, or the pattern alone.
+
+OPTIONS:
+      --db=FILE         Ignore database (default: %[2]s)
+      --report=FILE     Report of the scan ("-" reads it from standard input)
+      --pattern=REGEX   Only match sets whose paths all match REGEX
+      --note=TEXT       Why the sets are duplicated (default: the first path of each set)
+  -j, --json            List the entries as JSON
+  -h, --help            Show this help message
+
+EXAMPLES:
+  %[1]s ignore add --pattern='/vendor/' --note='Vendored libraries'
+  %[1]s ignore add --report=report.json 3f2a9c1b77d0
+  %[1]s ignore remove 2
+`, os.Args[0], defaultIgnorePath())
+}
+
+// defaultIgnorePath returns the default database location for the usage text.
+func defaultIgnorePath() string {
+	path, err := ignore.DefaultPath()
+	if err != nil {
+		return "unavailable, use --db"
+	}
+	return path
+}
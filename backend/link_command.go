@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/actions"
+)
+
+// runLinkCommand replaces duplicate files listed in a report with links to a kept copy.
+func runLinkCommand(args []string) int {
+	var reportPath string
+	var paths []string
+	options := actions.LinkOptions{Kind: actions.LinkHard}
+
+	for _, arg := range args {
+		if name, value, found := strings.Cut(arg, "="); found && strings.HasPrefix(name, "--") {
+			switch name {
+			case "--report":
+				reportPath = value
+			default:
+				fmt.Fprintf(os.Stderr, "Error: unknown option %s\n", name)
+				return 1
+			}
+			continue
+		}
+
+		switch arg {
+		case "--symlink":
+			if options.Kind == actions.LinkHard {
+				options.Kind = actions.LinkSymbolic
+			}
+		case "--relative":
+			options.Kind = actions.LinkRelativeSymbolic
+		case "--dry-run", "-n":
+			options.DryRun = true
+		case "--help", "-h":
+			printLinkUsage()
+			return 0
+		default:
+			if strings.HasPrefix(arg, "-") {
+				fmt.Fprintf(os.Stderr, "Error: unknown option %s\n", arg)
+				return 1
+			}
+			paths = append(paths, arg)
+		}
+	}
+
+	if reportPath == "" {
+		printLinkUsage()
+		return 1
+	}
+
+	if !options.DryRun && !enableAuditLog() {
+		return 1
+	}
+	report, err := loadReport(reportPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+		return 1
+	}
+
+	if len(paths) == 0 {
+		paths = actions.PathsToRemove(report)
+	}
+
+	exitCode := 0
+	for _, outcome := range actions.LinkFromReport(report, paths, options) {
+		switch {
+		case outcome.Err != nil:
+			fmt.Printf("Skipped %s: %s\n", outcome.Path, outcome.Err.Error())
+			exitCode = 1
+		case options.DryRun:
+			fmt.Printf("Would link: %s -> %s\n", outcome.Path, outcome.Destination)
+		default:
+			fmt.Printf("Linked: %s -> %s\n", outcome.Path, outcome.Destination)
+		}
+	}
+	return exitCode
+}
+
+func printLinkUsage() {
+	fmt.Printf(`Usage: %s link --report=FILE [OPTIONS] [<path>...]
+
+Replaces duplicate files listed in a report written with --json with links to a copy that
+is kept: the copy its set marks as kept, or else the first copy that isn't replaced. Without
+paths, the copies the report marks for removal (see --keep) are replaced, or every copy but
+the first of sets without marks. Sets whose copies changed since the scan are skipped. Hardlinks are the
+default; sets spanning several filesystems and copies whose permissions or owner differ
+from the kept copy are skipped.
+
+OPTIONS:
+      --report=FILE  Report of the scan ("-" reads it from standard input)
+      --symlink      Replace copies with symlinks to the kept copy's absolute path
+      --relative     Replace copies with relative symlinks
+  -n, --dry-run      Show what would be linked without changing anything
+  -h, --help         Show this help message
+`, os.Args[0])
+}
@@ -3,25 +3,92 @@ package main
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder"
 	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/helpers"
 	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/helpers/output"
 	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/logger"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/s3"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/selection"
 	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/status"
 )
 
 // MODIFY THIS FUNCTION
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := subcommands[os.Args[1]]; ok {
+			os.Exit(command(os.Args[2:]))
+		}
+	}
+
 	// Parse command line arguments
 	var rootDir string
 	var quietMode bool
 	var jsonMode bool
 	var showProgress bool
+	var showTree bool
+	var detectArchiveFolders bool
+	var expandArchives bool
+	var compareDecompressed bool
+	var findSimilarImages bool
+	var ignoreJPEGMetadata bool
+	var ignoreAudioTags bool
+	var findSimilarTexts bool
+	var normaliseText bool
+	var trimWhitespace bool
+	var ignoreDocumentMetadata bool
+	var detectTruncatedCopies bool
+	var analyseChunks bool
+	var s3Sources []string
+	var s3Endpoint string
+	var keepPolicy selection.Policy
+	var ignorePath string
+	useIgnore := true
+	chunkMinSize := -1   // Keep the default
+	textSimilarity := -1 // Keep the default
+	imageDistance := -1  // Keep the default
 
 	// Simple argument parsing
 	for i, arg := range os.Args[1:] {
+		// Options taking a value use the --name=value form
+		if name, value, found := strings.Cut(arg, "="); found && strings.HasPrefix(name, "--") {
+			switch name {
+			case "--s3":
+				if !s3.IsObjectPath(value) {
+					fmt.Fprintf(os.Stderr, "Error: %s expects an s3://bucket/prefix path, got %q\n", name, value)
+					os.Exit(1)
+				}
+				s3Sources = append(s3Sources, value)
+			case "--s3-endpoint":
+				s3Endpoint = value
+			case "--keep":
+				rule, err := selection.ParseRule(value)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+					os.Exit(1)
+				}
+				keepPolicy = append(keepPolicy, rule)
+			case "--ignore-db":
+				ignorePath = value
+			case "--image-distance":
+				imageDistance = parseIntOption(name, value)
+			case "--chunk-min-size":
+				chunkMinSize = parseIntOption(name, value)
+			case "--text-similarity":
+				textSimilarity = parseIntOption(name, value)
+				if textSimilarity > 100 {
+					fmt.Fprintf(os.Stderr, "Error: %s expects a percentage between 0 and 100, got %q\n", name, value)
+					os.Exit(1)
+				}
+			default:
+				fmt.Fprintf(os.Stderr, "Error: unknown option %s\n", name)
+				os.Exit(1)
+			}
+			continue
+		}
+
 		switch arg {
 		case "--quiet", "-q":
 			quietMode = true
@@ -29,6 +96,34 @@ func main() {
 			jsonMode = true
 		case "--progress", "-p":
 			showProgress = true
+		case "--tree", "-t":
+			showTree = true
+		case "--archive-folders":
+			detectArchiveFolders = true
+		case "--expand-archives":
+			expandArchives = true
+		case "--compressed":
+			compareDecompressed = true
+		case "--similar-images":
+			findSimilarImages = true
+		case "--jpeg-metadata":
+			ignoreJPEGMetadata = true
+		case "--audio-tags":
+			ignoreAudioTags = true
+		case "--similar-text":
+			findSimilarTexts = true
+		case "--normalise-text":
+			normaliseText = true
+		case "--trim-whitespace":
+			trimWhitespace = true
+		case "--document-metadata":
+			ignoreDocumentMetadata = true
+		case "--truncated":
+			detectTruncatedCopies = true
+		case "--chunks":
+			analyseChunks = true
+		case "--no-ignore":
+			useIgnore = false
 		case "--help", "-h":
 			printUsage()
 			os.Exit(0)
@@ -70,7 +165,22 @@ func main() {
 		logger.Info("Starting duplicate search for directory: "+rootDir, "Main")
 	}
 
-	filteredFileDuplicates, filteredFolderDuplicates, allFileDuplicates, allFolderDuplicates, err := fastdupefinder.RunFinder(rootDir)
+	config := fastdupefinder.DefaultConfig().
+		WithArchiveFolderDetection(detectArchiveFolders).
+		WithArchiveExpansion(expandArchives).
+		WithDecompressedComparison(compareDecompressed).
+		WithSimilarImages(findSimilarImages, imageDistance).
+		WithJPEGMetadataIgnored(ignoreJPEGMetadata).
+		WithAudioTagsIgnored(ignoreAudioTags).
+		WithSimilarTexts(findSimilarTexts, float64(textSimilarity)/100).
+		WithTextNormalisation(normaliseText || trimWhitespace, trimWhitespace).
+		WithDocumentMetadataIgnored(ignoreDocumentMetadata).
+		WithTruncatedCopyDetection(detectTruncatedCopies).
+		WithChunkAnalysis(analyseChunks, int64(chunkMinSize)*1024*1024).
+		WithS3Sources(s3Sources...).
+		WithS3Endpoint(s3Endpoint)
+
+	results, err := fastdupefinder.RunFinderWithResults(rootDir, config)
 	if err != nil {
 		if !quietMode {
 			logger.Fatal("Fatal error occurred: "+err.Error(), "Main")
@@ -85,36 +195,148 @@ func main() {
 	}
 
 	// Output results based on mode
+	reportOptions := helpers.ReportOptions{IncludeNested: showTree, KeepPolicy: keepPolicy}
+	if useIgnore {
+		database, err := loadIgnoreDatabase(ignorePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+			os.Exit(1)
+		}
+		reportOptions.Ignore = database
+	}
+	report := helpers.GenerateReportFromResults(results, reportOptions)
 	if jsonMode {
 		// JSON output mode - generate optimized report
-		report := helpers.GenerateReport(filteredFileDuplicates, filteredFolderDuplicates, allFileDuplicates, allFolderDuplicates)
 		fmt.Print(output.JSONifyReport(report))
 	} else {
 		// Standard text output mode - use the optimized report structure
-		report := helpers.GenerateReport(filteredFileDuplicates, filteredFolderDuplicates, allFileDuplicates, allFolderDuplicates)
 		fmt.Print(output.StringifyFileResults(report.FileDuplicates))
 		fmt.Print(output.StringifyFolderResults(report.FolderDuplicates))
+		if config.DetectArchiveFolders {
+			fmt.Print(output.StringifyArchiveResults(report.ArchiveDuplicates))
+		}
+		if config.CompareDecompressed {
+			fmt.Print(output.StringifyCompressedResults(report.CompressedEquivalents))
+		}
+		if config.FindSimilarImages {
+			fmt.Print(output.StringifySimilarImageResults(report.SimilarImages))
+		}
+		if config.FindSimilarTexts {
+			fmt.Print(output.StringifySimilarTextResults(report.SimilarTexts))
+		}
+		if config.IgnoreJPEGMetadata || config.IgnoreAudioTags || config.NormaliseText || config.IgnoreDocumentMetadata {
+			fmt.Print(output.StringifyContentResults(report.ContentDuplicates))
+		}
+		if config.DetectTruncatedCopies {
+			fmt.Print(output.StringifyTruncatedResults(report.TruncatedCopies))
+		}
+		if config.AnalyseChunks {
+			fmt.Print(output.StringifyChunkResults(report.SharedChunks))
+		}
+		fmt.Print(suppressedSummary(report.Summary))
 	}
 }
 
+// parseIntOption parses the value of a numeric option, exiting with an error if it is invalid.
+func parseIntOption(name string, value string) int {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		fmt.Fprintf(os.Stderr, "Error: %s expects a non-negative number, got %q\n", name, value)
+		os.Exit(1)
+	}
+	return n
+}
+
 func printUsage() {
-	fmt.Printf(`Usage: %s [OPTIONS] <directory>
+	fmt.Printf(`Usage: %[1]s [OPTIONS] <directory>
+       %[1]s delete --report=FILE [--trash] [--dry-run] (--marked | <path>...)
+       %[1]s link --report=FILE [--symlink|--relative] [--dry-run] [<path>...]
+       %[1]s dedupe --report=FILE [--dry-run]
+       %[1]s quarantine --report=FILE --to=DIR [--dry-run] [<path>...]
+       %[1]s restore [--dry-run] <manifest>
+       %[1]s mark --report=FILE [--json] <expression>
+       %[1]s script --report=FILE [--keep=RULE]... [--link|--symlink]
+       %[1]s ignore (add | list | remove) [OPTIONS]
+       %[1]s verify-audit [--log=FILE] [--expect=CHAIN]
 
 OPTIONS:
   -q, --quiet     Suppress progress messages and logging
   -j, --json      Output results in JSON format
   -p, --progress  Show progress updates on stderr (ignored in quiet mode)
+  -t, --tree      Include nested duplicates under each duplicate folder set
+      --keep=RULE Mark the copy of each set to keep, ranking copies by RULE (repeatable,
+                  later rules break ties): oldest, newest, shortest, longest, shallow
+                  (fewest path components), prefer=DIR or match=REGEX
+      --ignore-db=FILE
+                  Leave out sets listed in this ignore database instead of the default one
+      --no-ignore Report sets listed in the ignore database too
+      --archive-folders
+                  Report zip/tar archives whose content equals a scanned folder
+      --expand-archives
+                  Compare files inside zip/jar/tar(.gz) archives as virtual files
+      --compressed
+                  Match gzip/bzip2/zlib files with files of the same decompressed content
+      --similar-images
+                  Find JPEG/PNG/GIF images that look alike (re-encoded, resized...)
+      --image-distance=N
+                  Maximum perceptual hash distance for similar images (default 10)
+      --jpeg-metadata
+                  Match JPEGs with the same image data but different EXIF/XMP/comments
+      --audio-tags
+                  Match MP3/FLAC tracks with the same audio but different ID3/APE/Vorbis tags
+      --similar-text
+                  Find text and source files that differ by only a few lines
+      --text-similarity=N
+                  Minimum estimated similarity in percent for similar text (default 80)
+      --normalise-text
+                  Match text files differing only in BOM or CRLF/LF line endings
+      --trim-whitespace
+                  Like --normalise-text, also ignoring trailing spaces and tabs
+      --document-metadata
+                  Match .docx/.xlsx/.pptx/ODF documents that differ only in saved metadata
+      --truncated
+                  Find interrupted downloads and partial copies of larger files
+      --chunks
+                  Estimate block-level sharing between large files (VM images, dumps...)
+      --chunk-min-size=MB
+                  Minimum size in MB of files analysed with --chunks (default 16)
+      --s3=s3://BUCKET/PREFIX
+                  Also scan the objects of an S3 bucket (repeatable); uses the
+                  AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY/AWS_REGION variables
+      --s3-endpoint=URL
+                  S3-compatible endpoint, e.g. http://localhost:9000 (default AWS)
   -h, --help      Show this help message
 
+COMMANDS:
+  delete          Remove copies listed in a JSON report, keeping one copy of each set
+                  (see "%[1]s delete --help")
+  link            Replace copies listed in a JSON report with hardlinks or symlinks
+                  (see "%[1]s link --help")
+  dedupe          Share the data of copies listed in a JSON report on btrfs/XFS
+                  (see "%[1]s dedupe --help")
+  quarantine      Move copies listed in a JSON report to a folder, with an undo manifest
+                  (see "%[1]s quarantine --help")
+  restore         Move quarantined files back using the manifest
+  mark            Select copies listed in a JSON report with an expression
+                  (see "%[1]s mark --help")
+  script          Write a reviewable shell script removing or linking the copies of a
+                  JSON report (see "%[1]s script --help")
+  ignore          Keep a database of sets duplicated on purpose, left out of every scan
+                  (see "%[1]s ignore --help")
+  verify-audit    Check the hash chain of the audit log of every delete, link, dedupe,
+                  quarantine and restore (see "%[1]s verify-audit --help")
+
 EXAMPLES:
-  %s /path/to/scan                    # Basic scan with text output
-  %s -q /path/to/scan                 # Quiet mode for piping
-  %s -p /path/to/scan                 # Show progress updates
-  %s -j /path/to/scan                 # JSON output
-  %s -q -j /path/to/scan              # Quiet JSON mode for scripting
+  %[1]s /path/to/scan                    # Basic scan with text output
+  %[1]s -q /path/to/scan                 # Quiet mode for piping
+  %[1]s -p /path/to/scan                 # Show progress updates
+  %[1]s -j /path/to/scan                 # JSON output
+  %[1]s -q -j /path/to/scan              # Quiet JSON mode for scripting
+  %[1]s -t /path/to/scan                 # Show nested duplicate folders and files
+  %[1]s --keep=prefer=/photos --keep=oldest /path  # Keep the oldest copy, preferring /photos
 
 PIPING EXAMPLES:
-  %s -q /path | grep "Set"            # Find only duplicate sets
-  %s -q -j /path | jq .summary        # Extract summary with jq
-`, os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0])
+  %[1]s -q /path | grep "Set"            # Find only duplicate sets
+  %[1]s -q -j /path | jq .summary        # Extract summary with jq
+`, os.Args[0])
 }
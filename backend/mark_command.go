@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/actions"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/helpers"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/helpers/output"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/selection"
+)
+
+// runMarkCommand selects the copies of the file sets in a report for which an expression holds.
+func runMarkCommand(args []string) int {
+	var reportPath string
+	var source string
+	var roots []selection.Root
+	jsonMode := false
+	separator := "\n"
+
+	for _, arg := range args {
+		if name, value, found := strings.Cut(arg, "="); found && strings.HasPrefix(name, "--") {
+			switch name {
+			case "--report":
+				reportPath = value
+			case "--root":
+				label, dir, ok := strings.Cut(value, "=")
+				if !ok || label == "" || dir == "" {
+					fmt.Fprintf(os.Stderr, "Error: %s expects LABEL=DIR, got %q\n", name, value)
+					return 1
+				}
+				roots = append(roots, selection.Root{Label: label, Dir: dir})
+			default:
+				fmt.Fprintf(os.Stderr, "Error: unknown option %s\n", name)
+				return 1
+			}
+			continue
+		}
+
+		switch arg {
+		case "--json", "-j":
+			jsonMode = true
+		case "--null", "-0":
+			separator = "\x00"
+		case "--help", "-h":
+			printMarkUsage()
+			return 0
+		default:
+			if strings.HasPrefix(arg, "-") || source != "" {
+				fmt.Fprintf(os.Stderr, "Error: unexpected argument %s\n", arg)
+				return 1
+			}
+			source = arg
+		}
+	}
+
+	if reportPath == "" || source == "" {
+		printMarkUsage()
+		return 1
+	}
+
+	expression, err := selection.CompileExpression(source)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid expression: %s\n", err.Error())
+		return 1
+	}
+	report, err := loadReport(reportPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+		return 1
+	}
+
+	warned := make(map[string]bool)
+	for _, hash := range helpers.MarkWithExpression(&report, expression, roots) {
+		if !warned[hash] {
+			warned[hash] = true
+			fmt.Fprintf(os.Stderr, "Warning: the expression selects every copy of set %s, keeping all of them\n", hash)
+		}
+	}
+
+	if jsonMode {
+		fmt.Print(output.JSONifyReport(report))
+		return 0
+	}
+	listed := make(map[string]bool)
+	for _, path := range actions.PathsToRemove(report) {
+		if !listed[path] {
+			listed[path] = true
+			fmt.Print(path + separator)
+		}
+	}
+	return 0
+}
+
+func printMarkUsage() {
+	fmt.Printf(`Usage: %[1]s mark --report=FILE [OPTIONS] <expression>
+
+Evaluates an expression for every copy of the file sets in a report written with --json
+and lists the copies it selects. With --json, the report is written instead, with the
+selected copies marked for removal and the others marked as kept, for use with delete
+--marked, link or quarantine. If the expression selects every copy of a set, the set is
+kept as a whole.
+
+Expressions compare values with == != < <= > >=, match regular expressions with =~ and !~
+and combine conditions with && || ! and parentheses:
+  path, name, dir, ext, size, mtime, depth, root   The copy ("ext" is lower case, without dot)
+  keeper.path, keeper.mtime, ...                   The copy the report keeps (see --keep)
+  set.count, set.size, set.oldest, set.newest, set.mindepth, set.maxdepth
+Literals: "string", 'raw string', 10MB, true. Functions: date("2024-01-31"), lower(s),
+contains(s, sub), hasprefix(s, prefix), hassuffix(s, suffix).
+
+OPTIONS:
+      --report=FILE     Report of the scan ("-" reads it from standard input)
+      --root=LABEL=DIR  Name copies below DIR "LABEL" in the root variable (repeatable)
+  -j, --json            Write the marked report instead of the list of paths
+  -0, --null            Separate listed paths with NUL characters, for xargs -0
+  -h, --help            Show this help message
+
+EXAMPLES:
+  %[1]s mark --report=report.json 'path =~ "/Downloads/" && mtime > keeper.mtime'
+  %[1]s mark --report=report.json -j 'ext == "tmp" || depth > set.mindepth' | %[1]s link --report=-
+`, os.Args[0])
+}
@@ -0,0 +1,288 @@
+// Package actions removes the redundant copies found by a scan. Every operation is checked
+// against the duplicate sets first, so at least one copy of each set is always kept, and
+// can run as a dry run that reports what would happen without touching any file.
+package actions
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/archives"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/s3"
+	reporttypes "github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types/report_types"
+)
+
+// Method selects how paths are removed.
+type Method int
+
+const (
+	// MethodDelete removes files and folders permanently.
+	MethodDelete Method = iota
+	// MethodTrash moves files and folders to the freedesktop.org trash, so they can be restored
+	// from the desktop's file manager.
+	MethodTrash
+)
+
+// Options controls how Remove acts on paths.
+type Options struct {
+	Method Method
+	// DryRun checks every path as usual but leaves all files in place.
+	DryRun bool
+}
+
+// Outcome reports what happened to a single path.
+type Outcome struct {
+	Path string
+	// Destination is where the path was moved, or the kept copy a link points to.
+	Destination string
+	// Err is nil if the path was removed, or would have been in a dry run.
+	Err error
+}
+
+var (
+	// ErrLastCopy is returned for paths whose removal would leave a set without any copy.
+	ErrLastCopy = errors.New("refusing to remove the last remaining copy of a duplicate set")
+	// ErrNotInSet is returned for paths that don't belong to any duplicate set.
+	ErrNotInSet = errors.New("path is not part of a duplicate set")
+	// ErrNotOnDisk is returned for archive members and S3 objects, which are never modified.
+	ErrNotOnDisk = errors.New("archive members and S3 objects can't be removed")
+	// ErrTrashUnsupported is returned by MethodTrash on platforms without a freedesktop.org trash.
+	ErrTrashUnsupported = errors.New("the trash is not supported on this platform")
+)
+
+// Remove removes Paths, each of which must be a copy in one of Sets, using the method in Options.
+// Paths are refused as a whole per set: if removing them would leave a set without a copy that
+// still exists on disk, none of the set's paths are removed. Copies inside a removed folder,
+// archive members and S3 objects don't count as remaining.
+// Outcomes are returned in the order of Paths.
+func Remove(Sets [][]string, Paths []string, Options Options) []Outcome {
+	return remove(Paths, checkRemovals(Sets, Paths), nil, Options)
+}
+
+// RemoveFromReport is Remove for the file and folder sets of a report, guarded by Revalidate:
+// the paths of sets that changed since the scan are refused with the reason.
+func RemoveFromReport(Report reporttypes.ReportOutput, Paths []string, Options Options) []Outcome {
+	refused, hashes := revalidatedRemovals(Report, SetsFromReport(Report), Paths)
+	return remove(Paths, refused, hashes, Options)
+}
+
+// remove removes the paths that aren't refused, auditing them with the hashes known so far.
+func remove(Paths []string, refused map[string]error, hashes map[string]string, Options Options) []Outcome {
+	outcomes := make([]Outcome, 0, len(Paths))
+	done := make(map[string]bool)
+	for _, path := range Paths {
+		path = filepath.Clean(path)
+		if done[path] {
+			continue
+		}
+		done[path] = true
+
+		outcome := Outcome{Path: path, Err: refused[path]}
+		if outcome.Err == nil {
+			operation := AuditDelete
+			if Options.Method == MethodTrash {
+				operation = AuditTrash
+			}
+			outcome.Destination, outcome.Err = audited(operation, path, hashes[path], Options.DryRun, func() (string, error) {
+				return removePath(path, Options)
+			})
+		}
+		outcomes = append(outcomes, outcome)
+	}
+	return outcomes
+}
+
+// SetsFromReport returns the paths of every byte-identical file and folder set of a report,
+// including the sets nested below duplicate folders.
+func SetsFromReport(Report reporttypes.ReportOutput) [][]string {
+	return collectSets(Report, true)
+}
+
+// FileSetsFromReport returns the paths of every byte-identical file set of a report,
+// including the sets nested below duplicate folders.
+func FileSetsFromReport(Report reporttypes.ReportOutput) [][]string {
+	return collectSets(Report, false)
+}
+
+// collectSets gathers the sets of a report, with or without the folder sets.
+func collectSets(Report reporttypes.ReportOutput, IncludeFolders bool) [][]string {
+	var sets [][]string
+	var addFolders func(folderSets []reporttypes.FolderSet)
+	addFiles := func(fileSets []reporttypes.FileSet) {
+		for _, set := range fileSets {
+			sets = append(sets, set.Paths)
+		}
+	}
+	addFolders = func(folderSets []reporttypes.FolderSet) {
+		for _, set := range folderSets {
+			if IncludeFolders {
+				sets = append(sets, set.Paths)
+			}
+			if set.Nested != nil {
+				addFiles(set.Nested.FileDuplicates)
+				addFolders(set.Nested.FolderDuplicates)
+			}
+		}
+	}
+	addFiles(Report.FileDuplicates)
+	addFolders(Report.FolderDuplicates)
+	return sets
+}
+
+// AllButFirst returns every copy of the sets except the first one of each set.
+func AllButFirst(Sets [][]string) []string {
+	var paths []string
+	for _, set := range Sets {
+		if len(set) > 1 {
+			paths = append(paths, set[1:]...)
+		}
+	}
+	return paths
+}
+
+// PathsToRemove returns the copies that the file sets of a report, nested sets included, mark for
+// removal. Sets without marks, from reports generated without a keep policy, contribute every copy
+// but the first.
+func PathsToRemove(Report reporttypes.ReportOutput) []string {
+	return pathsToRemove(Report, true)
+}
+
+// MarkedPaths returns the copies that the file sets of a report, nested sets included, mark for
+// removal, like PathsToRemove, but leaves out sets without marks entirely.
+func MarkedPaths(Report reporttypes.ReportOutput) []string {
+	return pathsToRemove(Report, false)
+}
+
+// pathsToRemove collects the copies marked for removal, and every copy but the first of sets
+// without marks if unmarked is set.
+func pathsToRemove(Report reporttypes.ReportOutput, unmarked bool) []string {
+	var paths []string
+	var addFolders func(folderSets []reporttypes.FolderSet)
+	addFiles := func(fileSets []reporttypes.FileSet) {
+		for _, set := range fileSets {
+			if set.Marks == nil {
+				if unmarked {
+					paths = append(paths, AllButFirst([][]string{set.Paths})...)
+				}
+				continue
+			}
+			for _, path := range set.Paths {
+				if set.Marks[path] == reporttypes.MarkRemove {
+					paths = append(paths, path)
+				}
+			}
+		}
+	}
+	addFolders = func(folderSets []reporttypes.FolderSet) {
+		for _, set := range folderSets {
+			if set.Nested != nil {
+				addFiles(set.Nested.FileDuplicates)
+				addFolders(set.Nested.FolderDuplicates)
+			}
+		}
+	}
+	addFiles(Report.FileDuplicates)
+	addFolders(Report.FolderDuplicates)
+	return paths
+}
+
+// checkRemovals returns the reason each refused path can't be removed.
+func checkRemovals(Sets [][]string, Paths []string) map[string]error {
+	refused := make(map[string]error)
+	targets := make(map[string]bool, len(Paths))
+	for _, path := range Paths {
+		path = filepath.Clean(path)
+		if archives.IsVirtualPath(path) || s3.IsObjectPath(path) {
+			refused[path] = ErrNotOnDisk
+			continue
+		}
+		targets[path] = true
+	}
+
+	inSet := make(map[string]bool)
+	for _, set := range Sets {
+		var removed []string
+		remaining := false
+		for _, copyPath := range set {
+			copyPath = filepath.Clean(copyPath)
+			if targets[copyPath] {
+				removed = append(removed, copyPath)
+				continue
+			}
+			if !remaining && !insideAny(copyPath, targets) && existsOnDisk(copyPath) {
+				remaining = true
+			}
+		}
+		for _, path := range removed {
+			inSet[path] = true
+			if !remaining {
+				refused[path] = ErrLastCopy
+			}
+		}
+	}
+
+	for path := range targets {
+		if !inSet[path] {
+			refused[path] = ErrNotInSet
+		}
+	}
+	return refused
+}
+
+// revalidatedRemovals revalidates Paths against a report and returns the reason each refused path
+// can't be removed, the failure of its set or else that of checkRemovals among the valid paths,
+// and the hashes computed by revalidate.
+func revalidatedRemovals(Report reporttypes.ReportOutput, Sets [][]string, Paths []string) (map[string]error, map[string]string) {
+	checks, hashes := revalidate(Report, Paths)
+	refused := checkRemovals(Sets, ValidPaths(Paths, checks))
+	for _, check := range checks {
+		if check.Err != nil {
+			for _, path := range check.Paths {
+				refused[path] = check.Err
+			}
+		}
+	}
+	return refused, hashes
+}
+
+// insideAny reports whether a path lies inside one of the given folders.
+func insideAny(path string, folders map[string]bool) bool {
+	for dir, parent := path, filepath.Dir(path); parent != dir; dir, parent = parent, filepath.Dir(parent) {
+		if folders[parent] {
+			return true
+		}
+	}
+	return false
+}
+
+// existsOnDisk reports whether a copy can be relied on to remain after the removal.
+func existsOnDisk(path string) bool {
+	if archives.IsVirtualPath(path) || s3.IsObjectPath(path) {
+		return false
+	}
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// removePath removes a single checked path and returns its new location, if any.
+func removePath(path string, Options Options) (string, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return "", err
+	}
+	if Options.Method == MethodTrash && !trashSupported {
+		return "", ErrTrashUnsupported
+	}
+	if Options.DryRun {
+		return "", nil
+	}
+
+	if Options.Method == MethodTrash {
+		return moveToTrash(path)
+	}
+	if info.IsDir() {
+		return "", os.RemoveAll(path)
+	}
+	return "", os.Remove(path)
+}
@@ -0,0 +1,131 @@
+package actions
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/archives"
+	reporttypes "github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types/report_types"
+)
+
+func TestRemoveKeepsLastCopy(t *testing.T) {
+	tests := []struct {
+		name string
+		// sets and paths use names relative to a folder holding a.txt, b.txt and dir/c.txt.
+		sets    [][]string
+		paths   []string
+		wantErr map[string]error // Expected error by path; paths not listed are removed
+	}{
+		{name: "one copy of two", sets: [][]string{{"a.txt", "b.txt"}}, paths: []string{"b.txt"}},
+		{
+			name:    "every copy",
+			sets:    [][]string{{"a.txt", "b.txt"}},
+			paths:   []string{"a.txt", "b.txt"},
+			wantErr: map[string]error{"a.txt": ErrLastCopy, "b.txt": ErrLastCopy},
+		},
+		{
+			name:    "other copy gone from disk",
+			sets:    [][]string{{"a.txt", "b.txt", "gone.txt"}},
+			paths:   []string{"a.txt", "b.txt"},
+			wantErr: map[string]error{"a.txt": ErrLastCopy, "b.txt": ErrLastCopy},
+		},
+		{
+			name:    "other copy inside a removed folder",
+			sets:    [][]string{{"a.txt", "dir/c.txt"}, {"dir", "other"}},
+			paths:   []string{"a.txt", "dir"},
+			wantErr: map[string]error{"a.txt": ErrLastCopy},
+		},
+		{
+			name:    "other copy only in an archive",
+			sets:    [][]string{{"a.txt", archives.VirtualPath("x.zip", "a.txt")}},
+			paths:   []string{"a.txt"},
+			wantErr: map[string]error{"a.txt": ErrLastCopy},
+		},
+		{
+			name:    "archive member",
+			sets:    [][]string{{"a.txt", archives.VirtualPath("x.zip", "a.txt")}},
+			paths:   []string{archives.VirtualPath("x.zip", "a.txt")},
+			wantErr: map[string]error{archives.VirtualPath("x.zip", "a.txt"): ErrNotOnDisk},
+		},
+		{
+			name:    "not in a set",
+			sets:    [][]string{{"a.txt", "b.txt"}},
+			paths:   []string{"dir/c.txt"},
+			wantErr: map[string]error{"dir/c.txt": ErrNotInSet},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, name := range []string{"a.txt", "b.txt", "dir/c.txt", "other/c.txt"} {
+				path := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte("content"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			abs := func(name string) string {
+				if archives.IsVirtualPath(name) {
+					return name
+				}
+				return filepath.Join(dir, name)
+			}
+			var sets [][]string
+			for _, set := range test.sets {
+				var paths []string
+				for _, name := range set {
+					paths = append(paths, abs(name))
+				}
+				sets = append(sets, paths)
+			}
+			var paths []string
+			for _, name := range test.paths {
+				paths = append(paths, abs(name))
+			}
+
+			outcomes := Remove(sets, paths, Options{})
+			if len(outcomes) != len(paths) {
+				t.Fatalf("got %d outcomes for %d paths", len(outcomes), len(paths))
+			}
+			for i, name := range test.paths {
+				want := test.wantErr[name]
+				if !errors.Is(outcomes[i].Err, want) {
+					t.Errorf("%s: got error %v, want %v", name, outcomes[i].Err, want)
+				}
+				if archives.IsVirtualPath(name) {
+					continue
+				}
+				if _, err := os.Lstat(abs(name)); (err == nil) != (want != nil) {
+					t.Errorf("%s: removed is %t, want %t", name, err != nil, want == nil)
+				}
+			}
+		})
+	}
+}
+
+func TestPathsToRemove(t *testing.T) {
+	report := reporttypes.ReportOutput{
+		FileDuplicates: []reporttypes.FileSet{
+			{Paths: []string{"/a/1", "/b/1"}, Marks: map[string]string{"/a/1": reporttypes.MarkRemove, "/b/1": reporttypes.MarkKeep}},
+			{Paths: []string{"/a/2", "/b/2", "/c/2"}}, // Unmarked
+		},
+		FolderDuplicates: []reporttypes.FolderSet{{
+			Paths: []string{"/d", "/e"},
+			Nested: &reporttypes.NestedDuplicates{FileDuplicates: []reporttypes.FileSet{
+				{Paths: []string{"/d/3", "/e/3"}, Marks: map[string]string{"/d/3": reporttypes.MarkKeep, "/e/3": reporttypes.MarkRemove}},
+			}},
+		}},
+	}
+
+	if got, want := PathsToRemove(report), []string{"/a/1", "/b/2", "/c/2", "/e/3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("PathsToRemove returned %v, want %v", got, want)
+	}
+	if got, want := MarkedPaths(report), []string{"/a/1", "/e/3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MarkedPaths returned %v, want %v", got, want)
+	}
+}
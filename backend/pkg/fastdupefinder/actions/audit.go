@@ -0,0 +1,398 @@
+package actions
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/helpers"
+)
+
+var (
+	// ErrAuditChainBroken is returned by VerifyAuditLog for entries that don't continue the chain
+	// of the entry before them, as after removing or reordering entries.
+	ErrAuditChainBroken = errors.New("entry doesn't continue the chain of the previous entry")
+	// ErrAuditEntryModified is returned by VerifyAuditLog for entries whose content doesn't match
+	// their chain value, as after editing them or verifying with the wrong key.
+	ErrAuditEntryModified = errors.New("entry doesn't match its chain value")
+	// ErrAuditTruncated is returned by VerifyAuditLog if no entry has the chain value it expects,
+	// as after removing entries from the end of the log.
+	ErrAuditTruncated = errors.New("no entry has the expected chain value, entries were removed from the end")
+)
+
+// Operations recorded in the audit log.
+const (
+	AuditDelete     = "delete"
+	AuditTrash      = "trash"
+	AuditHardlink   = "hardlink"
+	AuditSymlink    = "symlink"
+	AuditDedupe     = "dedupe"
+	AuditQuarantine = "quarantine"
+	AuditRestore    = "restore"
+)
+
+// UnkeyedAuditWarning explains what a log verified without a key proves: its chain values are
+// plain hashes, which anyone who can edit the log can recompute.
+const UnkeyedAuditWarning = "the log has no key (FDF_AUDIT_KEY), so anyone who can edit it can recompute its chain; " +
+	"it is only checked for accidental damage"
+
+const (
+	// AuditAttempt is the Outcome of the entry recorded before each operation starts.
+	AuditAttempt = "attempt"
+	// AuditOK is the Outcome of audit entries whose operation succeeded.
+	AuditOK = "ok"
+)
+
+// AuditEntry is one line of the audit log, recording a single operation on a path.
+type AuditEntry struct {
+	Time        time.Time `json:"time"`
+	User        string    `json:"user"`
+	Operation   string    `json:"operation"`             // One of the Audit* operations
+	Path        string    `json:"path"`                  // Path that was acted on
+	Destination string    `json:"destination,omitempty"` // Where it was moved, or the kept copy it now links to
+	Hash        string    `json:"hash,omitempty"`        // Full hash of the file before the operation, as in CalculateHash
+	SizeBytes   int64     `json:"sizeBytes"`             // Size of the path before the operation
+	Outcome     string    `json:"outcome"`               // AuditAttempt, then AuditOK or the error of the operation
+
+	// Previous is the Chain of the entry before this one, empty for the first entry.
+	Previous string `json:"previous"`
+	// Chain is the SHA-256 of the entry with an empty Chain, or its HMAC-SHA256 if the log has a
+	// key. Since every entry includes the chain of the previous one, editing, removing or
+	// reordering entries breaks the chain of all following entries.
+	Chain string `json:"chain"`
+}
+
+// AuditLog appends the operations of the actions to a JSON-lines file, one AuditEntry per line.
+// Entries are chained by hash, so changes to the file are detected by VerifyAuditLog. With a key,
+// the chain values are HMACs that can't be recomputed without it, so a rewritten log can't be
+// passed off as genuine either. The log is safe for concurrent use within a process; processes
+// writing the same file at the same time may break the chain.
+type AuditLog struct {
+	path string
+	key  []byte
+	user string
+	mu   sync.Mutex
+}
+
+var (
+	auditMu sync.Mutex
+	// auditLog records the operations of all actions; it is set by SetAuditLog or opened on first
+	// use, see currentAuditLog.
+	auditLog *AuditLog
+)
+
+// DefaultAuditLogPath returns the location of the audit log in the user's configuration directory,
+// e.g. ~/.config/fast-dupe-finder/audit.jsonl on Linux.
+func DefaultAuditLogPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "fast-dupe-finder", "audit.jsonl"), nil
+}
+
+// AuditLogPathFromEnv returns the audit log named by $FDF_AUDIT_LOG, or DefaultAuditLogPath.
+func AuditLogPathFromEnv() (string, error) {
+	if path := os.Getenv("FDF_AUDIT_LOG"); path != "" {
+		return path, nil
+	}
+	return DefaultAuditLogPath()
+}
+
+// AuditKeyFromEnv returns the key of the audit log, $FDF_AUDIT_KEY, which may be empty.
+func AuditKeyFromEnv() []byte {
+	return []byte(os.Getenv("FDF_AUDIT_KEY"))
+}
+
+// OpenAuditLogFromEnv opens the audit log at AuditLogPathFromEnv with AuditKeyFromEnv.
+func OpenAuditLogFromEnv() (*AuditLog, error) {
+	path, err := AuditLogPathFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return OpenAuditLog(path, AuditKeyFromEnv())
+}
+
+// OpenAuditLog prepares the audit log at Path, creating it and its folder if needed. It fails if
+// the file can't be written or its last entry can't be read, so actions can be refused before
+// any file is touched. Key, if not empty, turns the chain into HMACs.
+func OpenAuditLog(Path string, Key []byte) (*AuditLog, error) {
+	if err := os.MkdirAll(filepath.Dir(Path), 0700); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	file.Close()
+	if _, err := lastAuditChain(Path); err != nil {
+		return nil, err
+	}
+	return &AuditLog{path: Path, key: Key, user: currentUser()}, nil
+}
+
+// SetAuditLog makes every following Remove, Link, Dedupe, Quarantine and Restore record its
+// operations in Log. Actions always record their operations outside dry runs: until a log is set,
+// or after nil is set, the one of OpenAuditLogFromEnv is opened on first use, and operations are
+// refused if it can't be. Each operation is recorded twice: an AuditAttempt entry synced to disk
+// before it starts, without which it doesn't start, and its outcome once it is done, so an
+// interrupted operation still leaves its attempt in the log. Operations refused by the checks
+// aren't recorded, since nothing was attempted.
+func SetAuditLog(Log *AuditLog) {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	auditLog = Log
+}
+
+// currentAuditLog returns the log set by SetAuditLog, opening the one named by the environment
+// if none is set.
+func currentAuditLog() (*AuditLog, error) {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	if auditLog == nil {
+		log, err := OpenAuditLogFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		auditLog = log
+	}
+	return auditLog, nil
+}
+
+// Record appends an entry for an operation on Path, chained to the last entry of the file.
+// Paths are recorded as absolute paths. Err is the error of the operation, or nil if it succeeded.
+func (l *AuditLog) Record(Operation string, Path string, Destination string, Hash string, SizeBytes int64, Err error) error {
+	outcome := AuditOK
+	if Err != nil {
+		outcome = Err.Error()
+	}
+	return l.record(Operation, Path, Destination, Hash, SizeBytes, outcome)
+}
+
+// record appends an entry with the given outcome, see Record.
+func (l *AuditLog) record(operation string, path string, destination string, hash string, sizeBytes int64, outcome string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	previous, err := lastAuditChain(l.path)
+	if err != nil {
+		return err
+	}
+	entry := AuditEntry{
+		Time:      time.Now().UTC(),
+		User:      l.user,
+		Operation: operation,
+		Path:      absolutePath(path),
+		Hash:      hash,
+		SizeBytes: sizeBytes,
+		Outcome:   outcome,
+		Previous:  previous,
+	}
+	if destination != "" {
+		entry.Destination = absolutePath(destination)
+	}
+	if entry.Chain, err = auditChain(entry, l.key); err != nil {
+		return err
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// VerifyAuditLog checks the chain of every entry of the audit log at Path, using the key the log
+// was written with, and returns the number of entries and the chain value of the last one.
+// Errors name the line of the first entry that fails. Removing entries from the end of the log
+// keeps the chain intact, so the last chain value should be kept elsewhere and passed as Expected
+// to a later call, which fails with ErrAuditTruncated unless an entry still has it.
+// Without Key, a log that passes only shows that it wasn't damaged by accident, see
+// UnkeyedAuditWarning.
+func VerifyAuditLog(Path string, Key []byte, Expected string) (int, string, error) {
+	file, err := os.Open(Path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	count, previous := 0, ""
+	found := Expected == ""
+	for scanner.Scan() {
+		var entry AuditEntry
+		decoder := json.NewDecoder(bytes.NewReader(scanner.Bytes()))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&entry); err != nil {
+			return count, previous, fmt.Errorf("line %d: %w", count+1, err)
+		}
+		if entry.Previous != previous {
+			return count, previous, fmt.Errorf("line %d: %w", count+1, ErrAuditChainBroken)
+		}
+		chain, err := auditChain(entry, Key)
+		if err != nil {
+			return count, previous, fmt.Errorf("line %d: %w", count+1, err)
+		}
+		if !hmac.Equal([]byte(chain), []byte(entry.Chain)) {
+			return count, previous, fmt.Errorf("line %d: %w", count+1, ErrAuditEntryModified)
+		}
+		count++
+		previous = entry.Chain
+		found = found || entry.Chain == Expected
+	}
+	if err := scanner.Err(); err != nil {
+		return count, previous, err
+	}
+	if !found {
+		return count, previous, ErrAuditTruncated
+	}
+	return count, previous, nil
+}
+
+// auditChain computes the chain value of an entry, ignoring its current Chain.
+func auditChain(entry AuditEntry, key []byte) (string, error) {
+	entry.Chain = ""
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return "", err
+	}
+	if len(key) > 0 {
+		mac := hmac.New(sha256.New, key)
+		mac.Write(data)
+		return hex.EncodeToString(mac.Sum(nil)), nil
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// lastAuditChain returns the chain value of the last entry of the log, or "" if it is empty.
+// Only the end of the file is read, which holds the last entry unless it is unusually long.
+func lastAuditChain(path string) (string, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+	offset := max(info.Size()-64*1024, 0)
+	tail := make([]byte, info.Size()-offset)
+	if _, err := file.ReadAt(tail, offset); err != nil && err != io.EOF {
+		return "", err
+	}
+	tail = bytes.TrimRight(tail, "\n")
+	if len(tail) == 0 {
+		return "", nil
+	}
+	if newline := bytes.LastIndexByte(tail, '\n'); newline >= 0 {
+		tail = tail[newline+1:]
+	}
+	var entry AuditEntry
+	if err := json.Unmarshal(tail, &entry); err != nil || entry.Chain == "" {
+		return "", fmt.Errorf("reading the last entry of audit log %s: the log is damaged", path)
+	}
+	return entry.Chain, nil
+}
+
+// absolutePath makes a path absolute, keeping it as is if that fails.
+func absolutePath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// currentUser returns the name of the user running the process.
+func currentUser() string {
+	if current, err := user.Current(); err == nil {
+		return current.Username
+	}
+	for _, name := range []string{"USER", "USERNAME"} {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return "unknown"
+}
+
+// audited runs an operation on path and records it in the audit log, unless dryRun is true.
+// The hash and size are taken before the operation, while the path still exists; hash is the
+// full hash of the file if the caller already knows it, or "" to compute it. The operation
+// doesn't run if the log can't be opened, the file can't be hashed or its attempt can't be
+// recorded. If it succeeded but its outcome couldn't be recorded, the recording error is
+// returned instead.
+func audited(operation string, path string, hash string, dryRun bool, run func() (string, error)) (string, error) {
+	if dryRun {
+		return run()
+	}
+	log, err := currentAuditLog()
+	if err != nil {
+		return "", fmt.Errorf("opening the audit log: %w", err)
+	}
+
+	var size int64
+	if info, err := os.Lstat(path); err == nil {
+		size = info.Size()
+		switch {
+		case info.Mode().IsRegular() && hash == "":
+			if hash, err = helpers.CalculateHash(path, false); err != nil {
+				return "", fmt.Errorf("hashing the file for the audit log: %w", err)
+			}
+		case info.IsDir():
+			size = auditFolderSize(path)
+		}
+	}
+	if err := log.record(operation, path, "", hash, size, AuditAttempt); err != nil {
+		return "", fmt.Errorf("recording the operation in the audit log: %w", err)
+	}
+	destination, err := run()
+	if recordErr := log.Record(operation, path, destination, hash, size, err); recordErr != nil && err == nil {
+		err = fmt.Errorf("recording the operation in the audit log: %w", recordErr)
+	}
+	return destination, err
+}
+
+// auditFolderSize returns the total size of the regular files below a folder.
+func auditFolderSize(dir string) int64 {
+	var total int64
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && entry.Type().IsRegular() {
+			if info, err := entry.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
@@ -0,0 +1,169 @@
+package actions
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/helpers"
+)
+
+// TestMain points the audit log the actions open by default at a temporary file, so tests never
+// write to the user's configuration directory.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "audit")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("FDF_AUDIT_LOG", filepath.Join(dir, "audit.jsonl"))
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestVerifyAuditLog(t *testing.T) {
+	tests := []struct {
+		name string
+		// tamper changes the lines of the log.
+		tamper  func(lines []string) []string
+		key     string // Key used to verify; the log is written with "secret"
+		expect  bool   // Pass the chain of the last entry before tampering as Expected
+		wantErr error
+	}{
+		{name: "untouched", key: "secret", expect: true},
+		{
+			name: "entry edited",
+			tamper: func(lines []string) []string {
+				lines[1] = strings.Replace(lines[1], `"delete"`, `"trash"`, 1)
+				return lines
+			},
+			key:     "secret",
+			wantErr: ErrAuditEntryModified,
+		},
+		{
+			name:    "entry removed",
+			tamper:  func(lines []string) []string { return append(lines[:1], lines[2:]...) },
+			key:     "secret",
+			wantErr: ErrAuditChainBroken,
+		},
+		{
+			name:    "entries reordered",
+			tamper:  func(lines []string) []string { lines[1], lines[2] = lines[2], lines[1]; return lines },
+			key:     "secret",
+			wantErr: ErrAuditChainBroken,
+		},
+		{
+			name:    "last entry removed",
+			tamper:  func(lines []string) []string { return lines[:len(lines)-1] },
+			key:     "secret",
+			expect:  true,
+			wantErr: ErrAuditTruncated,
+		},
+		{name: "wrong key", key: "other", wantErr: ErrAuditEntryModified},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			logPath := filepath.Join(t.TempDir(), "audit.jsonl")
+			log, err := OpenAuditLog(logPath, []byte("secret"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, path := range []string{"/a", "/b", "/c", "/d"} {
+				if err := log.Record(AuditDelete, path, "", "", 1, nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+			_, last, err := VerifyAuditLog(logPath, []byte("secret"), "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.tamper != nil {
+				data, _ := os.ReadFile(logPath)
+				lines := test.tamper(strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"))
+				os.WriteFile(logPath, []byte(strings.Join(lines, "\n")+"\n"), 0600)
+			}
+			expected := ""
+			if test.expect {
+				expected = last
+			}
+
+			if _, _, err := VerifyAuditLog(logPath, []byte(test.key), expected); !errors.Is(err, test.wantErr) {
+				t.Errorf("VerifyAuditLog returned %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuditedRecordsAttemptFirst(t *testing.T) {
+	dir := t.TempDir()
+	keeper := filepath.Join(dir, "a.txt")
+	copyPath := filepath.Join(dir, "b.txt")
+	writeTestFile(t, keeper, "content", 0644, time.Now())
+	writeTestFile(t, copyPath, "content", 0644, time.Now())
+	logPath := filepath.Join(dir, "audit", "audit.jsonl")
+	log, err := OpenAuditLog(logPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetAuditLog(log)
+	defer SetAuditLog(nil)
+
+	if outcomes := Remove([][]string{{keeper, copyPath}}, []string{copyPath}, Options{}); outcomes[0].Err != nil {
+		t.Fatal(outcomes[0].Err)
+	}
+	file, err := os.Open(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var outcomes []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatal(err)
+		}
+		if hash, _ := helpers.CalculateHash(keeper, false); entry.Hash != hash {
+			t.Errorf("%s entry has hash %q, want %q", entry.Outcome, entry.Hash, hash)
+		}
+		outcomes = append(outcomes, entry.Outcome)
+	}
+	if strings.Join(outcomes, ",") != AuditAttempt+","+AuditOK {
+		t.Errorf("log holds outcomes %v, want an attempt followed by ok", outcomes)
+	}
+
+	// Without a writable log, nothing is attempted.
+	os.Remove(logPath)
+	os.Mkdir(logPath, 0700)
+	writeTestFile(t, copyPath, "content", 0644, time.Now())
+	if outcomes := Remove([][]string{{keeper, copyPath}}, []string{copyPath}, Options{}); outcomes[0].Err == nil {
+		t.Errorf("Remove succeeded without an audit log")
+	}
+	if _, err := os.Stat(copyPath); err != nil {
+		t.Errorf("copy removed although its attempt wasn't recorded: %v", err)
+	}
+}
+
+func TestAuditLogOpenedByDefault(t *testing.T) {
+	dir := t.TempDir()
+	keeper := filepath.Join(dir, "a.txt")
+	copyPath := filepath.Join(dir, "b.txt")
+	writeTestFile(t, keeper, "content", 0644, time.Now())
+	writeTestFile(t, copyPath, "content", 0644, time.Now())
+	logPath := filepath.Join(dir, "audit", "audit.jsonl")
+	t.Setenv("FDF_AUDIT_LOG", logPath)
+	SetAuditLog(nil)
+	defer SetAuditLog(nil)
+
+	if outcomes := Remove([][]string{{keeper, copyPath}}, []string{copyPath}, Options{}); outcomes[0].Err != nil {
+		t.Fatal(outcomes[0].Err)
+	}
+	if count, _, err := VerifyAuditLog(logPath, nil, ""); err != nil || count != 2 {
+		t.Errorf("default audit log holds %d valid entries (%v), want an attempt and its outcome", count, err)
+	}
+}
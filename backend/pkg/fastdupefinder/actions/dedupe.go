@@ -0,0 +1,93 @@
+package actions
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+var (
+	// ErrDedupeUnsupported is returned for files on filesystems that can't share extents,
+	// such as ext4 or tmpfs, and on platforms without FIDEDUPERANGE.
+	ErrDedupeUnsupported = errors.New("extent sharing is not supported by this filesystem")
+	// ErrContentDiffers is returned when the kernel found the bytes of two copies to differ.
+	ErrContentDiffers = errors.New("content differs from the kept copy")
+)
+
+// DedupeOptions controls how Dedupe acts on sets.
+type DedupeOptions struct {
+	// DryRun checks every set as usual and reports the bytes that could be reclaimed,
+	// without asking the kernel to share any extents.
+	DryRun bool
+}
+
+// DedupeResult reports what happened to a single duplicate set.
+type DedupeResult struct {
+	// Source is the copy whose extents the other copies now share.
+	Source string
+	// Outcomes holds one entry per other copy, with Source as Destination.
+	Outcomes []Outcome
+	// ReclaimedBytes counts the bytes the kernel deduplicated. Extents that were already
+	// shared are counted again, since the kernel doesn't tell them apart.
+	ReclaimedBytes int64
+}
+
+// Dedupe makes the copies of every file set share their data extents on copy-on-write
+// filesystems such as btrfs and XFS, using the FIDEDUPERANGE ioctl. The kernel locks both
+// files and compares their bytes before sharing, so paths, metadata and content are never
+// changed and a copy modified since the scan is simply left alone.
+// The first copy of each set that is a regular file on disk is the source; archive members
+// and S3 objects are skipped.
+func Dedupe(Sets [][]string, Options DedupeOptions) []DedupeResult {
+	results := make([]DedupeResult, 0, len(Sets))
+	for _, set := range Sets {
+		var result DedupeResult
+		for _, path := range set {
+			path = filepath.Clean(path)
+			if result.Source == "" {
+				if isRegularFile(path) {
+					result.Source = path
+				}
+				continue
+			}
+			outcome := Outcome{Path: path, Destination: result.Source}
+			var deduped int64
+			_, outcome.Err = audited(AuditDedupe, path, "", Options.DryRun, func() (string, error) {
+				var err error
+				deduped, err = dedupeCopy(result.Source, path, Options)
+				return result.Source, err
+			})
+			result.ReclaimedBytes += deduped
+			result.Outcomes = append(result.Outcomes, outcome)
+		}
+		if result.Source != "" && len(result.Outcomes) > 0 {
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// dedupeCopy shares the extents of a copy with the source and returns the bytes deduplicated.
+func dedupeCopy(source string, path string, Options DedupeOptions) (int64, error) {
+	if !isRegularFile(path) {
+		return 0, ErrNotRegular
+	}
+	sourceInfo, err := os.Stat(source)
+	if err != nil {
+		return 0, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	if info.Size() != sourceInfo.Size() {
+		return 0, ErrChanged
+	}
+	if os.SameFile(info, sourceInfo) {
+		return 0, nil // A hardlink already shares everything
+	}
+	if Options.DryRun {
+		return info.Size(), nil
+	}
+	return dedupeFile(source, path, info.Size())
+}
@@ -0,0 +1,98 @@
+//go:build linux
+
+package actions
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// FIDEDUPERANGE ioctl, _IOWR(0x94, 54, struct file_dedupe_range).
+const fideduperange = 0xC0189436
+
+// Layout of struct file_dedupe_range with a single struct file_dedupe_range_info.
+const (
+	dedupeRangeHeaderSize = 24 // src_offset, src_length, dest_count, reserved1, reserved2
+	dedupeRangeInfoSize   = 32 // dest_fd, dest_offset, bytes_deduped, status, reserved
+
+	dedupeRangeSame    = 0 // FILE_DEDUPE_RANGE_SAME
+	dedupeRangeDiffers = 1 // FILE_DEDUPE_RANGE_DIFFERS
+)
+
+// dedupeChunkSize bounds each request; filesystems cap the length of a single dedupe anyway.
+const dedupeChunkSize = 16 * 1024 * 1024
+
+// dedupeFile asks the kernel to share the extents of Size bytes of source with path.
+func dedupeFile(source string, path string, Size int64) (int64, error) {
+	sourceFile, err := os.Open(source)
+	if err != nil {
+		return 0, err
+	}
+	defer sourceFile.Close()
+
+	// The destination must be open for writing unless the caller owns it; no data is written.
+	destFile, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		if destFile, err = os.Open(path); err != nil {
+			return 0, err
+		}
+	}
+	defer destFile.Close()
+
+	request := make([]byte, dedupeRangeHeaderSize+dedupeRangeInfoSize)
+	info := request[dedupeRangeHeaderSize:]
+	var deduped int64
+	for deduped < Size {
+		length := Size - deduped
+		if length > dedupeChunkSize {
+			length = dedupeChunkSize
+		}
+		clear(request)
+		binary.NativeEndian.PutUint64(request[0:], uint64(deduped))
+		binary.NativeEndian.PutUint64(request[8:], uint64(length))
+		binary.NativeEndian.PutUint16(request[16:], 1)
+		binary.NativeEndian.PutUint64(info[0:], uint64(destFile.Fd()))
+		binary.NativeEndian.PutUint64(info[8:], uint64(deduped))
+
+		_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, sourceFile.Fd(), fideduperange, uintptr(unsafe.Pointer(&request[0])))
+		runtime.KeepAlive(destFile)
+		if errno != 0 {
+			return deduped, dedupeError(errno)
+		}
+
+		switch status := int32(binary.NativeEndian.Uint32(info[24:])); {
+		case status == dedupeRangeDiffers:
+			return deduped, ErrContentDiffers
+		case status < 0:
+			return deduped, dedupeError(syscall.Errno(-status))
+		case status != dedupeRangeSame:
+			return deduped, syscall.EIO
+		}
+
+		n := int64(binary.NativeEndian.Uint64(info[16:]))
+		if n <= 0 {
+			break
+		}
+		deduped += n
+	}
+	// A filesystem may stop sharing early, e.g. at a partial last block; the copy then still
+	// holds its own data.
+	if deduped < Size {
+		return deduped, fmt.Errorf("deduplicated %d of %d bytes", deduped, Size)
+	}
+	return deduped, nil
+}
+
+// dedupeError maps the errors of filesystems without extent sharing to ErrDedupeUnsupported.
+// EINVAL is kept as is, since it also reports bad offsets or lengths on filesystems that do share.
+func dedupeError(errno syscall.Errno) error {
+	switch errno {
+	case syscall.EOPNOTSUPP, syscall.ENOTTY, syscall.EXDEV:
+		return ErrDedupeUnsupported
+	}
+	return errno
+}
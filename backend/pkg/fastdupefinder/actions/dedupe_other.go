@@ -0,0 +1,8 @@
+//go:build !linux
+
+package actions
+
+// dedupeFile reports that FIDEDUPERANGE is only available on Linux.
+func dedupeFile(source string, path string, Size int64) (int64, error) {
+	return 0, ErrDedupeUnsupported
+}
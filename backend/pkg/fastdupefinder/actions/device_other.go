@@ -0,0 +1,26 @@
+//go:build !unix
+
+package actions
+
+import (
+	"errors"
+	"os"
+)
+
+// deviceOf reports that device numbers are not available on this platform.
+func deviceOf(path string) (uint64, error) {
+	return 0, errors.New("device numbers are not available on this platform")
+}
+
+// sameMetadata reports whether two files have the same permissions.
+func sameMetadata(a os.FileInfo, b os.FileInfo) bool {
+	return a.Mode().Perm() == b.Mode().Perm()
+}
+
+// ownerOf reports that files have no Unix owner on this platform.
+func ownerOf(info os.FileInfo) (int, int, bool) {
+	return 0, 0, false
+}
+
+// preserveOwner does nothing on platforms without Unix file owners.
+func preserveOwner(link string, original os.FileInfo) {}
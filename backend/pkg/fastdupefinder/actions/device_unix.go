@@ -0,0 +1,49 @@
+//go:build unix
+
+package actions
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// deviceOf returns the device number of the filesystem holding a path, without following symlinks.
+func deviceOf(path string) (uint64, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return 0, err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, fmt.Errorf("no device number for %s", path)
+	}
+	return uint64(stat.Dev), nil
+}
+
+// sameMetadata reports whether two files have the same permissions and owner.
+func sameMetadata(a os.FileInfo, b os.FileInfo) bool {
+	if a.Mode().Perm() != b.Mode().Perm() {
+		return false
+	}
+	statA, okA := a.Sys().(*syscall.Stat_t)
+	statB, okB := b.Sys().(*syscall.Stat_t)
+	return okA && okB && statA.Uid == statB.Uid && statA.Gid == statB.Gid
+}
+
+// ownerOf returns the user and group owning a file.
+func ownerOf(info os.FileInfo) (int, int, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return int(stat.Uid), int(stat.Gid), true
+}
+
+// preserveOwner gives a new link the owner of the file it replaces. Only the superuser may
+// change the owner, so failures are ignored.
+func preserveOwner(link string, original os.FileInfo) {
+	if stat, ok := original.Sys().(*syscall.Stat_t); ok {
+		os.Lchown(link, int(stat.Uid), int(stat.Gid))
+	}
+}
@@ -0,0 +1,310 @@
+package actions
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/archives"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/helpers"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/s3"
+	reporttypes "github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types/report_types"
+)
+
+// LinkKind selects the kind of link that replaces a duplicate.
+type LinkKind int
+
+const (
+	// LinkHard makes the duplicate another name of the keeper's file.
+	LinkHard LinkKind = iota
+	// LinkSymbolic replaces the duplicate with a symlink to the keeper's absolute path.
+	LinkSymbolic
+	// LinkRelativeSymbolic replaces the duplicate with a symlink to the keeper's path relative
+	// to the duplicate's folder, which survives moving both together.
+	LinkRelativeSymbolic
+)
+
+// LinkOptions controls how Link replaces duplicates.
+type LinkOptions struct {
+	Kind LinkKind
+	// DryRun checks every path as usual but leaves all files in place.
+	DryRun bool
+}
+
+var (
+	// ErrCrossDevice is returned for hardlinks in sets whose copies are on different filesystems.
+	ErrCrossDevice = errors.New("the set spans several filesystems, which hardlinks can't")
+	// ErrMetadataDiffers is returned for hardlinks to a keeper with other permissions or owner,
+	// since the path would take over the keeper's.
+	ErrMetadataDiffers = errors.New("permissions or owner differ from the kept copy")
+	// ErrChanged is returned for copies whose content no longer matches the kept copy.
+	ErrChanged = errors.New("file changed since the scan")
+	// ErrNotRegular is returned for paths that are not regular files.
+	ErrNotRegular = errors.New("only regular files can be replaced by links")
+)
+
+// Link replaces Paths, each of which must be a copy in one of the file Sets, with links to a
+// copy of the same set that is kept: the first copy that isn't in Paths and is still a regular file.
+// Every copy is compared byte for byte with the keeper first and refused with ErrChanged if
+// they differ. Every link is created under a temporary name next to the copy and renamed over it,
+// so the path always holds either the copy or the link. Symlinks take over the times of the copy,
+// and its owner where permitted; hardlinks share the keeper's metadata and are refused if its
+// permissions or owner differ. Sets whose copies are on several filesystems are skipped when hardlinking.
+// Outcomes are returned in the order of Paths, with the kept copy as Destination.
+func Link(Sets [][]string, Paths []string, Options LinkOptions) []Outcome {
+	keepers, refused := assignKeepers(Sets, Paths)
+	return link(Paths, keepers, refused, nil, Options)
+}
+
+// LinkFromReport is Link for the sets of a report, guarded by Revalidate: the paths of sets that
+// changed since the scan are refused with the reason, and every other path is linked to the kept
+// copy its check verified, which honours the marks of the report.
+func LinkFromReport(Report reporttypes.ReportOutput, Paths []string, Options LinkOptions) []Outcome {
+	keepers := make(map[string]string)
+	refused := make(map[string]error)
+	checks, hashes := revalidate(Report, Paths)
+	for _, check := range checks {
+		for _, path := range check.Paths {
+			switch {
+			case check.Err != nil:
+				refused[path] = check.Err
+				delete(keepers, path)
+			case refused[path] != nil:
+			case archives.IsVirtualPath(path) || s3.IsObjectPath(path):
+				refused[path] = ErrNotOnDisk
+			case keepers[path] == "":
+				keepers[path] = check.Keeper
+			}
+		}
+	}
+	return link(Paths, keepers, refused, hashes, Options)
+}
+
+// link replaces the paths that aren't refused with links to their keepers, auditing them with the
+// hashes known so far.
+func link(Paths []string, keepers map[string]string, refused map[string]error, hashes map[string]string, Options LinkOptions) []Outcome {
+	// Hardlinks need the whole set on the keeper's filesystem.
+	if Options.Kind == LinkHard {
+		for _, set := range groupByKeeper(keepers) {
+			if !sameDevice(set) {
+				for _, path := range set[1:] {
+					refused[path] = ErrCrossDevice
+				}
+			}
+		}
+	}
+
+	outcomes := make([]Outcome, 0, len(Paths))
+	done := make(map[string]bool)
+	for _, path := range Paths {
+		path = filepath.Clean(path)
+		if done[path] {
+			continue
+		}
+		done[path] = true
+
+		outcome := Outcome{Path: path, Destination: keepers[path], Err: refused[path]}
+		if outcome.Err == nil {
+			operation := AuditSymlink
+			if Options.Kind == LinkHard {
+				operation = AuditHardlink
+			}
+			_, outcome.Err = audited(operation, path, hashes[path], Options.DryRun, func() (string, error) {
+				return keepers[path], replaceWithLink(path, keepers[path], Options)
+			})
+		}
+		outcomes = append(outcomes, outcome)
+	}
+	return outcomes
+}
+
+// assignKeepers picks the kept copy for every path and returns the reason refused paths can't be replaced.
+func assignKeepers(Sets [][]string, Paths []string) (map[string]string, map[string]error) {
+	keepers := make(map[string]string)
+	refused := make(map[string]error)
+	targets := make(map[string]bool, len(Paths))
+	for _, path := range Paths {
+		path = filepath.Clean(path)
+		if archives.IsVirtualPath(path) || s3.IsObjectPath(path) {
+			refused[path] = ErrNotOnDisk
+			continue
+		}
+		targets[path] = true
+	}
+
+	for _, set := range Sets {
+		var replaced []string
+		for _, copyPath := range set {
+			if copyPath = filepath.Clean(copyPath); targets[copyPath] {
+				replaced = append(replaced, copyPath)
+			}
+		}
+		keeper := chooseKeeper(set, targets, nil, isRegularFile)
+		for _, path := range replaced {
+			if keeper == "" {
+				refused[path] = ErrLastCopy
+			} else {
+				keepers[path] = keeper
+			}
+		}
+	}
+
+	for path := range targets {
+		if _, ok := keepers[path]; !ok && refused[path] == nil {
+			refused[path] = ErrNotInSet
+		}
+	}
+	return keepers, refused
+}
+
+// chooseKeeper returns the copy of a set that is kept while the targets are acted on: the first
+// copy marked as kept, or else the first copy, that isn't a target and that usable accepts.
+// It returns "" if there is none. Link and Revalidate both pick their keepers this way.
+func chooseKeeper(set []string, targets map[string]bool, marks map[string]string, usable func(path string) bool) string {
+	keeper := ""
+	for _, path := range set {
+		path = filepath.Clean(path)
+		if targets[path] || !usable(path) {
+			continue
+		}
+		if marks[path] == reporttypes.MarkKeep {
+			return path
+		}
+		if keeper == "" {
+			keeper = path
+		}
+	}
+	return keeper
+}
+
+// groupByKeeper returns every keeper followed by the paths linked to it.
+func groupByKeeper(keepers map[string]string) [][]string {
+	index := make(map[string]int)
+	var sets [][]string
+	for path, keeper := range keepers {
+		i, ok := index[keeper]
+		if !ok {
+			i = len(sets)
+			index[keeper] = i
+			sets = append(sets, []string{keeper})
+		}
+		sets[i] = append(sets[i], path)
+	}
+	return sets
+}
+
+// sameDevice reports whether all paths are on the same filesystem.
+func sameDevice(paths []string) bool {
+	first, err := deviceOf(paths[0])
+	if err != nil {
+		return false
+	}
+	for _, path := range paths[1:] {
+		if device, err := deviceOf(path); err != nil || device != first {
+			return false
+		}
+	}
+	return true
+}
+
+// isRegularFile reports whether a path is a regular file, without following symlinks.
+func isRegularFile(path string) bool {
+	if archives.IsVirtualPath(path) || s3.IsObjectPath(path) {
+		return false
+	}
+	info, err := os.Lstat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// replaceWithLink atomically replaces a copy with a link to the keeper.
+func replaceWithLink(path string, keeper string, Options LinkOptions) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return ErrNotRegular
+	}
+	keeperInfo, err := os.Stat(keeper)
+	if err != nil {
+		return err
+	}
+	if os.SameFile(info, keeperInfo) {
+		return nil // Already a hardlink of the keeper
+	}
+	if Options.Kind == LinkHard && !sameMetadata(info, keeperInfo) {
+		return ErrMetadataDiffers
+	}
+	// Equal sizes make the copy a prefix of the keeper exactly when their contents are equal.
+	if info.Size() != keeperInfo.Size() {
+		return ErrChanged
+	}
+	if same, err := helpers.IsPrefixOf(path, keeper); err != nil {
+		return err
+	} else if !same {
+		return ErrChanged
+	}
+	if Options.DryRun {
+		return nil
+	}
+
+	temp, err := createLink(path, keeper, Options.Kind)
+	if err != nil {
+		return err
+	}
+	if Options.Kind != LinkHard {
+		preserveOwner(temp, info)
+		if err := preserveLinkTimes(temp, info); err != nil {
+			os.Remove(temp)
+			return err
+		}
+	}
+	if err := os.Rename(temp, path); err != nil {
+		os.Remove(temp)
+		return err
+	}
+	return nil
+}
+
+// createLink creates a link to the keeper under an unused temporary name next to path.
+func createLink(path string, keeper string, kind LinkKind) (string, error) {
+	target := keeper
+	switch kind {
+	case LinkSymbolic:
+		absKeeper, err := filepath.Abs(keeper)
+		if err != nil {
+			return "", err
+		}
+		target = absKeeper
+	case LinkRelativeSymbolic:
+		absKeeper, err := filepath.Abs(keeper)
+		if err != nil {
+			return "", err
+		}
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return "", err
+		}
+		if target, err = filepath.Rel(filepath.Dir(absPath), absKeeper); err != nil {
+			return "", err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		temp := filepath.Join(filepath.Dir(path), fmt.Sprintf(".%s.%d.link-tmp", filepath.Base(path), rand.Int63()))
+		var err error
+		if kind == LinkHard {
+			err = os.Link(keeper, temp)
+		} else {
+			err = os.Symlink(target, temp)
+		}
+		if err == nil {
+			return temp, nil
+		}
+		if !os.IsExist(err) || attempt == 9 {
+			return "", err
+		}
+	}
+}
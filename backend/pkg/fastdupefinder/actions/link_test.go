@@ -0,0 +1,82 @@
+package actions
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestReplaceWithLink(t *testing.T) {
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name    string
+		kind    LinkKind
+		copy    string // Content of the copy; the keeper holds "same content"
+		dryRun  bool
+		wantErr error
+		linked  bool // Whether the copy is replaced by a link
+	}{
+		{name: "hardlink", kind: LinkHard, copy: "same content", linked: true},
+		{name: "symlink", kind: LinkSymbolic, copy: "same content", linked: true},
+		{name: "relative symlink", kind: LinkRelativeSymbolic, copy: "same content", linked: true},
+		{name: "dry run", kind: LinkSymbolic, copy: "same content", dryRun: true},
+		{name: "other content of the same size", kind: LinkSymbolic, copy: "same CONTENT", wantErr: ErrChanged},
+		{name: "other size", kind: LinkHard, copy: "longer content", wantErr: ErrChanged},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			keeper := filepath.Join(dir, "keeper.txt")
+			path := filepath.Join(dir, "sub", "copy.txt")
+			os.Mkdir(filepath.Dir(path), 0755)
+			if err := os.WriteFile(keeper, []byte("same content"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(test.copy), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chtimes(path, modTime, modTime); err != nil {
+				t.Fatal(err)
+			}
+
+			err := replaceWithLink(path, keeper, LinkOptions{Kind: test.kind, DryRun: test.dryRun})
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("replaceWithLink returned %v, want %v", err, test.wantErr)
+			}
+
+			info, err := os.Lstat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			keeperInfo, _ := os.Stat(keeper)
+			switch {
+			case !test.linked:
+				if data, _ := os.ReadFile(path); string(data) != test.copy || !info.Mode().IsRegular() {
+					t.Errorf("copy was modified")
+				}
+			case test.kind == LinkHard:
+				if !os.SameFile(info, keeperInfo) {
+					t.Errorf("copy is not a hardlink of the keeper")
+				}
+			default:
+				if info.Mode()&os.ModeSymlink == 0 {
+					t.Fatalf("copy is not a symlink")
+				}
+				target, _ := os.Stat(path)
+				if !os.SameFile(target, keeperInfo) {
+					t.Errorf("symlink doesn't point to the keeper")
+				}
+				// Only Linux sets the times of the symlink itself, see preserveLinkTimes.
+				if runtime.GOOS == "linux" && !info.ModTime().Equal(modTime) {
+					t.Errorf("symlink modified at %v, want the copy's %v", info.ModTime(), modTime)
+				}
+			}
+			if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+				t.Errorf("temporary links left behind: %v", entries)
+			}
+		})
+	}
+}
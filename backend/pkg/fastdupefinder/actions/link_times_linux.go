@@ -0,0 +1,37 @@
+//go:build linux
+
+package actions
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// Arguments of utimensat, from <fcntl.h>.
+const (
+	atFdcwd           = -0x64 // AT_FDCWD
+	atSymlinkNofollow = 0x100 // AT_SYMLINK_NOFOLLOW
+)
+
+// preserveLinkTimes gives a new symlink the access and modification times of the file it
+// replaces. utimensat with AT_SYMLINK_NOFOLLOW sets the times of the symlink itself, which
+// os.Chtimes can't since it follows links.
+func preserveLinkTimes(link string, original os.FileInfo) error {
+	path, err := syscall.BytePtrFromString(link)
+	if err != nil {
+		return err
+	}
+	modified := syscall.NsecToTimespec(original.ModTime().UnixNano())
+	times := [2]syscall.Timespec{modified, modified}
+	if stat, ok := original.Sys().(*syscall.Stat_t); ok {
+		times[0] = stat.Atim
+	}
+	dirFd := atFdcwd
+	_, _, errno := syscall.Syscall6(syscall.SYS_UTIMENSAT, uintptr(dirFd), uintptr(unsafe.Pointer(path)),
+		uintptr(unsafe.Pointer(&times[0])), atSymlinkNofollow, 0, 0)
+	if errno != 0 {
+		return &os.PathError{Op: "utimensat", Path: link, Err: errno}
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package actions
+
+import "os"
+
+// preserveLinkTimes does nothing where the times of a symlink itself can't be set through the
+// syscall package; symlinks keep the time they were created.
+func preserveLinkTimes(link string, original os.FileInfo) error {
+	return nil
+}
@@ -0,0 +1,353 @@
+package actions
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/helpers"
+	reporttypes "github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types/report_types"
+)
+
+var (
+	// ErrDestinationExists is returned instead of overwriting an existing file.
+	ErrDestinationExists = errors.New("destination already exists")
+	// ErrVerifyFailed is returned when a copied or quarantined file doesn't match its recorded hash.
+	ErrVerifyFailed = errors.New("content doesn't match the recorded hash")
+	// ErrNotQuarantined is returned by Restore for files whose move was recorded but never happened,
+	// as after a crash, which are still at their original path.
+	ErrNotQuarantined = errors.New("file was never moved to quarantine")
+)
+
+// QuarantineOptions controls how Quarantine moves copies.
+type QuarantineOptions struct {
+	// Directory receives the copies below their original absolute path, e.g.
+	// "/home/me/a.txt" moves to Directory + "/home/me/a.txt".
+	Directory string
+	// DryRun checks every path as usual but leaves all files in place and writes no manifest.
+	DryRun bool
+}
+
+// QuarantineEntry is one line of a quarantine manifest, describing a single quarantined file.
+type QuarantineEntry struct {
+	OriginalPath   string      `json:"originalPath"`
+	QuarantinePath string      `json:"quarantinePath"`
+	Hash           string      `json:"hash"` // Full xxhash of the content, as in CalculateHash
+	SizeBytes      int64       `json:"sizeBytes"`
+	ModTime        time.Time   `json:"modTime"`
+	Mode           os.FileMode `json:"mode"`
+	UID            *int        `json:"uid,omitempty"` // Owner, unset on platforms without Unix owners
+	GID            *int        `json:"gid,omitempty"`
+
+	// Error is set on a second line for the same file if its move failed, so Restore skips it.
+	Error string `json:"error,omitempty"`
+}
+
+// Quarantine moves Paths, each of which must be a copy in one of the file Sets, into the
+// quarantine directory instead of deleting them, keeping at least one copy of every set like Remove.
+// Moves across filesystems copy the file, verify the copy against the hash of the original
+// and only then remove the original.
+// Every move is first appended to a new manifest-<time>.jsonl file in the directory and synced
+// to disk, so Restore can undo all moves that happened even if the process is interrupted. Failed
+// moves are recorded as such. If the manifest can't be written, no further file is moved and the
+// error is returned. The manifest path is returned unless nothing was recorded, and outcomes are
+// returned in the order of Paths.
+func Quarantine(Sets [][]string, Paths []string, Options QuarantineOptions) (string, []Outcome, error) {
+	return quarantine(Paths, checkRemovals(Sets, Paths), nil, Options)
+}
+
+// QuarantineFromReport is Quarantine for the file sets of a report, guarded by Revalidate: the
+// paths of sets that changed since the scan are refused with the reason.
+func QuarantineFromReport(Report reporttypes.ReportOutput, Paths []string, Options QuarantineOptions) (string, []Outcome, error) {
+	refused, hashes := revalidatedRemovals(Report, FileSetsFromReport(Report), Paths)
+	return quarantine(Paths, refused, hashes, Options)
+}
+
+// quarantine moves the paths that aren't refused, auditing them with the hashes known so far.
+func quarantine(Paths []string, refused map[string]error, hashes map[string]string, Options QuarantineOptions) (string, []Outcome, error) {
+	directory, err := filepath.Abs(Options.Directory)
+	if err != nil {
+		return "", nil, err
+	}
+	manifest := &manifestWriter{path: filepath.Join(directory, "manifest-"+time.Now().Format("20060102T150405.000")+".jsonl")}
+	defer manifest.close()
+
+	outcomes := make([]Outcome, 0, len(Paths))
+	done := make(map[string]bool)
+	for _, path := range Paths {
+		path = filepath.Clean(path)
+		if done[path] {
+			continue
+		}
+		done[path] = true
+
+		outcome := Outcome{Path: path, Err: refused[path]}
+		if outcome.Err == nil {
+			var manifestErr error
+			outcome.Destination, outcome.Err = audited(AuditQuarantine, path, hashes[path], Options.DryRun, func() (string, error) {
+				entry, err := checkQuarantine(path, directory, Options.DryRun)
+				if err != nil || Options.DryRun {
+					return entry.QuarantinePath, err
+				}
+				if manifestErr = manifest.append(entry); manifestErr != nil {
+					return "", fmt.Errorf("writing manifest: %w", manifestErr)
+				}
+				if err := moveVerified(entry.OriginalPath, entry.QuarantinePath, entry.Hash, 0700); err != nil {
+					entry.Error = err.Error()
+					manifestErr = manifest.append(entry)
+					return entry.QuarantinePath, err
+				}
+				return entry.QuarantinePath, nil
+			})
+			if manifestErr != nil {
+				outcomes = append(outcomes, outcome)
+				return manifest.written(), outcomes, fmt.Errorf("writing manifest %s: %w", manifest.path, manifestErr)
+			}
+		}
+		outcomes = append(outcomes, outcome)
+	}
+	return manifest.written(), outcomes, nil
+}
+
+// Restore moves the files of a quarantine manifest back to their original paths and restores
+// their owner, mode and modification time. Files whose original path is occupied again, or whose
+// content no longer matches the recorded hash, are left in quarantine. Files whose move failed
+// are skipped, and a last line cut short by an interrupted Quarantine is ignored, since the move
+// it describes never started.
+func Restore(ManifestPath string, DryRun bool) ([]Outcome, error) {
+	data, err := os.ReadFile(ManifestPath)
+	if err != nil {
+		return nil, err
+	}
+	var entries []QuarantineEntry
+	failed := make(map[string]bool)
+	lines := bytes.Split(bytes.TrimRight(data, "\n"), []byte("\n"))
+	for i, line := range lines {
+		var entry QuarantineEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			if i == len(lines)-1 && !bytes.HasSuffix(data, []byte("\n")) {
+				break
+			}
+			return nil, fmt.Errorf("reading manifest %s: line %d: %w", ManifestPath, i+1, err)
+		}
+		if entry.Error != "" {
+			failed[entry.QuarantinePath] = true
+		} else {
+			entries = append(entries, entry)
+		}
+	}
+
+	outcomes := make([]Outcome, 0, len(entries))
+	for _, entry := range entries {
+		if failed[entry.QuarantinePath] {
+			continue
+		}
+		outcome := Outcome{Path: entry.QuarantinePath, Destination: entry.OriginalPath}
+		_, outcome.Err = audited(AuditRestore, entry.QuarantinePath, entry.Hash, DryRun, func() (string, error) {
+			return entry.OriginalPath, restoreFile(entry, DryRun)
+		})
+		outcomes = append(outcomes, outcome)
+	}
+	return outcomes, nil
+}
+
+// checkQuarantine checks that a file can be moved into the quarantine directory and returns its
+// manifest entry. The hash is only computed outside dry runs.
+func checkQuarantine(path string, directory string, DryRun bool) (QuarantineEntry, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return QuarantineEntry{}, err
+	}
+	entry := QuarantineEntry{OriginalPath: absPath, QuarantinePath: mirrorPath(directory, absPath)}
+
+	info, err := os.Lstat(absPath)
+	if err != nil {
+		return entry, err
+	}
+	if !info.Mode().IsRegular() {
+		return entry, ErrNotRegular
+	}
+	if _, err := os.Lstat(entry.QuarantinePath); err == nil {
+		return entry, ErrDestinationExists
+	}
+	entry.SizeBytes, entry.ModTime, entry.Mode = info.Size(), info.ModTime(), info.Mode()
+	if uid, gid, ok := ownerOf(info); ok {
+		entry.UID, entry.GID = &uid, &gid
+	}
+	if DryRun {
+		return entry, nil
+	}
+
+	entry.Hash, err = helpers.CalculateHash(absPath, false)
+	return entry, err
+}
+
+// restoreFile moves a quarantined file back to its original path.
+func restoreFile(entry QuarantineEntry, DryRun bool) error {
+	if _, err := os.Lstat(entry.OriginalPath); err == nil {
+		if _, err := os.Lstat(entry.QuarantinePath); errors.Is(err, os.ErrNotExist) {
+			return ErrNotQuarantined
+		}
+		return ErrDestinationExists
+	}
+	hash, err := helpers.CalculateHash(entry.QuarantinePath, false)
+	if err != nil {
+		return err
+	}
+	if hash != entry.Hash {
+		return ErrVerifyFailed
+	}
+	if DryRun {
+		return nil
+	}
+
+	if err := moveVerified(entry.QuarantinePath, entry.OriginalPath, entry.Hash, 0755); err != nil {
+		return err
+	}
+	// The owner is set before the mode, since changing it may clear setuid and setgid bits.
+	if entry.UID != nil && entry.GID != nil {
+		if err := setOwner(entry.OriginalPath, *entry.UID, *entry.GID); err != nil {
+			return err
+		}
+	}
+	if err := os.Chmod(entry.OriginalPath, entry.Mode.Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(entry.OriginalPath, entry.ModTime, entry.ModTime)
+}
+
+// mirrorPath returns the location of an absolute path below the quarantine directory.
+// A Windows volume such as "C:" becomes a folder named "C".
+func mirrorPath(directory string, absPath string) string {
+	volume := filepath.VolumeName(absPath)
+	rest := strings.TrimLeft(absPath[len(volume):], `/\`)
+	volume = strings.Trim(strings.ReplaceAll(volume, ":", ""), `/\`)
+	return filepath.Join(directory, volume, rest)
+}
+
+// moveVerified moves a file, creating missing parent folders with DirMode. If the destination
+// is on another filesystem, the file is copied with its owner, mode and modification time, the copy is
+// checked against Hash and the source is removed only if it matches.
+func moveVerified(source string, destination string, Hash string, DirMode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(destination), DirMode); err != nil {
+		return err
+	}
+	if _, err := os.Lstat(destination); err == nil {
+		return ErrDestinationExists
+	}
+
+	err := os.Rename(source, destination)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	if err := copyFile(source, destination); err != nil {
+		return err
+	}
+	copied, err := helpers.CalculateHash(destination, false)
+	if err == nil && copied != Hash {
+		err = ErrVerifyFailed
+	}
+	if err != nil {
+		os.Remove(destination)
+		return err
+	}
+	return os.Remove(source)
+}
+
+// copyFile copies a file to a new destination with the owner, mode and modification time of the
+// source. A partial copy is removed.
+func copyFile(source string, destination string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(destination, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if uid, gid, ok := ownerOf(info); ok && err == nil {
+		err = setOwner(destination, uid, gid)
+	}
+	if err == nil {
+		err = os.Chtimes(destination, info.ModTime(), info.ModTime())
+	}
+	if err != nil {
+		os.Remove(destination)
+	}
+	return err
+}
+
+// setOwner gives a file the owner it had before it was moved. Only the superuser may give files
+// to other users, so for everyone else a failure is logged and the file keeps their ownership.
+func setOwner(path string, uid int, gid int) error {
+	err := os.Lchown(path, uid, gid)
+	if err != nil && os.Geteuid() != 0 {
+		log.Printf("Warning: could not restore the owner of %s: %v", path, err)
+		return nil
+	}
+	return err
+}
+
+// manifestWriter appends entries to a quarantine manifest, one JSON line each, creating the file
+// on the first entry. Every line is synced to disk before append returns.
+type manifestWriter struct {
+	path string
+	file *os.File
+}
+
+func (m *manifestWriter) append(entry QuarantineEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if m.file == nil {
+		if err := os.MkdirAll(filepath.Dir(m.path), 0700); err != nil {
+			return err
+		}
+		file, err := os.OpenFile(m.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return err
+		}
+		m.file = file
+	}
+	if _, err := m.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return m.file.Sync()
+}
+
+// written returns the path of the manifest, or "" if no entry was written.
+func (m *manifestWriter) written() string {
+	if m.file == nil {
+		return ""
+	}
+	return m.path
+}
+
+func (m *manifestWriter) close() {
+	if m.file != nil {
+		m.file.Close()
+	}
+}
@@ -0,0 +1,169 @@
+package actions
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQuarantineAndRestore(t *testing.T) {
+	tests := []struct {
+		name string
+		// damage changes the manifest or the files after Quarantine, before Restore.
+		damage func(t *testing.T, manifestPath string, quarantined string)
+		// wantErr is the error Restore reports for the quarantined copy, nil if it is restored.
+		wantErr error
+	}{
+		{name: "round trip"},
+		{
+			name: "line cut short by an interruption",
+			damage: func(t *testing.T, manifestPath string, quarantined string) {
+				appendToFile(t, manifestPath, `{"originalPath":"/never/moved`)
+			},
+		},
+		{
+			name: "content changed in quarantine",
+			damage: func(t *testing.T, manifestPath string, quarantined string) {
+				os.WriteFile(quarantined, []byte("changed"), 0600)
+			},
+			wantErr: ErrVerifyFailed,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			keeper := filepath.Join(dir, "files", "a.txt")
+			copyPath := filepath.Join(dir, "files", "b.txt")
+			modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+			writeTestFile(t, keeper, "content", 0644, modTime)
+			writeTestFile(t, copyPath, "content", 0640, modTime)
+
+			quarantineDir := filepath.Join(dir, "quarantine")
+			sets := [][]string{{keeper, copyPath}}
+			manifestPath, outcomes, err := Quarantine(sets, []string{copyPath}, QuarantineOptions{Directory: quarantineDir})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(outcomes) != 1 || outcomes[0].Err != nil {
+				t.Fatalf("quarantine outcomes %+v, want the copy moved", outcomes)
+			}
+			quarantined := outcomes[0].Destination
+			if _, err := os.Lstat(copyPath); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("copy still in place after quarantine: %v", err)
+			}
+			if data, err := os.ReadFile(quarantined); err != nil || string(data) != "content" {
+				t.Errorf("quarantined copy holds %q, %v", data, err)
+			}
+			if test.damage != nil {
+				test.damage(t, manifestPath, quarantined)
+			}
+
+			outcomes, err = Restore(manifestPath, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(outcomes) != 1 || !errors.Is(outcomes[0].Err, test.wantErr) {
+				t.Fatalf("restore outcomes %+v, want error %v", outcomes, test.wantErr)
+			}
+			if test.wantErr != nil {
+				return
+			}
+			info, err := os.Stat(copyPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != 0640 || !info.ModTime().Equal(modTime) {
+				t.Errorf("restored copy has mode %v and time %v", info.Mode().Perm(), info.ModTime())
+			}
+			if _, err := os.Lstat(quarantined); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("restored copy still in quarantine: %v", err)
+			}
+		})
+	}
+}
+
+func TestRestoreSkipsFailedAndUnmovedFiles(t *testing.T) {
+	dir := t.TempDir()
+	original := filepath.Join(dir, "a.txt")
+	writeTestFile(t, original, "content", 0644, time.Now())
+	manifestPath := filepath.Join(dir, "manifest.jsonl")
+	entry := `{"originalPath":"` + original + `","quarantinePath":"` + filepath.Join(dir, "q", "a.txt") + `","hash":"x"`
+	// The first file's move failed; the second was recorded but the process stopped before moving it.
+	appendToFile(t, manifestPath, entry+`}`+"\n"+entry+`,"error":"disk full"}`+"\n")
+	other := strings.ReplaceAll(entry, "a.txt", "b.txt")
+	writeTestFile(t, filepath.Join(dir, "b.txt"), "content", 0644, time.Now())
+	appendToFile(t, manifestPath, other+"}\n")
+
+	outcomes, err := Restore(manifestPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outcomes) != 1 || !errors.Is(outcomes[0].Err, ErrNotQuarantined) {
+		t.Errorf("restore outcomes %+v, want only the unmoved file with ErrNotQuarantined", outcomes)
+	}
+}
+
+func TestCopyFileKeepsOwner(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "a.txt")
+	writeTestFile(t, source, "content", 0644, time.Now())
+	if os.Geteuid() == 0 {
+		// Only the superuser can tell a kept owner from the copier's own.
+		if err := os.Lchown(source, 1234, 5678); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sourceInfo, err := os.Lstat(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid, gid, ok := ownerOf(sourceInfo)
+	if !ok {
+		t.Skip("files have no Unix owner on this platform")
+	}
+
+	destination := filepath.Join(dir, "b.txt")
+	if err := copyFile(source, destination); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Lstat(destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotUID, gotGID, _ := ownerOf(info); gotUID != uid || gotGID != gid {
+		t.Errorf("copy is owned by %d:%d, want %d:%d", gotUID, gotGID, uid, gid)
+	}
+}
+
+// writeTestFile creates a file with its folder, mode and modification time.
+func writeTestFile(t *testing.T, path string, content string, mode os.FileMode, modTime time.Time) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), mode); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// appendToFile appends text to a file, creating it if needed.
+func appendToFile(t *testing.T, path string, text string) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(text); err != nil {
+		t.Fatal(err)
+	}
+}
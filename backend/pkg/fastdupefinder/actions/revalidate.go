@@ -0,0 +1,311 @@
+package actions
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/archives"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/helpers"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/s3"
+	reporttypes "github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types/report_types"
+)
+
+var (
+	// ErrNoKeeper is returned for sets whose copies that would be kept are all gone.
+	ErrNoKeeper = errors.New("no copy that would be kept is left on disk")
+	// ErrSizeChanged is returned for files whose size differs from the report.
+	ErrSizeChanged = errors.New("size differs from the report")
+	// ErrModified is returned for files modified after the scan started.
+	ErrModified = errors.New("modified after the scan started")
+	// ErrContentChanged is returned for files whose content no longer matches the report or the kept copy.
+	ErrContentChanged = errors.New("content no longer matches the report")
+)
+
+// SetCheck is the result of revalidating one duplicate set of a report.
+type SetCheck struct {
+	// Hash is the hash of a file set or the signature of a folder set, as in the report.
+	Hash string
+	// Keeper is the copy that is kept, which was checked along with Paths.
+	Keeper string
+	// Paths are the copies of the set that would be removed or replaced.
+	Paths []string
+	// Err is nil if the set can be acted on, otherwise the reason, naming the file that failed.
+	Err error
+}
+
+// Revalidate checks, right before acting on Paths, that the report still describes the disk.
+// For every set of the report holding some of Paths, nested sets included, the paths and the
+// copy that is kept, chosen like Link does (the first copy the report marks as kept, or else the
+// first other copy that is still a regular file, or a folder for folder sets), are stat'ed and
+// hashed again. The keeper is returned in the check, so the actions use exactly the copy checked.
+// The set fails if a size differs from the report, a file was modified after the scan started or
+// a hash no longer matches; folder sets fail if any file of the folders differs this way or from
+// the same file in the kept folder.
+// Paths that belong to no set get a check of their own failing with ErrNotInSet.
+func Revalidate(Report reporttypes.ReportOutput, Paths []string) []SetCheck {
+	checks, _ := revalidate(Report, Paths)
+	return checks
+}
+
+// revalidate is Revalidate, also returning the full hashes it computed by path, which the
+// actions record in the audit log instead of hashing the files again.
+func revalidate(Report reporttypes.ReportOutput, Paths []string) ([]SetCheck, map[string]string) {
+	v := validator{hashes: make(map[string]string), targets: make(map[string]bool), seen: make(map[string]bool)}
+	if Report.Summary.ScanStartedAt != nil {
+		v.startedAt = *Report.Summary.ScanStartedAt
+	}
+	for _, path := range Paths {
+		v.targets[filepath.Clean(path)] = true
+	}
+
+	inSet := make(map[string]bool)
+	var checks []SetCheck
+	addCheck := func(hash string, paths []string, marks map[string]string, isFolder bool, sizeBytes int64) {
+		if check, ok := v.check(hash, paths, marks, isFolder, sizeBytes); ok {
+			for _, path := range check.Paths {
+				inSet[path] = true
+			}
+			checks = append(checks, check)
+		}
+	}
+	var addFolders func(folderSets []reporttypes.FolderSet)
+	addFiles := func(fileSets []reporttypes.FileSet) {
+		for _, set := range fileSets {
+			addCheck(set.Hash, set.Paths, set.Marks, false, set.SizeBytes)
+		}
+	}
+	addFolders = func(folderSets []reporttypes.FolderSet) {
+		for _, set := range folderSets {
+			addCheck(set.Signature, set.Paths, set.Marks, true, set.SizeBytes)
+			if set.Nested != nil {
+				addFiles(set.Nested.FileDuplicates)
+				addFolders(set.Nested.FolderDuplicates)
+			}
+		}
+	}
+	addFiles(Report.FileDuplicates)
+	addFolders(Report.FolderDuplicates)
+
+	for _, path := range Paths {
+		path = filepath.Clean(path)
+		if !inSet[path] {
+			inSet[path] = true
+			checks = append(checks, SetCheck{Paths: []string{path}, Err: ErrNotInSet})
+		}
+	}
+	return checks, v.hashes
+}
+
+// ValidPaths returns the entries of Paths whose sets all passed revalidation, in the order of Paths.
+func ValidPaths(Paths []string, Checks []SetCheck) []string {
+	failed := make(map[string]bool)
+	for _, check := range Checks {
+		if check.Err != nil {
+			for _, path := range check.Paths {
+				failed[path] = true
+			}
+		}
+	}
+	var valid []string
+	for _, path := range Paths {
+		if !failed[filepath.Clean(path)] {
+			valid = append(valid, path)
+		}
+	}
+	return valid
+}
+
+// validator holds the state shared by the checks of one Revalidate call.
+type validator struct {
+	startedAt time.Time         // Zero if the report doesn't record it
+	hashes    map[string]string // Full hashes computed so far
+	targets   map[string]bool   // Cleaned paths being acted on
+	seen      map[string]bool   // Sets already checked, as listed under several folder sets
+}
+
+// check revalidates a single set and reports false if it holds none of the targets.
+func (v *validator) check(hash string, paths []string, marks map[string]string, isFolder bool, sizeBytes int64) (SetCheck, bool) {
+	check := SetCheck{Hash: hash}
+	for _, path := range paths {
+		if path = filepath.Clean(path); v.targets[path] {
+			check.Paths = append(check.Paths, path)
+		}
+	}
+	if len(check.Paths) == 0 {
+		return check, false
+	}
+	key := hash + "\x00" + strings.Join(check.Paths, "\x00")
+	if v.seen[key] {
+		return check, false
+	}
+	v.seen[key] = true
+
+	usable := isRegularFile
+	if isFolder {
+		usable = isFolderOnDisk
+	}
+	if check.Keeper = chooseKeeper(paths, v.targets, marks, usable); check.Keeper == "" {
+		check.Err = ErrNoKeeper
+		return check, true
+	}
+
+	for _, path := range append([]string{check.Keeper}, check.Paths...) {
+		if archives.IsVirtualPath(path) || s3.IsObjectPath(path) {
+			continue // Never acted on; the actions refuse them
+		}
+		var err error
+		if isFolder {
+			err = v.checkFolder(path, check.Keeper, sizeBytes)
+		} else {
+			err = v.checkFile(path, hash, sizeBytes)
+		}
+		if err != nil {
+			check.Err = err
+			break
+		}
+	}
+	return check, true
+}
+
+// checkFile checks a file against the size and truncated hash of its set.
+func (v *validator) checkFile(path string, hash string, sizeBytes int64) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s: %w", path, ErrContentChanged)
+	}
+	if err := v.checkStat(path, info, sizeBytes); err != nil {
+		return err
+	}
+	full, err := v.hash(path)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(full, hash) {
+		return fmt.Errorf("%s: %w", path, ErrContentChanged)
+	}
+	return nil
+}
+
+// checkFolder checks that a folder holds the same files as the kept folder, with unchanged sizes,
+// modification times before the scan and equal content. The kept folder is checked against the
+// total size of its set when compared with itself.
+func (v *validator) checkFolder(path string, keeper string, sizeBytes int64) error {
+	files, err := folderFiles(path)
+	if err != nil {
+		return err
+	}
+	if path == keeper {
+		var total int64
+		for name, info := range files {
+			if err := v.checkStat(filepath.Join(path, name), info, -1); err != nil {
+				return err
+			}
+			total += info.Size()
+		}
+		if sizeBytes >= 0 && total != sizeBytes {
+			return fmt.Errorf("%s: %w", path, ErrSizeChanged)
+		}
+		return nil
+	}
+
+	kept, err := folderFiles(keeper)
+	if err != nil {
+		return err
+	}
+	if len(files) != len(kept) {
+		return fmt.Errorf("%s: %w", path, ErrContentChanged)
+	}
+	for name, info := range files {
+		filePath := filepath.Join(path, name)
+		keptInfo, ok := kept[name]
+		if !ok {
+			return fmt.Errorf("%s: %w", filePath, ErrContentChanged)
+		}
+		if err := v.checkStat(filePath, info, keptInfo.Size()); err != nil {
+			return err
+		}
+		hash, err := v.hash(filePath)
+		if err != nil {
+			return err
+		}
+		keptHash, err := v.hash(filepath.Join(keeper, name))
+		if err != nil {
+			return err
+		}
+		if hash != keptHash {
+			return fmt.Errorf("%s: %w", filePath, ErrContentChanged)
+		}
+	}
+	return nil
+}
+
+// checkStat compares the size of a file with sizeBytes, unless it is negative, and its
+// modification time with the start of the scan.
+func (v *validator) checkStat(path string, info fs.FileInfo, sizeBytes int64) error {
+	if sizeBytes >= 0 && info.Size() != sizeBytes {
+		return fmt.Errorf("%s: %w", path, ErrSizeChanged)
+	}
+	if !v.startedAt.IsZero() && info.ModTime().After(v.startedAt) {
+		return fmt.Errorf("%s: %w", path, ErrModified)
+	}
+	return nil
+}
+
+// hash returns the full hash of a file, computing it once per Revalidate call.
+func (v *validator) hash(path string) (string, error) {
+	if hash, ok := v.hashes[path]; ok {
+		return hash, nil
+	}
+	hash, err := helpers.CalculateHash(path, false)
+	if err != nil {
+		return "", err
+	}
+	v.hashes[path] = hash
+	return hash, nil
+}
+
+// isFolderOnDisk reports whether a path is a folder, without following symlinks.
+func isFolderOnDisk(path string) bool {
+	if archives.IsVirtualPath(path) || s3.IsObjectPath(path) {
+		return false
+	}
+	info, err := os.Lstat(path)
+	return err == nil && info.IsDir()
+}
+
+// folderFiles returns the regular files below a folder by their path relative to it.
+// Folders holding other kinds of entries, such as symlinks, are reported as changed, since
+// they were never reported as duplicates.
+func folderFiles(dir string) (map[string]fs.FileInfo, error) {
+	files := make(map[string]fs.FileInfo)
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return fmt.Errorf("%s: %w", path, ErrContentChanged)
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[rel] = info
+		return nil
+	})
+	return files, err
+}
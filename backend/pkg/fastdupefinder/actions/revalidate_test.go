@@ -0,0 +1,113 @@
+package actions
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/helpers"
+	reporttypes "github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types/report_types"
+)
+
+func TestRevalidate(t *testing.T) {
+	tests := []struct {
+		name string
+		// damage changes the files after the scan.
+		damage     func(t *testing.T, dir string)
+		target     string // Name of the copy acted on; "a.txt" if empty
+		wantKeeper string // Name of the kept copy
+		wantErr    error
+	}{
+		// b.txt and c.txt are both marked as kept; the first one wins, as in Link.
+		{name: "unchanged", wantKeeper: "b.txt"},
+		{
+			name:    "size changed",
+			damage:  func(t *testing.T, dir string) { appendToFile(t, filepath.Join(dir, "a.txt"), "more") },
+			wantErr: ErrSizeChanged,
+		},
+		{
+			name: "modified after the scan",
+			damage: func(t *testing.T, dir string) {
+				os.Chtimes(filepath.Join(dir, "b.txt"), time.Now(), time.Now())
+			},
+			wantErr: ErrModified,
+		},
+		{
+			name: "content changed",
+			damage: func(t *testing.T, dir string) {
+				writeTestFile(t, filepath.Join(dir, "a.txt"), "CONTENT", 0644, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+			},
+			wantErr: ErrContentChanged,
+		},
+		{
+			name:       "marked keeper gone",
+			damage:     func(t *testing.T, dir string) { os.Remove(filepath.Join(dir, "b.txt")) },
+			wantKeeper: "c.txt",
+		},
+		{
+			name: "every other copy gone",
+			damage: func(t *testing.T, dir string) {
+				os.Remove(filepath.Join(dir, "b.txt"))
+				os.Remove(filepath.Join(dir, "c.txt"))
+			},
+			wantErr: ErrNoKeeper,
+		},
+		{name: "not in a set", target: "other.txt", wantErr: ErrNotInSet},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+			var paths []string
+			for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+				path := filepath.Join(dir, name)
+				writeTestFile(t, path, "content", 0644, modTime)
+				paths = append(paths, path)
+			}
+			hash, err := helpers.CalculateHash(paths[0], false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			startedAt := time.Now().Add(-time.Minute)
+			report := reporttypes.ReportOutput{
+				Summary: reporttypes.SummaryInfo{ScanStartedAt: &startedAt},
+				FileDuplicates: []reporttypes.FileSet{{
+					Hash:      hash[:16],
+					SizeBytes: int64(len("content")),
+					Paths:     paths,
+					Marks: map[string]string{
+						paths[0]: reporttypes.MarkRemove,
+						paths[1]: reporttypes.MarkKeep,
+						paths[2]: reporttypes.MarkKeep,
+					},
+				}},
+			}
+			if test.damage != nil {
+				test.damage(t, dir)
+			}
+			target := paths[0]
+			if test.target != "" {
+				target = filepath.Join(dir, test.target)
+			}
+
+			checks := Revalidate(report, []string{target})
+			if len(checks) != 1 || !errors.Is(checks[0].Err, test.wantErr) {
+				t.Fatalf("Revalidate returned %+v, want one check with error %v", checks, test.wantErr)
+			}
+			if test.wantKeeper != "" && checks[0].Keeper != filepath.Join(dir, test.wantKeeper) {
+				t.Errorf("kept copy is %s, want %s", checks[0].Keeper, test.wantKeeper)
+			}
+
+			// Link must refuse the failed sets and use the keeper that was checked.
+			outcomes := LinkFromReport(report, []string{target}, LinkOptions{Kind: LinkSymbolic, DryRun: true})
+			if len(outcomes) != 1 || !errors.Is(outcomes[0].Err, test.wantErr) {
+				t.Fatalf("LinkFromReport returned %+v, want error %v", outcomes, test.wantErr)
+			}
+			if test.wantErr == nil && outcomes[0].Destination != checks[0].Keeper {
+				t.Errorf("linked to %s, but revalidated %s", outcomes[0].Destination, checks[0].Keeper)
+			}
+		})
+	}
+}
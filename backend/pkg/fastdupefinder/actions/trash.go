@@ -0,0 +1,165 @@
+package actions
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// trashInfoSuffix ends the names of the files describing trashed items.
+const trashInfoSuffix = ".trashinfo"
+
+// moveToTrash moves a file or folder to the trash following the freedesktop.org Trash
+// specification and returns its location in the trash.
+// Paths on the filesystem of the home trash go to $XDG_DATA_HOME/Trash; paths on other
+// filesystems go to the trash at the top directory of their mount, since the move must be a rename.
+func moveToTrash(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	trashDir, topDir, err := trashDirFor(absPath)
+	if err != nil {
+		return "", err
+	}
+	for _, dir := range []string{filepath.Join(trashDir, "files"), filepath.Join(trashDir, "info")} {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return "", err
+		}
+	}
+
+	// Top directory trashes record paths relative to the top directory, so they stay valid
+	// if the filesystem is mounted elsewhere.
+	recordedPath := absPath
+	if topDir != "" {
+		if recordedPath, err = filepath.Rel(topDir, absPath); err != nil {
+			return "", err
+		}
+	}
+	info := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		(&url.URL{Path: filepath.ToSlash(recordedPath)}).EscapedPath(),
+		time.Now().Format("2006-01-02T15:04:05"))
+
+	name, err := reserveTrashName(trashDir, filepath.Base(absPath), info)
+	if err != nil {
+		return "", err
+	}
+	destination := filepath.Join(trashDir, "files", name)
+	if err := os.Rename(absPath, destination); err != nil {
+		os.Remove(filepath.Join(trashDir, "info", name+trashInfoSuffix))
+		return "", err
+	}
+	return destination, nil
+}
+
+// reserveTrashName picks a name that is free in both files/ and info/ of a trash directory by
+// creating its .trashinfo file exclusively, so concurrent trashing never reuses a name.
+func reserveTrashName(trashDir string, baseName string, info string) (string, error) {
+	for i := 1; ; i++ {
+		name := baseName
+		if i > 1 {
+			name = baseName + "." + strconv.Itoa(i)
+		}
+		infoPath := filepath.Join(trashDir, "info", name+trashInfoSuffix)
+		file, err := os.OpenFile(infoPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if _, err := os.Lstat(filepath.Join(trashDir, "files", name)); err == nil {
+			// An orphaned item without info file; keep both and try the next name.
+			file.Close()
+			os.Remove(infoPath)
+			continue
+		}
+		_, err = file.WriteString(info)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(infoPath)
+			return "", err
+		}
+		return name, nil
+	}
+}
+
+// trashDirFor returns the trash directory for a path and, for top directory trashes,
+// the top directory of the mount it belongs to.
+func trashDirFor(absPath string) (string, string, error) {
+	if !trashSupported {
+		return "", "", ErrTrashUnsupported
+	}
+	device, err := deviceOf(absPath)
+	if err != nil {
+		return "", "", err
+	}
+
+	homeTrash, err := homeTrashDir()
+	if err != nil {
+		return "", "", err
+	}
+	if err := os.MkdirAll(homeTrash, 0700); err != nil {
+		return "", "", err
+	}
+	if homeDevice, err := deviceOf(homeTrash); err == nil && homeDevice == device {
+		return homeTrash, "", nil
+	}
+
+	topDir, err := mountTopDir(absPath, device)
+	if err != nil {
+		return "", "", err
+	}
+	uid := strconv.Itoa(os.Getuid())
+
+	// An administrator-created $topdir/.Trash must be a real directory with the sticky bit set.
+	shared := filepath.Join(topDir, ".Trash")
+	if info, err := os.Lstat(shared); err == nil && info.IsDir() && info.Mode()&os.ModeSticky != 0 {
+		return filepath.Join(shared, uid), topDir, nil
+	}
+
+	private := filepath.Join(topDir, ".Trash-"+uid)
+	if err := os.Mkdir(private, 0700); err != nil && !os.IsExist(err) {
+		return "", "", err
+	}
+	if info, err := os.Lstat(private); err != nil || !info.IsDir() {
+		return "", "", fmt.Errorf("%s is not a usable trash directory", private)
+	}
+	return private, topDir, nil
+}
+
+// homeTrashDir returns $XDG_DATA_HOME/Trash, defaulting to ~/.local/share/Trash.
+func homeTrashDir() (string, error) {
+	if dataHome := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dataHome) {
+		return filepath.Join(dataHome, "Trash"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", "Trash"), nil
+}
+
+// mountTopDir returns the highest ancestor of a path that is still on the given device.
+func mountTopDir(absPath string, device uint64) (string, error) {
+	dir := filepath.Dir(absPath)
+	for {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir, nil
+		}
+		parentDevice, err := deviceOf(parent)
+		if err != nil {
+			return "", err
+		}
+		if parentDevice != device {
+			return dir, nil
+		}
+		dir = parent
+	}
+}
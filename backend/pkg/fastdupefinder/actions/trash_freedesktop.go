@@ -0,0 +1,7 @@
+//go:build linux || freebsd || openbsd || netbsd || dragonfly
+
+package actions
+
+// trashSupported is set on platforms following the freedesktop.org trash layout.
+// macOS keeps its own trash, managed by the Finder, so it is not one of them.
+const trashSupported = true
@@ -0,0 +1,6 @@
+//go:build !(linux || freebsd || openbsd || netbsd || dragonfly)
+
+package actions
+
+// trashSupported is set on platforms following the freedesktop.org trash layout.
+const trashSupported = false
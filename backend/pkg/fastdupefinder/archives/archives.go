@@ -0,0 +1,170 @@
+// Package archives reads zip and tar archives with the standard library, so their
+// members can be compared with the regular files and folders of a scan.
+package archives
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// Member describes a single entry inside an archive.
+type Member struct {
+	Name      string // Slash-separated name relative to the archive root
+	Size      int64  // Uncompressed size in bytes
+	IsDir     bool
+	IsRegular bool // False for directories, symlinks and other special entries
+}
+
+// archiveKind identifies the container format of an archive file.
+type archiveKind int
+
+const (
+	kindNone archiveKind = iota
+	kindZip
+	kindTar
+	kindTarGz
+)
+
+// kindOf detects the archive format from the file name.
+func kindOf(ArchivePath string) archiveKind {
+	lower := strings.ToLower(ArchivePath)
+	switch {
+	case strings.HasSuffix(lower, ".zip"), strings.HasSuffix(lower, ".jar"):
+		return kindZip
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return kindTarGz
+	case strings.HasSuffix(lower, ".tar"):
+		return kindTar
+	}
+	return kindNone
+}
+
+// IsArchive reports whether the file name has a supported archive extension
+// (.zip, .jar, .tar, .tar.gz or .tgz).
+func IsArchive(ArchivePath string) bool {
+	return kindOf(ArchivePath) != kindNone
+}
+
+// IsSequential reports whether members of the archive can only be reached by reading it from the
+// start, as in tar files. Zip members are located through the central directory instead.
+func IsSequential(ArchivePath string) bool {
+	kind := kindOf(ArchivePath)
+	return kind == kindTar || kind == kindTarGz
+}
+
+// cleanMemberName normalises a member name to a relative slash path.
+// It returns an empty string for the archive root and for names escaping it.
+func cleanMemberName(name string) string {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "." || strings.HasPrefix(name, "../") {
+		return ""
+	}
+	return name
+}
+
+// Walk calls fn for every member of the archive in stored order.
+// For regular members the reader streams the uncompressed content; it is only
+// valid until fn returns. Returning an error from fn stops the walk.
+func Walk(ArchivePath string, fn func(member Member, content io.Reader) error) error {
+	switch kindOf(ArchivePath) {
+	case kindZip:
+		return walkZip(ArchivePath, fn)
+	case kindTar, kindTarGz:
+		return walkTar(ArchivePath, fn)
+	}
+	return fmt.Errorf("unsupported archive format: %s", ArchivePath)
+}
+
+// walkZip iterates the central directory of a zip file.
+func walkZip(ArchivePath string, fn func(member Member, content io.Reader) error) error {
+	reader, err := zip.OpenReader(ArchivePath)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	for _, file := range reader.File {
+		name := cleanMemberName(file.Name)
+		if name == "" {
+			continue
+		}
+		mode := file.FileInfo().Mode()
+		member := Member{
+			Name:      name,
+			Size:      int64(file.UncompressedSize64),
+			IsDir:     mode.IsDir(),
+			IsRegular: mode.IsRegular(),
+		}
+		if !member.IsRegular {
+			if err := fn(member, nil); err != nil {
+				return err
+			}
+			continue
+		}
+
+		content, err := file.Open()
+		if err != nil {
+			return err
+		}
+		err = fn(member, content)
+		content.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkTar iterates a plain or gzip-compressed tar stream.
+func walkTar(ArchivePath string, fn func(member Member, content io.Reader) error) error {
+	file, err := os.Open(ArchivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var stream io.Reader = file
+	if kindOf(ArchivePath) == kindTarGz {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		stream = gz
+	}
+
+	reader := tar.NewReader(stream)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := cleanMemberName(header.Name)
+		if name == "" {
+			continue
+		}
+		mode := header.FileInfo().Mode()
+		member := Member{
+			Name:      name,
+			Size:      header.Size,
+			IsDir:     mode.IsDir(),
+			IsRegular: mode.IsRegular(),
+		}
+		var content io.Reader
+		if member.IsRegular {
+			content = reader
+		}
+		if err := fn(member, content); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package archives
+
+import (
+	"io"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types"
+)
+
+// VirtualSeparator separates an archive path from the member path inside it,
+// as in "project.zip!/src/main.go".
+const VirtualSeparator = "!"
+
+// VirtualRoot returns the directory path under which the members of an archive are addressed.
+func VirtualRoot(ArchivePath string) string {
+	return ArchivePath + VirtualSeparator
+}
+
+// ReadTree reads every member of an archive into a DirTree rooted at VirtualRoot(ArchivePath),
+// so archives can go through the same folder signature code as real directories.
+// HashContent is called for each regular member; the returned map holds the hashes
+// keyed by the member's path in the tree.
+func ReadTree(ArchivePath string, HashContent func(content io.Reader) (string, error)) (*types.DirTree, *sync.Map, error) {
+	root := VirtualRoot(ArchivePath)
+	tree := types.NewDirTree()
+	tree.AddDir(root)
+	hashes := &sync.Map{}
+
+	// addDirs records a directory and any parents implied by the member name, top-down
+	// so every directory is linked to its parent.
+	var addDirs func(name string)
+	addDirs = func(name string) {
+		if name == "." || name == "" {
+			return
+		}
+		dirPath := filepath.Join(root, filepath.FromSlash(name))
+		if _, ok := tree.Get(dirPath); ok {
+			return
+		}
+		addDirs(path.Dir(name))
+		tree.AddDir(dirPath)
+	}
+
+	err := Walk(ArchivePath, func(member Member, content io.Reader) error {
+		memberPath := filepath.Join(root, filepath.FromSlash(member.Name))
+		switch {
+		case member.IsDir:
+			addDirs(member.Name)
+		case member.IsRegular:
+			addDirs(path.Dir(member.Name))
+			hash, err := HashContent(content)
+			if err != nil {
+				return err
+			}
+			tree.AddFile(memberPath, member.Size)
+			hashes.Store(memberPath, hash)
+		default:
+			addDirs(path.Dir(member.Name))
+			tree.MarkIncomplete(filepath.Dir(memberPath))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return tree, hashes, nil
+}
@@ -0,0 +1,146 @@
+package archives
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// VirtualPath returns the path addressing a member inside an archive,
+// e.g. "archive.zip!/dir/file.txt". Such paths don't exist on disk and must never
+// be passed to code that modifies files.
+func VirtualPath(ArchivePath string, MemberName string) string {
+	return VirtualRoot(ArchivePath) + "/" + MemberName
+}
+
+// SplitVirtualPath splits a virtual path into the archive path and the member name.
+// The last argument is false if the path doesn't point inside a supported archive.
+func SplitVirtualPath(Path string) (string, string, bool) {
+	separator := VirtualSeparator + "/"
+	offset := 0
+	for {
+		i := strings.Index(Path[offset:], separator)
+		if i < 0 {
+			return "", "", false
+		}
+		archivePath := Path[:offset+i]
+		if IsArchive(archivePath) {
+			return archivePath, Path[offset+i+len(separator):], true
+		}
+		offset += i + len(separator)
+	}
+}
+
+// IsVirtualPath reports whether the path addresses a member inside an archive.
+func IsVirtualPath(Path string) bool {
+	_, _, ok := SplitVirtualPath(Path)
+	return ok
+}
+
+// List returns all regular members of an archive with their uncompressed sizes.
+func List(ArchivePath string) ([]Member, error) {
+	var members []Member
+	err := Walk(ArchivePath, func(member Member, content io.Reader) error {
+		if member.IsRegular {
+			members = append(members, member)
+		}
+		return nil
+	})
+	return members, err
+}
+
+// OpenMember opens a regular member of an archive for streaming and returns its uncompressed size.
+// Zip members are located through the central directory; tar members require reading
+// the archive up to the member.
+func OpenMember(ArchivePath string, MemberName string) (io.ReadCloser, int64, error) {
+	switch kindOf(ArchivePath) {
+	case kindZip:
+		return openZipMember(ArchivePath, MemberName)
+	case kindTar, kindTarGz:
+		return openTarMember(ArchivePath, MemberName)
+	}
+	return nil, 0, fmt.Errorf("unsupported archive format: %s", ArchivePath)
+}
+
+// OpenVirtualPath opens the archive member addressed by a virtual path.
+func OpenVirtualPath(Path string) (io.ReadCloser, int64, error) {
+	archivePath, memberName, ok := SplitVirtualPath(Path)
+	if !ok {
+		return nil, 0, fmt.Errorf("not an archive member path: %s", Path)
+	}
+	return OpenMember(archivePath, memberName)
+}
+
+// memberReader closes the member stream together with the archive it was opened from.
+type memberReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (m *memberReader) Close() error {
+	var firstErr error
+	for _, closer := range m.closers {
+		if err := closer.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func openZipMember(ArchivePath string, MemberName string) (io.ReadCloser, int64, error) {
+	reader, err := zip.OpenReader(ArchivePath)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, file := range reader.File {
+		if cleanMemberName(file.Name) != MemberName || !file.FileInfo().Mode().IsRegular() {
+			continue
+		}
+		content, err := file.Open()
+		if err != nil {
+			reader.Close()
+			return nil, 0, err
+		}
+		return &memberReader{Reader: content, closers: []io.Closer{content, reader}}, int64(file.UncompressedSize64), nil
+	}
+	reader.Close()
+	return nil, 0, fmt.Errorf("member %s not found in %s: %w", MemberName, ArchivePath, os.ErrNotExist)
+}
+
+func openTarMember(ArchivePath string, MemberName string) (io.ReadCloser, int64, error) {
+	file, err := os.Open(ArchivePath)
+	if err != nil {
+		return nil, 0, err
+	}
+	closers := []io.Closer{file}
+
+	var stream io.Reader = file
+	if kindOf(ArchivePath) == kindTarGz {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			file.Close()
+			return nil, 0, err
+		}
+		closers = append([]io.Closer{gz}, closers...)
+		stream = gz
+	}
+
+	reader := tar.NewReader(stream)
+	for {
+		header, err := reader.Next()
+		if err != nil {
+			(&memberReader{closers: closers}).Close()
+			if err == io.EOF {
+				return nil, 0, fmt.Errorf("member %s not found in %s: %w", MemberName, ArchivePath, os.ErrNotExist)
+			}
+			return nil, 0, err
+		}
+		if cleanMemberName(header.Name) == MemberName && header.FileInfo().Mode().IsRegular() {
+			return &memberReader{Reader: reader, closers: closers}, header.Size, nil
+		}
+	}
+}
@@ -49,6 +49,14 @@ func RunDuplicateFinderWithAdvancedConfigC(rootDir *C.char, cpuCores C.int, filt
 	return C.CString(result)
 }
 
+//export RunDuplicateFinderWithJSONConfigC
+func RunDuplicateFinderWithJSONConfigC(rootDir *C.char, configJSON *C.char) *C.char {
+	goRootDir := C.GoString(rootDir)
+	goConfigJSON := C.GoString(configJSON)
+	result := library.RunDuplicateFinderWithJSONConfig(goRootDir, goConfigJSON)
+	return C.CString(result)
+}
+
 //export GetCurrentStatusC
 func GetCurrentStatusC() *C.char {
 	result := library.GetCurrentStatus()
@@ -176,6 +184,84 @@ func GetLastReportC() *C.char {
 	return C.CString(result)
 }
 
+//export GetLastReportWithNestedC
+func GetLastReportWithNestedC() *C.char {
+	result := library.GetLastReportWithNested()
+	return C.CString(result)
+}
+
+//export LinkDuplicatesC
+func LinkDuplicatesC(pathsJSON *C.char, kind *C.char, dryRun C.int) *C.char {
+	result := library.LinkDuplicates(C.GoString(pathsJSON), C.GoString(kind), dryRun != 0)
+	return C.CString(result)
+}
+
+//export DeleteDuplicatesC
+func DeleteDuplicatesC(pathsJSON *C.char, trash C.int, dryRun C.int) *C.char {
+	result := library.DeleteDuplicates(C.GoString(pathsJSON), trash != 0, dryRun != 0)
+	return C.CString(result)
+}
+
+//export QuarantineDuplicatesC
+func QuarantineDuplicatesC(pathsJSON *C.char, directory *C.char, dryRun C.int) *C.char {
+	result := library.QuarantineDuplicates(C.GoString(pathsJSON), C.GoString(directory), dryRun != 0)
+	return C.CString(result)
+}
+
+//export RestoreQuarantineC
+func RestoreQuarantineC(manifestPath *C.char, dryRun C.int) *C.char {
+	result := library.RestoreQuarantine(C.GoString(manifestPath), dryRun != 0)
+	return C.CString(result)
+}
+
+//export DedupeDuplicatesC
+func DedupeDuplicatesC(dryRun C.int) *C.char {
+	result := library.DedupeDuplicates(dryRun != 0)
+	return C.CString(result)
+}
+
+//export SetKeepPolicyC
+func SetKeepPolicyC(policyJSON *C.char) *C.char {
+	result := library.SetKeepPolicy(C.GoString(policyJSON))
+	return C.CString(result)
+}
+
+//export MarkDuplicatesC
+func MarkDuplicatesC(expression *C.char, rootsJSON *C.char) *C.char {
+	result := library.MarkDuplicates(C.GoString(expression), C.GoString(rootsJSON))
+	return C.CString(result)
+}
+
+//export RevalidateDuplicatesC
+func RevalidateDuplicatesC(pathsJSON *C.char) *C.char {
+	result := library.RevalidateDuplicates(C.GoString(pathsJSON))
+	return C.CString(result)
+}
+
+//export GetIgnoreEntriesC
+func GetIgnoreEntriesC() *C.char {
+	result := library.GetIgnoreEntries()
+	return C.CString(result)
+}
+
+//export AddIgnoreEntriesC
+func AddIgnoreEntriesC(entriesJSON *C.char) *C.char {
+	result := library.AddIgnoreEntries(C.GoString(entriesJSON))
+	return C.CString(result)
+}
+
+//export RemoveIgnoreEntriesC
+func RemoveIgnoreEntriesC(valuesJSON *C.char) *C.char {
+	result := library.RemoveIgnoreEntries(C.GoString(valuesJSON))
+	return C.CString(result)
+}
+
+//export VerifyAuditLogC
+func VerifyAuditLogC(expected *C.char) *C.char {
+	result := library.VerifyAuditLog(C.GoString(expected))
+	return C.CString(result)
+}
+
 // C callback helper function - this will be implemented on the C side
 // but we need to declare it here for Go to call it
 func callCStatusCallback(callback unsafe.Pointer, status *C.char) {
@@ -10,6 +10,63 @@ type Phase1Config struct {
 	// When true, only files with the same size AND filename will be considered potential duplicates
 	// When false (default), files are grouped only by size
 	FilterByFilename bool `json:"filterByFilename"`
+
+	// DetectArchiveFolders compares zip and tar(.gz) archives with the folders of the scan
+	// When true, archives whose members equal a folder's content are reported as duplicates of it
+	DetectArchiveFolders bool `json:"detectArchiveFolders"`
+
+	// ExpandArchives treats members of zip, jar and tar(.gz) files as virtual files in Phase 1
+	// Members get paths like "archive.zip!/dir/file.txt" and are hashed by streaming them
+	ExpandArchives bool `json:"expandArchives"`
+
+	// CompareDecompressed matches gzip, bzip2 and zlib files against files with the same decompressed content
+	// Such files are reported as compressed equivalents, separately from byte-identical duplicates
+	CompareDecompressed bool `json:"compareDecompressed"`
+
+	// FindSimilarImages clusters JPEG, PNG and GIF images that look alike using perceptual hashes
+	// ImageDistance is the maximum Hamming distance (0-64) between pHashes of similar images
+	FindSimilarImages bool `json:"findSimilarImages"`
+	ImageDistance     int  `json:"imageDistance"`
+
+	// IgnoreJPEGMetadata matches JPEGs whose image data is identical while their EXIF, XMP or comment segments differ
+	// Such files are reported as content duplicates, separately from byte-identical duplicates
+	IgnoreJPEGMetadata bool `json:"ignoreJpegMetadata"`
+
+	// IgnoreAudioTags matches MP3 and FLAC files whose audio frames are identical while their ID3, APE or Vorbis tags differ
+	// Such files are reported as content duplicates together with the tag fields that differ
+	IgnoreAudioTags bool `json:"ignoreAudioTags"`
+
+	// FindSimilarTexts reports pairs of UTF-8 text files whose content is largely the same using MinHash
+	// TextSimilarity is the minimum estimated similarity (0-1) of a reported pair; binary files are skipped
+	FindSimilarTexts bool    `json:"findSimilarTexts"`
+	TextSimilarity   float64 `json:"textSimilarity"`
+
+	// NormaliseText matches UTF-8 text files that differ only by a BOM or CRLF/CR/LF line endings
+	// TrimTrailingWhitespace additionally ignores spaces and tabs at the end of lines
+	NormaliseText          bool `json:"normaliseText"`
+	TrimTrailingWhitespace bool `json:"trimTrailingWhitespace"`
+
+	// IgnoreDocumentMetadata matches OOXML (.docx, .xlsx, .pptx) and ODF documents by the content of their
+	// zip members, ignoring metadata parts such as docProps/core.xml that change on every save
+	IgnoreDocumentMetadata bool `json:"ignoreDocumentMetadata"`
+
+	// DetectTruncatedCopies reports files whose content is a strict prefix of a larger file
+	// such as interrupted downloads or half-finished copies
+	DetectTruncatedCopies bool `json:"detectTruncatedCopies"`
+
+	// AnalyseChunks splits files of at least ChunkMinFileSize bytes into content-defined chunks
+	// and reports files sharing large parts of their content, with the savings of block-level dedupe
+	AnalyseChunks    bool  `json:"analyseChunks"`
+	ChunkMinFileSize int64 `json:"chunkMinFileSize"`
+
+	// S3Sources lists "s3://bucket/prefix" paths whose objects are scanned alongside the local directory
+	// Objects are accessed through s3.DefaultClient and reported with their s3:// paths
+	S3Sources []string `json:"s3Sources"`
+
+	// S3Endpoint is the base URL of the S3-compatible store of S3Sources, e.g. "http://localhost:9000"
+	// Scans with S3 sources set s3.DefaultClient to a client for it, with credentials from the
+	// environment; if empty, AWS_ENDPOINT_URL or the AWS endpoint of the region is used
+	S3Endpoint string `json:"s3Endpoint"`
 }
 
 // DefaultConfig returns a Config with default values
@@ -17,6 +74,25 @@ func DefaultConfig() Phase1Config {
 	return Phase1Config{
 		CpuCores:         0,     // Auto-detect
 		FilterByFilename: false, // Disabled by default
+
+		DetectArchiveFolders: false, // Disabled by default
+		ExpandArchives:       false, // Disabled by default
+		CompareDecompressed:  false, // Disabled by default
+		FindSimilarImages:    false, // Disabled by default
+		ImageDistance:        10,    // Tolerates re-encoding and resizing
+		IgnoreJPEGMetadata:   false, // Disabled by default
+		IgnoreAudioTags:      false, // Disabled by default
+		FindSimilarTexts:     false, // Disabled by default
+		TextSimilarity:       0.8,   // Tolerates edits to a few lines
+
+		NormaliseText:          false,            // Disabled by default
+		TrimTrailingWhitespace: false,            // Whitespace is significant by default
+		IgnoreDocumentMetadata: false,            // Disabled by default
+		DetectTruncatedCopies:  false,            // Disabled by default
+		AnalyseChunks:          false,            // Disabled by default
+		ChunkMinFileSize:       16 * 1024 * 1024, // 16MB
+		S3Sources:              nil,              // Local directory only
+		S3Endpoint:             "",               // AWS_ENDPOINT_URL or AWS
 	}
 }
 
@@ -31,3 +107,94 @@ func (c Phase1Config) WithFilenameFilter(enabled bool) Phase1Config {
 	c.FilterByFilename = enabled
 	return c
 }
+
+// WithArchiveFolderDetection returns a new Config with archive-to-folder comparison enabled/disabled
+func (c Phase1Config) WithArchiveFolderDetection(enabled bool) Phase1Config {
+	c.DetectArchiveFolders = enabled
+	return c
+}
+
+// WithArchiveExpansion returns a new Config with archive members scanned as virtual files enabled/disabled
+func (c Phase1Config) WithArchiveExpansion(enabled bool) Phase1Config {
+	c.ExpandArchives = enabled
+	return c
+}
+
+// WithDecompressedComparison returns a new Config with compressed equivalent detection enabled/disabled
+func (c Phase1Config) WithDecompressedComparison(enabled bool) Phase1Config {
+	c.CompareDecompressed = enabled
+	return c
+}
+
+// WithSimilarImages returns a new Config with similar image detection enabled/disabled
+// If maxDistance is negative, the current distance is kept
+func (c Phase1Config) WithSimilarImages(enabled bool, maxDistance int) Phase1Config {
+	c.FindSimilarImages = enabled
+	if maxDistance >= 0 {
+		c.ImageDistance = maxDistance
+	}
+	return c
+}
+
+// WithJPEGMetadataIgnored returns a new Config with metadata-insensitive JPEG comparison enabled/disabled
+func (c Phase1Config) WithJPEGMetadataIgnored(enabled bool) Phase1Config {
+	c.IgnoreJPEGMetadata = enabled
+	return c
+}
+
+// WithAudioTagsIgnored returns a new Config with tag-insensitive audio comparison enabled/disabled
+func (c Phase1Config) WithAudioTagsIgnored(enabled bool) Phase1Config {
+	c.IgnoreAudioTags = enabled
+	return c
+}
+
+// WithSimilarTexts returns a new Config with near-duplicate text detection enabled/disabled
+// If minSimilarity is negative, the current threshold is kept
+func (c Phase1Config) WithSimilarTexts(enabled bool, minSimilarity float64) Phase1Config {
+	c.FindSimilarTexts = enabled
+	if minSimilarity >= 0 {
+		c.TextSimilarity = minSimilarity
+	}
+	return c
+}
+
+// WithTextNormalisation returns a new Config with normalised text comparison enabled/disabled
+func (c Phase1Config) WithTextNormalisation(enabled bool, trimTrailingWhitespace bool) Phase1Config {
+	c.NormaliseText = enabled
+	c.TrimTrailingWhitespace = trimTrailingWhitespace
+	return c
+}
+
+// WithDocumentMetadataIgnored returns a new Config with metadata-insensitive office document comparison enabled/disabled
+func (c Phase1Config) WithDocumentMetadataIgnored(enabled bool) Phase1Config {
+	c.IgnoreDocumentMetadata = enabled
+	return c
+}
+
+// WithTruncatedCopyDetection returns a new Config with truncated copy detection enabled/disabled
+func (c Phase1Config) WithTruncatedCopyDetection(enabled bool) Phase1Config {
+	c.DetectTruncatedCopies = enabled
+	return c
+}
+
+// WithChunkAnalysis returns a new Config with shared chunk analysis enabled/disabled
+// If minFileSize is negative, the current minimum file size is kept
+func (c Phase1Config) WithChunkAnalysis(enabled bool, minFileSize int64) Phase1Config {
+	c.AnalyseChunks = enabled
+	if minFileSize >= 0 {
+		c.ChunkMinFileSize = minFileSize
+	}
+	return c
+}
+
+// WithS3Sources returns a new Config that also scans the objects below the given "s3://bucket/prefix" paths
+func (c Phase1Config) WithS3Sources(sources ...string) Phase1Config {
+	c.S3Sources = append([]string(nil), sources...)
+	return c
+}
+
+// WithS3Endpoint returns a new Config that reads S3 sources from the given S3-compatible endpoint
+func (c Phase1Config) WithS3Endpoint(endpoint string) Phase1Config {
+	c.S3Endpoint = endpoint
+	return c
+}
@@ -0,0 +1,66 @@
+package helpers
+
+// BKTree indexes 64-bit hashes by Hamming distance for fast near-neighbour queries.
+// It is not safe for concurrent use.
+type BKTree struct {
+	root *bkNode
+}
+
+// bkNode holds a hash, the IDs inserted with it and children keyed by distance.
+type bkNode struct {
+	hash     uint64
+	ids      []int
+	children map[int]*bkNode
+}
+
+// NewBKTree creates an empty BK-tree.
+func NewBKTree() *BKTree {
+	return &BKTree{}
+}
+
+// Insert adds a hash with a caller-defined ID.
+func (t *BKTree) Insert(Hash uint64, ID int) {
+	if t.root == nil {
+		t.root = &bkNode{hash: Hash, ids: []int{ID}, children: make(map[int]*bkNode)}
+		return
+	}
+	node := t.root
+	for {
+		distance := HammingDistance(node.hash, Hash)
+		if distance == 0 {
+			node.ids = append(node.ids, ID)
+			return
+		}
+		child, ok := node.children[distance]
+		if !ok {
+			node.children[distance] = &bkNode{hash: Hash, ids: []int{ID}, children: make(map[int]*bkNode)}
+			return
+		}
+		node = child
+	}
+}
+
+// Query returns the IDs of all hashes within MaxDistance of Hash.
+func (t *BKTree) Query(Hash uint64, MaxDistance int) []int {
+	var result []int
+	if t.root == nil {
+		return result
+	}
+	stack := []*bkNode{t.root}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		distance := HammingDistance(node.hash, Hash)
+		if distance <= MaxDistance {
+			result = append(result, node.ids...)
+		}
+		// By the triangle inequality only children in this distance band can match.
+		for childDistance, child := range node.children {
+			if childDistance >= distance-MaxDistance && childDistance <= distance+MaxDistance {
+				stack = append(stack, child)
+			}
+		}
+	}
+	return result
+}
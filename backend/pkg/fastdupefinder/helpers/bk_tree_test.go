@@ -0,0 +1,45 @@
+package helpers
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestBKTreeQuery(t *testing.T) {
+	hashes := []uint64{
+		0x0000000000000000,
+		0x0000000000000001, // 1 bit from the first
+		0x0000000000000003, // 2 bits
+		0x000000000000000f, // 4 bits
+		0x00000000000000ff, // 8 bits
+		0xffffffffffffffff, // 64 bits
+		0x0000000000000000, // Same hash as the first
+	}
+	tree := NewBKTree()
+	for id, hash := range hashes {
+		tree.Insert(hash, id)
+	}
+	tests := []struct {
+		hash        uint64
+		maxDistance int
+		want        []int
+	}{
+		{0, 0, []int{0, 6}},
+		{0, 1, []int{0, 1, 6}},
+		{0, 4, []int{0, 1, 2, 3, 6}},
+		{0x0f, 4, []int{0, 1, 2, 3, 4, 6}},
+		{0xffffffffffffff00, 8, []int{5}},
+		{0x8000000000000000, 0, nil},
+	}
+	for _, test := range tests {
+		got := tree.Query(test.hash, test.maxDistance)
+		sort.Ints(got)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Query(%#x, %d) = %v, want %v", test.hash, test.maxDistance, got, test.want)
+		}
+	}
+	if got := NewBKTree().Query(0, 64); len(got) != 0 {
+		t.Errorf("empty tree returned %v", got)
+	}
+}
@@ -0,0 +1,164 @@
+package helpers
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cespare/xxhash"
+)
+
+// Sizes of the fixed parts of the audio tag formats stripped by CalculateAudioHash.
+const (
+	id3v2HeaderSize     = 10
+	id3v1TagSize        = 128
+	apeFooterSize       = 32
+	flacBlockHeaderSize = 4
+)
+
+var errNoAudioPayload = errors.New("no audio data after stripping tags")
+
+// IsAudio reports whether the file extension belongs to an MP3 or FLAC file.
+func IsAudio(FilePath string) bool {
+	switch strings.ToLower(filepath.Ext(FilePath)) {
+	case ".mp3", ".flac":
+		return true
+	}
+	return false
+}
+
+// CalculateAudioHash hashes the audio frames of an MP3 or FLAC file, leaving out its tags:
+// leading ID3v2 tags, FLAC metadata blocks (STREAMINFO, Vorbis comments, pictures, padding...),
+// a trailing APE tag and a trailing ID3v1 tag.
+// Two copies of a track whose tags were edited differently get the same hash.
+// If 'Partial' is true, only the length and the first PartialHashSize bytes of audio data are hashed.
+func CalculateAudioHash(FilePath string, Partial bool) (string, error) {
+	file, err := os.Open(FilePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	start, end, err := audioPayloadRange(file)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", FilePath, err)
+	}
+
+	hash := xxhash.New()
+	length := end - start
+	if Partial {
+		binary.Write(hash, binary.BigEndian, length)
+		if length > PartialHashSize {
+			length = PartialHashSize
+		}
+	}
+	if _, err := file.Seek(start, io.SeekStart); err != nil {
+		return "", err
+	}
+	if _, err := io.CopyN(hash, file, length); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+// audioPayloadRange returns the byte range [start, end) of a file that holds audio frames.
+func audioPayloadRange(file *os.File) (int64, int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	start, err := skipID3v2Tags(file, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	if start, err = skipFLACMetadata(file, start); err != nil {
+		return 0, 0, err
+	}
+
+	end := info.Size()
+	// ID3v1 is always the very last tag; an APE tag may sit right before it.
+	var id3v1 [3]byte
+	if end-start >= id3v1TagSize {
+		if _, err := file.ReadAt(id3v1[:], end-id3v1TagSize); err == nil && string(id3v1[:]) == "TAG" {
+			end -= id3v1TagSize
+		}
+	}
+	if end-start >= apeFooterSize {
+		if apeSize, found := readAPETagSize(file, end); found && apeSize <= end-start {
+			end -= apeSize
+		}
+	}
+
+	if end <= start {
+		return 0, 0, errNoAudioPayload
+	}
+	return start, end, nil
+}
+
+// skipID3v2Tags returns the offset after all consecutive ID3v2 tags starting at offset.
+func skipID3v2Tags(file *os.File, offset int64) (int64, error) {
+	for {
+		var header [id3v2HeaderSize]byte
+		if _, err := file.ReadAt(header[:], offset); err != nil {
+			if err == io.EOF {
+				return offset, nil
+			}
+			return 0, err
+		}
+		if string(header[:3]) != "ID3" {
+			return offset, nil
+		}
+		offset += id3v2HeaderSize + int64(syncsafeInt(header[6:10]))
+		if header[5]&0x10 != 0 {
+			// A footer, a copy of the header, follows the tag.
+			offset += id3v2HeaderSize
+		}
+	}
+}
+
+// skipFLACMetadata returns the offset of the first audio frame if a FLAC stream starts at offset,
+// or offset unchanged otherwise.
+func skipFLACMetadata(file *os.File, offset int64) (int64, error) {
+	var magic [4]byte
+	if _, err := file.ReadAt(magic[:], offset); err != nil || string(magic[:]) != "fLaC" {
+		return offset, nil
+	}
+	offset += 4
+	for {
+		var header [flacBlockHeaderSize]byte
+		if _, err := file.ReadAt(header[:], offset); err != nil {
+			return 0, fmt.Errorf("truncated FLAC metadata: %w", err)
+		}
+		length := int64(header[1])<<16 | int64(header[2])<<8 | int64(header[3])
+		offset += flacBlockHeaderSize + length
+		if header[0]&0x80 != 0 {
+			// Last metadata block.
+			return offset, nil
+		}
+	}
+}
+
+// readAPETagSize looks for an APE tag footer ending at end and returns the full size of the tag.
+func readAPETagSize(file *os.File, end int64) (int64, bool) {
+	var footer [apeFooterSize]byte
+	if _, err := file.ReadAt(footer[:], end-apeFooterSize); err != nil || string(footer[:8]) != "APETAGEX" {
+		return 0, false
+	}
+	// The size covers the items and the footer; a header of the same size may precede them.
+	size := int64(binary.LittleEndian.Uint32(footer[12:16]))
+	if binary.LittleEndian.Uint32(footer[20:24])&0x80000000 != 0 {
+		size += apeFooterSize
+	}
+	return size, true
+}
+
+// syncsafeInt decodes a 28-bit ID3v2 integer stored in four 7-bit bytes.
+func syncsafeInt(b []byte) int {
+	return int(b[0]&0x7F)<<21 | int(b[1]&0x7F)<<14 | int(b[2]&0x7F)<<7 | int(b[3]&0x7F)
+}
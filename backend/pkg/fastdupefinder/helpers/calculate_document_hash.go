@@ -0,0 +1,78 @@
+package helpers
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cespare/xxhash"
+)
+
+// volatileDocumentParts lists the container parts rewritten on every save without changing
+// the document's content: OOXML core and application properties, ODF metadata and view settings.
+var volatileDocumentParts = map[string]bool{
+	"docProps/core.xml": true,
+	"docProps/app.xml":  true,
+	"meta.xml":          true,
+	"settings.xml":      true,
+}
+
+// IsOfficeDocument reports whether the file extension belongs to an OOXML (Word, Excel,
+// PowerPoint) or ODF (OpenDocument) file.
+func IsOfficeDocument(FilePath string) bool {
+	switch strings.ToLower(filepath.Ext(FilePath)) {
+	case ".docx", ".docm", ".xlsx", ".xlsm", ".pptx", ".pptm",
+		".odt", ".ods", ".odp", ".odg":
+		return true
+	}
+	return false
+}
+
+// CalculateDocumentHash hashes an OOXML or ODF document by the content of its zip members,
+// leaving out volatile metadata parts such as docProps/core.xml or meta.xml.
+// Members are hashed in sorted name order, so neither their order in the container nor the
+// compression level affect the hash.
+// If 'Partial' is true, only member names, sizes and CRC-32 checksums from the zip directory
+// are hashed, without decompressing anything.
+func CalculateDocumentHash(FilePath string, Partial bool) (string, error) {
+	reader, err := zip.OpenReader(FilePath)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", FilePath, err)
+	}
+	defer reader.Close()
+
+	var members []*zip.File
+	for _, member := range reader.File {
+		if !volatileDocumentParts[member.Name] && !member.FileInfo().IsDir() {
+			members = append(members, member)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].Name < members[j].Name })
+
+	hash := xxhash.New()
+	for _, member := range members {
+		hash.Write([]byte(member.Name))
+		hash.Write([]byte{0})
+		binary.Write(hash, binary.BigEndian, member.UncompressedSize64)
+		if Partial {
+			binary.Write(hash, binary.BigEndian, member.CRC32)
+			continue
+		}
+
+		content, err := member.Open()
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", FilePath, err)
+		}
+		_, err = io.Copy(hash, content)
+		content.Close()
+		if err != nil {
+			return "", fmt.Errorf("%s!/%s: %w", FilePath, member.Name, err)
+		}
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
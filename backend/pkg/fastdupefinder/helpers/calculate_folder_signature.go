@@ -2,60 +2,68 @@ package helpers
 
 import (
 	"fmt"
-	"log"
-	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 	"sync"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types"
 )
 
+// nonDuplicableSignature is cached for folders that can't be duplicates.
+// An empty string can't be used because an empty folder legitimately has an empty signature.
+const nonDuplicableSignature = "\x00"
+
 // GetFolderSignature is a recursive helper that calculates a canonical signature for a folder.
+// It reads folder contents from the Phase 1 directory snapshot, so no filesystem I/O is performed.
 // It is designed to be thread-safe and uses sync.Map for concurrent cache access.
 // It returns the signature and a boolean indicating if the folder is a candidate for duplication.
 // A folder is NOT a candidate if it contains any unique files or unique sub-folders.
 func GetFolderSignature(
 	FolderPath string,
+	Tree *types.DirTree,
 	PathToHashMap *sync.Map,
 	FolderSignatureCache *sync.Map,
 ) (string, bool) {
 	// Base Case: If we have already calculated this signature, return it from the cache.
 	if sig, found := FolderSignatureCache.Load(FolderPath); found {
+		if sig.(string) == nonDuplicableSignature {
+			return "", false
+		}
 		return sig.(string), true
 	}
 
-	entries, err := os.ReadDir(FolderPath)
-	if err != nil {
-		log.Printf("Could not read directory %s: %v", FolderPath, err)
-		return "", false // Cannot be a duplicate if we can't read it.
+	node, found := Tree.Get(FolderPath)
+	if !found || node.Incomplete {
+		FolderSignatureCache.Store(FolderPath, nonDuplicableSignature)
+		return "", false // Cannot be a duplicate if we don't know its full contents.
 	}
 
-	var contentItems []string
+	contentItems := make([]string, 0, len(node.Dirs)+len(node.Files))
+
+	for _, name := range node.Dirs {
+		// Recursive step for subdirectory
+		childSignature, childIsDuplicable := GetFolderSignature(filepath.Join(FolderPath, name), Tree, PathToHashMap, FolderSignatureCache)
+		if !childIsDuplicable {
+			// This optimization prevents further processing if a unique child is found.
+			// We cache this "unique" status to avoid re-calculating for other potential parents.
+			FolderSignatureCache.Store(FolderPath, nonDuplicableSignature)
+			return "", false
+		}
+		// Prefix 'D:' for directory to distinguish from files with the same name.
+		contentItems = append(contentItems, fmt.Sprintf("D:%s:%s", name, childSignature))
+	}
 
-	for _, entry := range entries {
-		fullPath := filepath.Join(FolderPath, entry.Name())
-		if entry.IsDir() {
-			// Recursive step for subdirectory
-			childSignature, childIsDuplicable := GetFolderSignature(fullPath, PathToHashMap, FolderSignatureCache)
-			if !childIsDuplicable {
-				// This optimization prevents further processing if a unique child is found.
-				// We cache this "unique" status to avoid re-calculating for other potential parents.
-				FolderSignatureCache.Store(FolderPath, "") // Storing an empty string for non-duplicable folders.
-				return "", false
-			}
-			// Prefix 'D:' for directory to distinguish from files with the same name.
-			contentItems = append(contentItems, fmt.Sprintf("D:%s:%s", entry.Name(), childSignature))
-		} else {
-			// File step: look up the file's hash.
-			hash, found := PathToHashMap.Load(fullPath)
-			if !found {
-				// Folder contains a unique file, so it's not a duplicate candidate.
-				FolderSignatureCache.Store(FolderPath, "")
-				return "", false
-			}
-			// Prefix 'F:' for file.
-			contentItems = append(contentItems, fmt.Sprintf("F:%s:%s", entry.Name(), hash.(string)))
+	for name := range node.Files {
+		// File step: look up the file's hash.
+		hash, found := PathToHashMap.Load(filepath.Join(FolderPath, name))
+		if !found {
+			// Folder contains a unique file, so it's not a duplicate candidate.
+			FolderSignatureCache.Store(FolderPath, nonDuplicableSignature)
+			return "", false
 		}
+		// Prefix 'F:' for file.
+		contentItems = append(contentItems, fmt.Sprintf("F:%s:%s", name, hash.(string)))
 	}
 
 	// Sort the content items to create a canonical signature, independent of filesystem order.
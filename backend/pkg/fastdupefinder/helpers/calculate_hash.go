@@ -6,6 +6,9 @@ import (
 	"os"
 
 	"github.com/cespare/xxhash"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/archives"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/s3"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/sources"
 )
 
 // PartialHashSize defines how many bytes to read from each section of a file
@@ -24,6 +27,13 @@ const (
 // - Files < 10MB: hash first and last 4KB
 // - Files >= 10MB: hash first, middle, and last 4KB
 func CalculateHash(FilePath string, Partial bool) (string, error) {
+	if archives.IsVirtualPath(FilePath) {
+		return calculateVirtualHash(FilePath, Partial)
+	}
+	if s3.IsObjectPath(FilePath) {
+		return calculateObjectHash(FilePath, Partial)
+	}
+
 	file, err := os.Open(FilePath)
 	if err != nil {
 		return "", err
@@ -109,7 +119,141 @@ func CalculateHash(FilePath string, Partial bool) (string, error) {
 		}
 	} else {
 		// Read the entire file for full hash
-		if _, err := io.Copy(hash, file); err != nil {
+		return HashReader(file)
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+// HashReader computes the full hash of a stream, in the same format as CalculateHash.
+func HashReader(Content io.Reader) (string, error) {
+	hash := xxhash.New()
+	if _, err := io.Copy(hash, Content); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+// calculateVirtualHash hashes an archive member by streaming it.
+// The partial hash covers the same byte ranges as CalculateHash does for a regular file
+// of the same size, so loose files and their archived copies produce matching hashes.
+func calculateVirtualHash(FilePath string, Partial bool) (string, error) {
+	if archivePath, memberName, _ := archives.SplitVirtualPath(FilePath); archives.IsSequential(archivePath) {
+		return sequentialMemberHash(archivePath, memberName, Partial)
+	}
+
+	content, size, err := archives.OpenVirtualPath(FilePath)
+	if err != nil {
+		return "", err
+	}
+	defer content.Close()
+
+	if !Partial {
+		return HashReader(content)
+	}
+
+	// Member streams can't seek, so skip forward to each section instead.
+	hash := xxhash.New()
+	buffer := make([]byte, PartialHashSize)
+	var position int64
+	for _, offset := range partialHashOffsets(size) {
+		if offset > position {
+			if _, err := io.CopyN(io.Discard, content, offset-position); err != nil {
+				return "", err
+			}
+			position = offset
+		}
+		n, err := io.ReadFull(content, buffer)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return "", err
+		}
+		hash.Write(buffer[:n])
+		position += int64(n)
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+// partialHashOffsets returns the offsets of the PartialHashSize sections that CalculateHash
+// reads for the partial hash of a file of the given size.
+func partialHashOffsets(size int64) []int64 {
+	if size < SmallFileThreshold {
+		return []int64{0}
+	} else if size < MediumFileThreshold {
+		return []int64{0, size - PartialHashSize}
+	}
+	return []int64{0, (size / 2) - (PartialHashSize / 2), size - PartialHashSize}
+}
+
+// calculateObjectHash hashes an object of an S3-compatible store.
+// The partial hash fetches the same byte ranges as CalculateHash reads for a regular file of
+// the same size with ranged GETs, so local files and their remote copies produce matching hashes.
+func calculateObjectHash(FilePath string, Partial bool) (string, error) {
+	bucket, key, _ := s3.SplitObjectPath(FilePath)
+	client := s3.DefaultClient()
+
+	if !Partial {
+		content, _, err := client.Open(bucket, key, 0, -1)
+		if err != nil {
+			return "", err
+		}
+		defer content.Close()
+		return HashReader(content)
+	}
+
+	// The first range also tells the object size, which decides the other ranges.
+	hash := xxhash.New()
+	content, size, err := client.Open(bucket, key, 0, PartialHashSize)
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(hash, content)
+	content.Close()
+	if err != nil {
+		return "", err
+	}
+
+	for _, offset := range partialHashOffsets(size)[1:] {
+		content, _, err := client.Open(bucket, key, offset, PartialHashSize)
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(hash, content)
+		content.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+// CalculateSourceHash computes the hash of a file of a Source, in the same format as CalculateHash.
+// The partial hash covers the same byte ranges CalculateHash reads for a regular file of the
+// same size, so files of different sources produce matching hashes.
+func CalculateSourceHash(Source sources.Source, Name string, Partial bool) (string, error) {
+	if !Partial {
+		content, err := Source.OpenAt(Name, 0)
+		if err != nil {
+			return "", err
+		}
+		defer content.Close()
+		return HashReader(content)
+	}
+
+	stat, err := Source.StatFile(Name)
+	if err != nil {
+		return "", err
+	}
+	hash := xxhash.New()
+	for _, offset := range partialHashOffsets(stat.Size) {
+		content, err := Source.OpenAt(Name, offset)
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(hash, io.LimitReader(content, PartialHashSize))
+		content.Close()
+		if err != nil {
 			return "", err
 		}
 	}
@@ -0,0 +1,75 @@
+package helpers
+
+import (
+	"bytes"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/s3"
+)
+
+func TestObjectsHashLikeLocalFiles(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	objects := map[string][]byte{
+		"small.bin":  make([]byte, 5000),
+		"medium.bin": make([]byte, 2*1024*1024),
+		"large.bin":  make([]byte, 11*1024*1024),
+	}
+	for _, content := range objects {
+		random.Read(content)
+	}
+
+	var mu sync.Mutex
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/bucket/")
+		content, found := objects[name]
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		mu.Unlock()
+		http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+	s3.SetDefaultClient(&s3.Client{Endpoint: server.URL, Region: "us-east-1"})
+	defer s3.SetDefaultClient(nil)
+
+	dir := t.TempDir()
+	for name, content := range objects {
+		loose := filepath.Join(dir, name)
+		if err := os.WriteFile(loose, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		for _, partial := range []bool{true, false} {
+			ranges = nil
+			want, err := CalculateHash(loose, partial)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := CalculateHash(s3.ObjectPath("bucket", name), partial)
+			if err != nil {
+				t.Fatalf("%s (partial %v): %v", name, partial, err)
+			}
+			if got != want {
+				t.Errorf("%s (partial %v): object hash %s, local file hash %s", name, partial, got, want)
+			}
+			wantRequests := len(partialHashOffsets(int64(len(content))))
+			if !partial {
+				wantRequests = 1
+			}
+			if len(ranges) != wantRequests || (!partial && ranges[0] != "") {
+				t.Errorf("%s (partial %v): sent ranges %q", name, partial, ranges)
+			}
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package helpers
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"  // Register GIF decoder
+	_ "image/jpeg" // Register JPEG decoder
+	_ "image/png"  // Register PNG decoder
+	"io"
+	"math"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types"
+)
+
+// MaxImagePixels is the largest image, in pixels, that is decoded for a fingerprint.
+// Decoding needs about 4 bytes per pixel, so larger images are skipped rather than risking
+// running out of memory on huge or maliciously crafted files.
+const MaxImagePixels = 50_000_000
+
+// IsSupportedImage reports whether the file extension belongs to an image format
+// that can be fingerprinted (JPEG, PNG or GIF).
+func IsSupportedImage(FilePath string) bool {
+	switch strings.ToLower(filepath.Ext(FilePath)) {
+	case ".jpg", ".jpeg", ".jpe", ".png", ".gif":
+		return true
+	}
+	return false
+}
+
+// CalculateImageFingerprint decodes an image and computes its pHash.
+// The hash is computed from a grayscale thumbnail, so re-encoded, resized or
+// metadata-stripped copies of a picture end up within a small Hamming distance.
+// Images with more than MaxImagePixels pixels are refused before decoding them.
+func CalculateImageFingerprint(FilePath string) (types.ImageFingerprint, error) {
+	file, err := os.Open(FilePath)
+	if err != nil {
+		return types.ImageFingerprint{}, err
+	}
+	defer file.Close()
+
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return types.ImageFingerprint{}, err
+	}
+	if int64(config.Width)*int64(config.Height) > MaxImagePixels {
+		return types.ImageFingerprint{}, fmt.Errorf("image is %dx%d pixels, more than the limit of %d", config.Width, config.Height, MaxImagePixels)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return types.ImageFingerprint{}, err
+	}
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return types.ImageFingerprint{}, err
+	}
+
+	bounds := img.Bounds()
+	return types.ImageFingerprint{
+		Path:   FilePath,
+		Width:  bounds.Dx(),
+		Height: bounds.Dy(),
+		PHash:  perceptualHash(img),
+	}, nil
+}
+
+// HammingDistance returns the number of differing bits between two hashes.
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// perceptualHash keeps the sign of the lowest 8x8 DCT frequencies of a 32x32 thumbnail
+// relative to their median, ignoring the DC term for the median.
+func perceptualHash(img image.Image) uint64 {
+	const size = 32
+	pixels := grayThumbnail(img, size, size)
+
+	// Separable 2D DCT-II, only the 8 lowest frequencies are needed in each direction.
+	var rows [size][8]float64
+	for y := 0; y < size; y++ {
+		for u := 0; u < 8; u++ {
+			var sum float64
+			for x := 0; x < size; x++ {
+				sum += pixels[y*size+x] * dctCoefficients[u][x]
+			}
+			rows[y][u] = sum
+		}
+	}
+	var coefficients [64]float64
+	for v := 0; v < 8; v++ {
+		for u := 0; u < 8; u++ {
+			var sum float64
+			for y := 0; y < size; y++ {
+				sum += rows[y][u] * dctCoefficients[v][y]
+			}
+			coefficients[v*8+u] = sum
+		}
+	}
+
+	sorted := make([]float64, 63)
+	copy(sorted, coefficients[1:])
+	sort.Float64s(sorted)
+	median := (sorted[30] + sorted[31]) / 2
+
+	var hash uint64
+	for _, c := range coefficients {
+		hash <<= 1
+		if c > median {
+			hash |= 1
+		}
+	}
+	return hash
+}
+
+// dctCoefficients caches cos((2x+1)uπ/64) for the 32-point DCT.
+var dctCoefficients = func() [8][32]float64 {
+	var table [8][32]float64
+	for u := 0; u < 8; u++ {
+		for x := 0; x < 32; x++ {
+			table[u][x] = math.Cos(float64(2*x+1) * float64(u) * math.Pi / 64)
+		}
+	}
+	return table
+}()
+
+// grayThumbnail downsamples an image to width x height luminance values by averaging
+// the source pixels covered by each thumbnail pixel.
+func grayThumbnail(img image.Image, width, height int) []float64 {
+	bounds := img.Bounds()
+	sums := make([]float64, width*height)
+	counts := make([]float64, width*height)
+	if bounds.Dx() == 0 || bounds.Dy() == 0 {
+		return sums
+	}
+
+	// JPEG images decode to YCbCr; their Y plane already is the luminance.
+	ycbcr, isYCbCr := img.(*image.YCbCr)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		ty := (y - bounds.Min.Y) * height / bounds.Dy()
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			tx := (x - bounds.Min.X) * width / bounds.Dx()
+			var luminance float64
+			if isYCbCr {
+				luminance = float64(ycbcr.Y[ycbcr.YOffset(x, y)])
+			} else {
+				luminance = float64(color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y)
+			}
+			sums[ty*width+tx] += luminance
+			counts[ty*width+tx]++
+		}
+	}
+
+	for i := range sums {
+		if counts[i] > 0 {
+			sums[i] /= counts[i]
+		}
+	}
+	return sums
+}
@@ -0,0 +1,43 @@
+package helpers
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// patternImage draws a grayscale image of the given size from a pattern over [0,1)².
+func patternImage(width, height int, pattern func(x, y float64) float64) image.Image {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetGray(x, y, color.Gray{Y: uint8(255 * pattern(float64(x)/float64(width), float64(y)/float64(height)))})
+		}
+	}
+	return img
+}
+
+func TestPerceptualHash(t *testing.T) {
+	waves := func(x, y float64) float64 { return 0.5 + 0.5*math.Sin(6*x)*math.Cos(9*y) }
+	rings := func(x, y float64) float64 { return 0.5 + 0.5*math.Cos(20*math.Hypot(x-0.5, y-0.5)) }
+	darker := func(x, y float64) float64 { return 0.8 * waves(x, y) }
+	tests := []struct {
+		name    string
+		a, b    image.Image
+		similar bool // Whether the hashes must be within 4 bits, otherwise at least 16 bits apart
+	}{
+		{name: "same image", a: patternImage(64, 64, waves), b: patternImage(64, 64, waves), similar: true},
+		{name: "resized", a: patternImage(400, 300, waves), b: patternImage(200, 150, waves), similar: true},
+		{name: "darker", a: patternImage(128, 128, waves), b: patternImage(128, 128, darker), similar: true},
+		{name: "other picture", a: patternImage(128, 128, waves), b: patternImage(128, 128, rings)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			distance := HammingDistance(perceptualHash(test.a), perceptualHash(test.b))
+			if test.similar && distance > 4 || !test.similar && distance < 16 {
+				t.Errorf("distance %d between the hashes, similar %t", distance, test.similar)
+			}
+		})
+	}
+}
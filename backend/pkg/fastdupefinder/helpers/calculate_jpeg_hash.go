@@ -0,0 +1,194 @@
+package helpers
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cespare/xxhash"
+)
+
+// HashFunc is the signature of the format-aware hashers, which has the same shape as CalculateHash.
+// They don't replace CalculateHash in Phases 2 and 3: Phase3FindNormalisedDuplicates runs each of
+// them in its own partial-then-full pass over the matching files, after Phase 3.
+// With Partial set, a hasher may hash only a prefix of the content it would hash in full.
+type HashFunc func(FilePath string, Partial bool) (string, error)
+
+// ErrNotApplicable is returned by a HashFunc for files it does not handle, e.g. binary files
+// given to a text hasher. Such files are skipped without logging an error.
+var ErrNotApplicable = errors.New("file not handled by this hasher")
+
+// JPEG marker codes used by the metadata-insensitive hasher.
+const (
+	jpegMarkerSOI  = 0xD8
+	jpegMarkerEOI  = 0xD9
+	jpegMarkerSOS  = 0xDA
+	jpegMarkerRST0 = 0xD0
+	jpegMarkerRST7 = 0xD7
+	jpegMarkerAPP0 = 0xE0
+	jpegMarkerAPPF = 0xEF
+	jpegMarkerCOM  = 0xFE
+	jpegMarkerTEM  = 0x01
+)
+
+var errNotJPEG = errors.New("not a JPEG file")
+
+// IsJPEG reports whether the file extension belongs to a JPEG image.
+func IsJPEG(FilePath string) bool {
+	switch strings.ToLower(filepath.Ext(FilePath)) {
+	case ".jpg", ".jpeg", ".jpe", ".jfif":
+		return true
+	}
+	return false
+}
+
+// CalculateJPEGHash hashes a JPEG while skipping its metadata: APPn segments (EXIF, XMP,
+// ICC, JFIF...) and COM segments are left out, everything else - quantisation and Huffman
+// tables, frame and scan headers and the entropy-coded image data - is hashed.
+// Two JPEGs with identical pixel data but different metadata get the same hash.
+// If 'Partial' is true, only the first PartialHashSize bytes of image data are hashed.
+func CalculateJPEGHash(FilePath string, Partial bool) (string, error) {
+	file, err := os.Open(FilePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	hash := xxhash.New()
+
+	var soi [2]byte
+	if _, err := io.ReadFull(reader, soi[:]); err != nil || soi[0] != 0xFF || soi[1] != jpegMarkerSOI {
+		return "", fmt.Errorf("%s: %w", FilePath, errNotJPEG)
+	}
+
+	var scanBytes int
+	for {
+		marker, err := nextJPEGMarker(reader)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", FilePath, err)
+		}
+
+		switch {
+		case marker == jpegMarkerEOI:
+			// Anything after EOI (thumbnails, trailers) is not part of the image.
+			return fmt.Sprintf("%x", hash.Sum(nil)), nil
+		case marker == jpegMarkerTEM || (marker >= jpegMarkerRST0 && marker <= jpegMarkerRST7):
+			// Standalone markers without a length field.
+			hash.Write([]byte{0xFF, marker})
+			continue
+		}
+
+		var lengthBytes [2]byte
+		if _, err := io.ReadFull(reader, lengthBytes[:]); err != nil {
+			return "", fmt.Errorf("%s: truncated segment: %w", FilePath, err)
+		}
+		length := int(binary.BigEndian.Uint16(lengthBytes[:]))
+		if length < 2 {
+			return "", fmt.Errorf("%s: invalid segment length %d", FilePath, length)
+		}
+
+		if (marker >= jpegMarkerAPP0 && marker <= jpegMarkerAPPF) || marker == jpegMarkerCOM {
+			// Metadata segment: skip it entirely.
+			if _, err := reader.Discard(length - 2); err != nil {
+				return "", fmt.Errorf("%s: truncated segment: %w", FilePath, err)
+			}
+			continue
+		}
+
+		hash.Write([]byte{0xFF, marker})
+		hash.Write(lengthBytes[:])
+		if _, err := io.CopyN(hash, reader, int64(length-2)); err != nil {
+			return "", fmt.Errorf("%s: truncated segment: %w", FilePath, err)
+		}
+
+		if marker == jpegMarkerSOS {
+			// Entropy-coded data follows until the next marker that is not a
+			// stuffed zero byte or a restart marker.
+			limit := -1
+			if Partial {
+				limit = PartialHashSize - scanBytes
+			}
+			n, err := hashJPEGScanData(reader, hash, limit)
+			scanBytes += n
+			if err != nil {
+				return "", fmt.Errorf("%s: %w", FilePath, err)
+			}
+			if Partial && scanBytes >= PartialHashSize {
+				return fmt.Sprintf("%x", hash.Sum(nil)), nil
+			}
+		}
+	}
+}
+
+// nextJPEGMarker reads the next marker code, skipping fill bytes.
+func nextJPEGMarker(reader *bufio.Reader) (byte, error) {
+	b, err := reader.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if b != 0xFF {
+		return 0, errors.New("expected JPEG marker")
+	}
+	for b == 0xFF {
+		if b, err = reader.ReadByte(); err != nil {
+			return 0, err
+		}
+	}
+	return b, nil
+}
+
+// hashJPEGScanData hashes entropy-coded data up to, but not including, the next segment marker,
+// which is left unread. It hashes at most limit bytes unless limit is negative.
+func hashJPEGScanData(reader *bufio.Reader, hash io.Writer, limit int) (int, error) {
+	var written int
+	buffer := make([]byte, 0, 4096)
+	flush := func() {
+		hash.Write(buffer)
+		written += len(buffer)
+		buffer = buffer[:0]
+	}
+
+	for limit < 0 || written+len(buffer) < limit {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return written, fmt.Errorf("truncated image data: %w", err)
+		}
+		if b != 0xFF {
+			buffer = append(buffer, b)
+			if len(buffer) == cap(buffer) {
+				flush()
+			}
+			continue
+		}
+
+		// Put the 0xFF back and look at it together with the byte after it.
+		reader.UnreadByte()
+		pair, err := reader.Peek(2)
+		if err != nil {
+			return written, fmt.Errorf("truncated image data: %w", err)
+		}
+		if pair[1] == 0x00 || (pair[1] >= jpegMarkerRST0 && pair[1] <= jpegMarkerRST7) {
+			// Stuffed byte or restart marker: part of the scan.
+			buffer = append(buffer, pair[0], pair[1])
+			reader.Discard(2)
+			if len(buffer) >= cap(buffer)-1 {
+				flush()
+			}
+			continue
+		}
+		// A real marker ends the scan and is left for nextJPEGMarker.
+		break
+	}
+
+	if limit >= 0 && len(buffer) > limit-written {
+		buffer = buffer[:limit-written]
+	}
+	flush()
+	return written, nil
+}
@@ -0,0 +1,209 @@
+package helpers
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"math"
+	"os"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cespare/xxhash"
+)
+
+// MinHash parameters for text similarity. The signature is split into MinHashBands bands of
+// MinHashRows values for LSH; pairs sharing one band are candidates. With 16 bands of 8 rows
+// documents above ~70% similarity are very likely to collide in at least one band.
+const (
+	MinHashSize   = 128
+	MinHashBands  = 16
+	MinHashRows   = MinHashSize / MinHashBands
+	ShingleTokens = 5                // Tokens per shingle
+	TextSniffSize = 8192             // Bytes inspected to tell text from binary
+	MaxTextSize   = 16 * 1024 * 1024 // Larger files are not compared as text
+)
+
+// minHashSeeds holds one seed per MinHash function, derived with splitmix64.
+var minHashSeeds = func() [MinHashSize]uint64 {
+	var seeds [MinHashSize]uint64
+	state := uint64(0x5EED)
+	for i := range seeds {
+		state += 0x9E3779B97F4A7C15
+		seeds[i] = mix64(state)
+	}
+	return seeds
+}()
+
+// IsTextFile sniffs the start of a file and reports whether it looks like UTF-8 text:
+// no NUL bytes and valid UTF-8, allowing a rune cut off at the end of the sniffed block.
+func IsTextFile(FilePath string) (bool, error) {
+	file, err := os.Open(FilePath)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	buffer := make([]byte, TextSniffSize)
+	n, err := io.ReadFull(file, buffer)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+	return looksLikeText(buffer[:n], n == TextSniffSize), nil
+}
+
+// looksLikeText reports whether data is NUL-free UTF-8. If truncated is set, an incomplete
+// rune at the end of data is accepted.
+func looksLikeText(data []byte, truncated bool) bool {
+	if bytes.IndexByte(data, 0) >= 0 {
+		return false
+	}
+	for len(data) > 0 {
+		r, size := utf8.DecodeRune(data)
+		if r == utf8.RuneError && size <= 1 {
+			return truncated && len(data) < utf8.UTFMax && !utf8.FullRune(data)
+		}
+		data = data[size:]
+	}
+	return true
+}
+
+// CalculateMinHash splits a text file into words and punctuation, hashes every run of
+// ShingleTokens consecutive tokens and returns the MinHash signature of these shingles.
+// Files without any token return a nil signature.
+func CalculateMinHash(FilePath string) ([]uint64, error) {
+	file, err := os.Open(FilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), MaxTextSize)
+	scanner.Split(scanTextTokens)
+
+	signature := make([]uint64, MinHashSize)
+	for i := range signature {
+		signature[i] = math.MaxUint64
+	}
+
+	// Rolling window of the last ShingleTokens token hashes.
+	var window [ShingleTokens]uint64
+	var tokens int
+	addShingle := func(count int) {
+		var shingle [ShingleTokens * 8]byte
+		for i := 0; i < count; i++ {
+			value := window[(tokens-count+i)%ShingleTokens]
+			for j := 0; j < 8; j++ {
+				shingle[i*8+j] = byte(value >> (8 * j))
+			}
+		}
+		updateMinHash(signature, xxhash.Sum64(shingle[:count*8]))
+	}
+
+	for scanner.Scan() {
+		window[tokens%ShingleTokens] = xxhash.Sum64(scanner.Bytes())
+		tokens++
+		if tokens >= ShingleTokens {
+			addShingle(ShingleTokens)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case tokens == 0:
+		return nil, nil
+	case tokens < ShingleTokens:
+		// Short texts form a single shingle.
+		addShingle(tokens)
+	}
+	return signature, nil
+}
+
+// updateMinHash lowers each signature value to the shingle's hash under that function if smaller.
+func updateMinHash(signature []uint64, shingle uint64) {
+	for i, seed := range minHashSeeds {
+		if value := mix64(shingle ^ seed); value < signature[i] {
+			signature[i] = value
+		}
+	}
+}
+
+// EstimateSimilarity estimates the Jaccard similarity of two shingle sets from their MinHash
+// signatures as the fraction of equal values.
+func EstimateSimilarity(a, b []uint64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var equal int
+	for i := range a {
+		if a[i] == b[i] {
+			equal++
+		}
+	}
+	return float64(equal) / float64(len(a))
+}
+
+// MinHashBandKey returns the hash of one LSH band of a signature.
+func MinHashBandKey(signature []uint64, band int) uint64 {
+	var data [MinHashRows * 8]byte
+	for i, value := range signature[band*MinHashRows : (band+1)*MinHashRows] {
+		for j := 0; j < 8; j++ {
+			data[i*8+j] = byte(value >> (8 * j))
+		}
+	}
+	return xxhash.Sum64(data[:])
+}
+
+// scanTextTokens is a bufio.SplitFunc returning words (runs of letters, digits and '_')
+// and single punctuation or symbol characters, skipping whitespace.
+func scanTextTokens(data []byte, atEOF bool) (int, []byte, error) {
+	start := 0
+	for start < len(data) {
+		r, size := utf8.DecodeRune(data[start:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		start += size
+	}
+	if start == len(data) || (!atEOF && !utf8.FullRune(data[start:])) {
+		return start, nil, nil
+	}
+
+	first, size := utf8.DecodeRune(data[start:])
+	if !isWordRune(first) {
+		return start + size, data[start : start+size], nil
+	}
+	for i := start + size; i < len(data); {
+		if !atEOF && !utf8.FullRune(data[i:]) {
+			break
+		}
+		r, size := utf8.DecodeRune(data[i:])
+		if !isWordRune(r) {
+			return i, data[start:i], nil
+		}
+		i += size
+	}
+	if atEOF {
+		return len(data), data[start:], nil
+	}
+	// Request more data to complete the word.
+	return start, nil, nil
+}
+
+// isWordRune reports whether r belongs to a word token.
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// mix64 is the splitmix64 finaliser, used to derive independent hash functions.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xBF58476D1CE4E5B9
+	x ^= x >> 27
+	x *= 0x94D049BB133111EB
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,133 @@
+package helpers
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/cespare/xxhash"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/archives"
+)
+
+// tarKey identifies a version of a tar archive, so a changed archive is never answered from the cache.
+type tarKey struct {
+	path    string
+	size    int64
+	modTime int64 // Modification time in Unix nanoseconds
+}
+
+// memberHashes holds the partial and full hashes of every regular member of a tar archive.
+type memberHashes struct {
+	partial map[string]string
+	full    map[string]string
+
+	ready chan struct{} // Closed once the hashes are computed
+	err   error
+}
+
+// tarHashes caches the member hashes of the tar archives of the running scan, see ClearArchiveHashes.
+var (
+	tarHashes   = make(map[tarKey]*memberHashes)
+	tarHashesMu sync.Mutex
+)
+
+// ClearArchiveHashes empties the cache of tar member hashes. Scans call it when they end,
+// so a long-running process doesn't keep the hashes of every archive it ever scanned.
+func ClearArchiveHashes() {
+	tarHashesMu.Lock()
+	defer tarHashesMu.Unlock()
+	tarHashes = make(map[tarKey]*memberHashes)
+}
+
+// sequentialMemberHash returns a hash of a member of a tar archive. Opening a tar member
+// means decompressing and reading the archive up to it, so all members are hashed in a single
+// pass on the first request and later requests are answered from the cache, which is keyed by
+// the archive's path, size and modification time.
+func sequentialMemberHash(ArchivePath string, MemberName string, Partial bool) (string, error) {
+	info, err := os.Stat(ArchivePath)
+	if err != nil {
+		return "", err
+	}
+
+	// The first worker to ask for a member of the archive hashes it; the others wait for it.
+	key := tarKey{path: ArchivePath, size: info.Size(), modTime: info.ModTime().UnixNano()}
+	tarHashesMu.Lock()
+	hashes, cached := tarHashes[key]
+	if !cached {
+		hashes = &memberHashes{ready: make(chan struct{})}
+		tarHashes[key] = hashes
+	}
+	tarHashesMu.Unlock()
+
+	if !cached {
+		hashes.partial, hashes.full, hashes.err = hashAllMembers(ArchivePath)
+		close(hashes.ready)
+	}
+	<-hashes.ready
+	if hashes.err != nil {
+		return "", hashes.err
+	}
+	table := hashes.full
+	if Partial {
+		table = hashes.partial
+	}
+	hash, ok := table[MemberName]
+	if !ok {
+		return "", fmt.Errorf("member %s not found in %s: %w", MemberName, ArchivePath, os.ErrNotExist)
+	}
+	return hash, nil
+}
+
+// hashAllMembers computes the partial and full hashes of every regular member of an archive
+// while streaming it once. The first member of a name wins, like in archives.OpenMember.
+func hashAllMembers(ArchivePath string) (map[string]string, map[string]string, error) {
+	partial := make(map[string]string)
+	full := make(map[string]string)
+	err := archives.Walk(ArchivePath, func(member archives.Member, content io.Reader) error {
+		if !member.IsRegular {
+			return nil
+		}
+		if _, seen := full[member.Name]; seen {
+			return nil
+		}
+		partialHash, fullHash, err := hashStream(content, member.Size)
+		if err != nil {
+			return err
+		}
+		partial[member.Name], full[member.Name] = partialHash, fullHash
+		return nil
+	})
+	return partial, full, err
+}
+
+// hashStream computes the partial hash, over the sections CalculateHash reads for a file of
+// the given size, and the full hash of a stream in a single read.
+func hashStream(content io.Reader, size int64) (string, string, error) {
+	full := xxhash.New()
+	partial := xxhash.New()
+	offsets := partialHashOffsets(size)
+	buffer := make([]byte, 64*1024)
+	var position int64
+	for {
+		n, err := content.Read(buffer)
+		chunk := buffer[:n]
+		full.Write(chunk)
+		// Sections are ascending and don't overlap, so they are written to the partial hash in order.
+		for _, offset := range offsets {
+			start := max(offset, position)
+			end := min(offset+PartialHashSize, position+int64(n))
+			if start < end {
+				partial.Write(chunk[start-position : end-position])
+			}
+		}
+		position += int64(n)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", "", err
+		}
+	}
+	return fmt.Sprintf("%x", partial.Sum(nil)), fmt.Sprintf("%x", full.Sum(nil)), nil
+}
@@ -0,0 +1,93 @@
+package helpers
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/archives"
+)
+
+func TestTarMembersHashLikeLooseFiles(t *testing.T) {
+	dir := t.TempDir()
+	random := rand.New(rand.NewSource(1))
+	contents := map[string][]byte{
+		"small.txt":  []byte("hello"),
+		"medium.bin": make([]byte, 2*1024*1024),
+		"large.bin":  make([]byte, 11*1024*1024),
+	}
+	random.Read(contents["medium.bin"])
+	random.Read(contents["large.bin"])
+
+	archivePath := filepath.Join(dir, "files.tar.gz")
+	file, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed := gzip.NewWriter(file)
+	writer := tar.NewWriter(compressed)
+	for name, content := range contents {
+		writer.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		writer.Write(content)
+	}
+	writer.Close()
+	compressed.Close()
+	file.Close()
+
+	for name, content := range contents {
+		loose := filepath.Join(dir, name)
+		if err := os.WriteFile(loose, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		for _, partial := range []bool{true, false} {
+			want, err := CalculateHash(loose, partial)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := CalculateHash(archives.VirtualPath(archivePath, name), partial)
+			if err != nil {
+				t.Fatalf("%s (partial %v): %v", name, partial, err)
+			}
+			if got != want {
+				t.Errorf("%s (partial %v): member hash %s, loose file hash %s", name, partial, got, want)
+			}
+		}
+	}
+}
+
+func TestTarHashesFollowArchiveChanges(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "files.tar")
+	writeTar := func(content string, modTime time.Time) {
+		file, err := os.Create(archivePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writer := tar.NewWriter(file)
+		writer.WriteHeader(&tar.Header{Name: "a.txt", Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		writer.Write([]byte(content))
+		writer.Close()
+		file.Close()
+		os.Chtimes(archivePath, modTime, modTime)
+	}
+	member := archives.VirtualPath(archivePath, "a.txt")
+
+	writeTar("first", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	first, err := CalculateHash(member, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Same size, other content and time: the cached hashes of the old archive must not be used.
+	writeTar("other", time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	if second, err := CalculateHash(member, false); err != nil || second == first {
+		t.Errorf("hash after the archive changed is %s, %v; the old one was %s", second, err, first)
+	}
+
+	ClearArchiveHashes()
+	if len(tarHashes) != 0 {
+		t.Errorf("%d archives still cached after ClearArchiveHashes", len(tarHashes))
+	}
+}
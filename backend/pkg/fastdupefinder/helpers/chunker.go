@@ -0,0 +1,103 @@
+package helpers
+
+import (
+	"io"
+	"os"
+
+	"github.com/cespare/xxhash"
+)
+
+// Chunk size bounds of the content-defined chunker. Cut points are searched between
+// MinChunkSize and MaxChunkSize bytes and average about AvgChunkSize bytes.
+const (
+	MinChunkSize = 16 * 1024
+	AvgChunkSize = 64 * 1024
+	MaxChunkSize = 256 * 1024
+)
+
+// FastCDC normalised chunking: a stricter mask before AvgChunkSize and a looser one after it
+// keep chunk sizes close to the average. The masks test the high bits of the gear hash, which
+// depend on the last 64 bytes read.
+const (
+	chunkMaskStrict = uint64(1<<18-1) << (64 - 18) // 2 bits more than log2(AvgChunkSize)
+	chunkMaskLoose  = uint64(1<<14-1) << (64 - 14) // 2 bits fewer than log2(AvgChunkSize)
+)
+
+// gearTable maps every byte value to a pseudo-random 64-bit value for the rolling gear hash.
+var gearTable = func() [256]uint64 {
+	var table [256]uint64
+	state := uint64(0x6EA7)
+	for i := range table {
+		state += 0x9E3779B97F4A7C15
+		table[i] = mix64(state)
+	}
+	return table
+}()
+
+// ChunkFile splits a file into content-defined chunks (FastCDC) and calls OnChunk with the
+// xxhash and size of each chunk in file order. Since cut points depend on the content only,
+// an insertion or deletion changes the chunks around it but not the ones after it.
+func ChunkFile(FilePath string, OnChunk func(Hash uint64, Size int)) error {
+	file, err := os.Open(FilePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	buffer := make([]byte, 2*MaxChunkSize)
+	start, end := 0, 0
+	atEOF := false
+	for {
+		// Keep at least MaxChunkSize bytes buffered unless the file ends first.
+		if !atEOF && end-start < MaxChunkSize {
+			copy(buffer, buffer[start:end])
+			end -= start
+			start = 0
+			n, err := io.ReadFull(file, buffer[end:])
+			end += n
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				atEOF = true
+			} else if err != nil {
+				return err
+			}
+		}
+		if start == end {
+			return nil
+		}
+
+		size := findChunkBoundary(buffer[start:end])
+		OnChunk(xxhash.Sum64(buffer[start:start+size]), size)
+		start += size
+	}
+}
+
+// findChunkBoundary returns the length of the chunk at the start of data.
+func findChunkBoundary(data []byte) int {
+	n := len(data)
+	if n <= MinChunkSize {
+		return n
+	}
+	if n > MaxChunkSize {
+		n = MaxChunkSize
+	}
+	normal := AvgChunkSize
+	if n < normal {
+		normal = n
+	}
+
+	var fingerprint uint64
+	i := MinChunkSize
+	for ; i < normal; i++ {
+		fingerprint = fingerprint<<1 + gearTable[data[i]]
+		if fingerprint&chunkMaskStrict == 0 {
+			return i + 1
+		}
+	}
+	for ; i < n; i++ {
+		fingerprint = fingerprint<<1 + gearTable[data[i]]
+		if fingerprint&chunkMaskLoose == 0 {
+			return i + 1
+		}
+	}
+	return n
+}
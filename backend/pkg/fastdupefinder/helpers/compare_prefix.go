@@ -0,0 +1,66 @@
+package helpers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/cespare/xxhash"
+)
+
+// CalculateLeadingBlockHash hashes the first PartialHashSize bytes of a file, the same block
+// the partial hash of CalculateHash starts with. Files sharing it may be prefixes of each other.
+func CalculateLeadingBlockHash(FilePath string) (string, error) {
+	file, err := os.Open(FilePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := xxhash.New()
+	if _, err := io.CopyN(hash, file, PartialHashSize); err != nil && err != io.EOF {
+		return "", err
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+// IsPrefixOf reports whether the content of PrefixPath equals the beginning of the content of
+// FilePath. The files are compared block by block, stopping at the first difference.
+func IsPrefixOf(PrefixPath string, FilePath string) (bool, error) {
+	prefixFile, err := os.Open(PrefixPath)
+	if err != nil {
+		return false, err
+	}
+	defer prefixFile.Close()
+
+	file, err := os.Open(FilePath)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	prefixBuffer := make([]byte, 64*1024)
+	buffer := make([]byte, len(prefixBuffer))
+	for {
+		n, err := io.ReadFull(prefixFile, prefixBuffer)
+		if n > 0 {
+			if _, err := io.ReadFull(file, buffer[:n]); err != nil {
+				// FilePath is shorter than PrefixPath.
+				if err == io.EOF || err == io.ErrUnexpectedEOF {
+					return false, nil
+				}
+				return false, err
+			}
+			if !bytes.Equal(prefixBuffer[:n], buffer[:n]) {
+				return false, nil
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package helpers
+
+import (
+	"bufio"
+	"compress/bzip2"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Compression formats recognised by the decompression-aware comparison.
+const (
+	CompressionGzip  = "gzip"
+	CompressionBzip2 = "bzip2"
+	CompressionZlib  = "zlib"
+)
+
+// CompressionFormat returns the compression format implied by a file's extension,
+// or an empty string if the file isn't a supported compressed file.
+// Tarballs (.tar.gz, .tgz) are included; their decompressed form is the plain .tar.
+func CompressionFormat(FilePath string) string {
+	switch strings.ToLower(filepath.Ext(FilePath)) {
+	case ".gz", ".tgz":
+		return CompressionGzip
+	case ".bz2", ".tbz2":
+		return CompressionBzip2
+	case ".zz", ".zlib":
+		return CompressionZlib
+	}
+	return ""
+}
+
+// decompressedReader closes the decompressor together with the underlying file.
+type decompressedReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (d *decompressedReader) Close() error {
+	var firstErr error
+	for _, closer := range d.closers {
+		if err := closer.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// OpenDecompressed opens a gzip, bzip2 or zlib file and returns a stream of its decompressed content.
+// Multi-member gzip files are read in full, like gunzip does.
+func OpenDecompressed(FilePath string) (io.ReadCloser, error) {
+	format := CompressionFormat(FilePath)
+	if format == "" {
+		return nil, fmt.Errorf("not a compressed file: %s", FilePath)
+	}
+
+	file, err := os.Open(FilePath)
+	if err != nil {
+		return nil, err
+	}
+	buffered := bufio.NewReader(file)
+
+	switch format {
+	case CompressionGzip:
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return &decompressedReader{Reader: gz, closers: []io.Closer{gz, file}}, nil
+	case CompressionBzip2:
+		return &decompressedReader{Reader: bzip2.NewReader(buffered), closers: []io.Closer{file}}, nil
+	default:
+		zr, err := zlib.NewReader(buffered)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return &decompressedReader{Reader: zr, closers: []io.Closer{zr, file}}, nil
+	}
+}
+
+// CalculateDecompressedHash computes the full hash and size of a compressed file's content.
+// The hash has the same format as CalculateHash, so it can be compared with plain files.
+func CalculateDecompressedHash(FilePath string) (string, int64, error) {
+	content, err := OpenDecompressed(FilePath)
+	if err != nil {
+		return "", 0, err
+	}
+	defer content.Close()
+
+	counter := &countingReader{Reader: content}
+	hash, err := HashReader(counter)
+	if err != nil {
+		return "", 0, err
+	}
+	return hash, counter.count, nil
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	io.Reader
+	count int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.Reader.Read(p)
+	c.count += int64(n)
+	return n, err
+}
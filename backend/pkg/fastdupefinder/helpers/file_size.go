@@ -0,0 +1,31 @@
+package helpers
+
+import (
+	"os"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/archives"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/s3"
+)
+
+// FileSize returns the size of a file, the uncompressed size for an archive member path or
+// the object size for an S3 path.
+func FileSize(FilePath string) (int64, error) {
+	if s3.IsObjectPath(FilePath) {
+		bucket, key, _ := s3.SplitObjectPath(FilePath)
+		return s3.DefaultClient().Stat(bucket, key)
+	}
+	if archives.IsVirtualPath(FilePath) {
+		content, size, err := archives.OpenVirtualPath(FilePath)
+		if err != nil {
+			return 0, err
+		}
+		content.Close()
+		return size, nil
+	}
+
+	info, err := os.Stat(FilePath)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
@@ -0,0 +1,123 @@
+package helpers
+
+import (
+	"path/filepath"
+	"sort"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types"
+	reporttypes "github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types/report_types"
+)
+
+// buildNestedFolderSets converts the top-level folder duplicates to FolderSets and attaches
+// every duplicate set that Phase 5 removed to the nearest duplicate folder set enclosing it.
+// A nested set whose copies live under several duplicate folder sets is listed under each of them.
+func buildNestedFolderSets(
+	filteredFileDuplicates,
+	filteredFolderDuplicates,
+	allFileDuplicates,
+	allFolderDuplicates map[string][]string,
+	tree *types.DirTree) []reporttypes.FolderSet {
+
+	// Step 1: Map every duplicate folder path to its signature, so the enclosing
+	// set of any path can be found by walking up its parent directories.
+	pathToSignature := make(map[string]string)
+	for signature, paths := range allFolderDuplicates {
+		for _, path := range paths {
+			pathToSignature[filepath.Clean(path)] = signature
+		}
+	}
+
+	// Step 2: Link each removed folder set to the folder sets directly enclosing it.
+	childFolders := make(map[string][]string)
+	for signature, paths := range allFolderDuplicates {
+		if _, isTopLevel := filteredFolderDuplicates[signature]; isTopLevel {
+			continue
+		}
+		for _, parent := range enclosingSignatures(paths, pathToSignature) {
+			childFolders[parent] = append(childFolders[parent], signature)
+		}
+	}
+
+	// Step 3: Link each file set to the folder sets enclosing the paths Phase 5 removed from it.
+	childFiles := make(map[string]map[string][]string)
+	for hash, paths := range allFileDuplicates {
+		kept := make(map[string]struct{}, len(filteredFileDuplicates[hash]))
+		for _, path := range filteredFileDuplicates[hash] {
+			kept[path] = struct{}{}
+		}
+		removed := make([]string, 0, len(paths))
+		for _, path := range paths {
+			if _, ok := kept[path]; !ok {
+				removed = append(removed, path)
+			}
+		}
+		for _, parent := range enclosingSignatures(removed, pathToSignature) {
+			if childFiles[parent] == nil {
+				childFiles[parent] = make(map[string][]string)
+			}
+			childFiles[parent][hash] = paths
+		}
+	}
+
+	// Step 4: Assemble the tree below each top-level set.
+	// The visiting set guards against cycles, which consistent scan data never produces.
+	visiting := make(map[string]bool)
+	var build func(signature string) reporttypes.FolderSet
+	build = func(signature string) reporttypes.FolderSet {
+		set := convertFolderSet(signature, allFolderDuplicates[signature], tree)
+		visiting[signature] = true
+		defer delete(visiting, signature)
+
+		nested := &reporttypes.NestedDuplicates{}
+		for _, child := range childFolders[signature] {
+			if !visiting[child] {
+				nested.FolderDuplicates = append(nested.FolderDuplicates, build(child))
+			}
+		}
+		sort.Slice(nested.FolderDuplicates, func(i, j int) bool {
+			return nested.FolderDuplicates[i].Signature < nested.FolderDuplicates[j].Signature
+		})
+		if len(childFiles[signature]) > 0 {
+			nested.FileDuplicates, _ = convertFileMapToSets(childFiles[signature], tree)
+		}
+
+		if len(nested.FolderDuplicates) > 0 || len(nested.FileDuplicates) > 0 {
+			set.Nested = nested
+		}
+		return set
+	}
+
+	sets := make([]reporttypes.FolderSet, 0, len(filteredFolderDuplicates))
+	for signature := range filteredFolderDuplicates {
+		sets = append(sets, build(signature))
+	}
+	// Sort by signature for deterministic output
+	sort.Slice(sets, func(i, j int) bool { return sets[i].Signature < sets[j].Signature })
+	return sets
+}
+
+// enclosingSignatures returns the signatures of the nearest duplicate folders that
+// contain the given paths, without repetitions and in sorted order.
+func enclosingSignatures(paths []string, pathToSignature map[string]string) []string {
+	seen := make(map[string]struct{})
+	var result []string
+	for _, path := range paths {
+		dir := filepath.Dir(filepath.Clean(path))
+		for {
+			if signature, ok := pathToSignature[dir]; ok {
+				if _, dup := seen[signature]; !dup {
+					seen[signature] = struct{}{}
+					result = append(result, signature)
+				}
+				break
+			}
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
+			}
+			dir = parent
+		}
+	}
+	sort.Strings(result)
+	return result
+}
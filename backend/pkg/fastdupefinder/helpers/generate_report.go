@@ -5,7 +5,14 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
+	"strings"
 
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/archives"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/ignore"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/s3"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/selection"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types"
 	reporttypes "github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types/report_types"
 )
 
@@ -32,6 +39,116 @@ func calculateFolderSize(folderPath string) int64 {
 	return totalSize
 }
 
+// folderSize returns the total size of a folder from the Phase 1 snapshot, falling back to
+// calculateFolderSize for folders that aren't in it.
+func folderSize(folderPath string, tree *types.DirTree) int64 {
+	if tree != nil {
+		if _, ok := tree.Get(folderPath); ok {
+			return tree.Summarize(folderPath, make(map[string]types.DirSummary)).TotalSize
+		}
+	}
+	return calculateFolderSize(folderPath)
+}
+
+// ReportOptions controls optional parts of the generated report.
+type ReportOptions struct {
+	// IncludeNested attaches the duplicate sets that Phase 5 removed as nested
+	// children of the top-level folder set that contains them.
+	IncludeNested bool `json:"includeNested"`
+
+	// KeepPolicy, if not empty, decides which copy of every file and folder set is kept:
+	// the set lists it first and marks each path "keep" or "remove".
+	KeepPolicy selection.Policy `json:"keepPolicy,omitempty"`
+
+	// Ignore, if not nil, leaves out the file and folder sets matching one of its entries,
+	// nested sets included, and counts them in SummaryInfo.SuppressedSets.
+	Ignore *ignore.Database `json:"-"`
+}
+
+// convertFileMapToSets converts a map of file duplicates to a slice of FileSet.
+// Truncates hash to 12 characters to save memory (sufficient for display).
+// Sizes come from the Phase 1 snapshot when tree is not nil.
+func convertFileMapToSets(dupes map[string][]string, tree *types.DirTree) ([]reporttypes.FileSet, int64) {
+	var totalWasted int64 = 0
+	sets := make([]reporttypes.FileSet, 0, len(dupes))
+	for hash, paths := range dupes {
+		// Prefer a local file for the size lookup; archive members and S3 objects are slower to open.
+		var virtualPaths, remotePaths []string
+		sizePath := ""
+		for _, path := range paths {
+			if archives.IsVirtualPath(path) {
+				virtualPaths = append(virtualPaths, path)
+			} else if s3.IsObjectPath(path) {
+				remotePaths = append(remotePaths, path)
+			} else if sizePath == "" {
+				sizePath = path
+			}
+		}
+		if sizePath == "" && len(paths) > 0 {
+			sizePath = paths[0]
+		}
+
+		var sizeBytes int64
+		if sizePath != "" {
+			size, err := snapshotFileSize(sizePath, tree)
+			if err == nil {
+				sizeBytes = size
+				// Wasted space is (count - 1) * size for this set
+				if len(paths) > 1 {
+					totalWasted += sizeBytes * int64(len(paths)-1)
+				}
+			} else {
+				log.Printf("Warning: Could not stat file %s to get size: %v", sizePath, err)
+				sizeBytes = -1 // Indicate error
+			}
+		}
+		sets = append(sets, reporttypes.FileSet{
+			Hash:         truncateHash(hash),
+			Paths:        paths,
+			SizeBytes:    sizeBytes,
+			VirtualPaths: virtualPaths,
+			RemotePaths:  remotePaths,
+		})
+	}
+	// Sort by hash for deterministic output
+	sort.Slice(sets, func(i, j int) bool { return sets[i].Hash < sets[j].Hash })
+	return sets, totalWasted
+}
+
+// convertFolderMapToSets converts a map of folder duplicates to a slice of FolderSet.
+// Truncates signature to 12 characters to save memory.
+func convertFolderMapToSets(dupes map[string][]string, tree *types.DirTree) []reporttypes.FolderSet {
+	sets := make([]reporttypes.FolderSet, 0, len(dupes))
+	for signature, paths := range dupes {
+		sets = append(sets, convertFolderSet(signature, paths, tree))
+	}
+	// Sort by signature for deterministic output
+	sort.Slice(sets, func(i, j int) bool { return sets[i].Signature < sets[j].Signature })
+	return sets
+}
+
+// convertFolderSet builds a single FolderSet, using the size of the first folder.
+// The size is summed from the Phase 1 snapshot when tree is not nil.
+func convertFolderSet(signature string, paths []string, tree *types.DirTree) reporttypes.FolderSet {
+	var sizeBytes int64
+	if len(paths) > 0 {
+		sizeBytes = folderSize(paths[0], tree)
+	}
+	return reporttypes.FolderSet{
+		Signature: truncateHash(signature),
+		Paths:     paths,
+		SizeBytes: sizeBytes,
+	}
+}
+
+// truncateHash shortens a hash or signature to its first 12 characters to save memory.
+func truncateHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
 // GenerateReport formats all findings into a optimized JSON structure.
 // Focuses on essential data while minimizing memory usage and generation time.
 func GenerateReport(
@@ -39,81 +156,345 @@ func GenerateReport(
 	filteredFolderDuplicates,
 	allFileDuplicates,
 	allFolderDuplicates map[string][]string) reporttypes.ReportOutput {
+	return GenerateReportWithOptions(filteredFileDuplicates, filteredFolderDuplicates, allFileDuplicates, allFolderDuplicates, ReportOptions{})
+}
+
+// GenerateReportWithOptions formats all findings like GenerateReport and adds the
+// optional sections selected in Options.
+func GenerateReportWithOptions(
+	filteredFileDuplicates,
+	filteredFolderDuplicates,
+	allFileDuplicates,
+	allFolderDuplicates map[string][]string,
+	Options ReportOptions) reporttypes.ReportOutput {
+	return generateReport(filteredFileDuplicates, filteredFolderDuplicates, allFileDuplicates, allFolderDuplicates, Options, nil)
+}
 
-	// Helper function to convert a map of file duplicates to a slice of FileSet.
-	// Truncates hash to 12 characters to save memory (sufficient for display).
-	convertFileMapToSets := func(dupes map[string][]string) ([]reporttypes.FileSet, int64) {
-		var totalWasted int64 = 0
-		sets := make([]reporttypes.FileSet, 0, len(dupes))
-		for hash, paths := range dupes {
-			var sizeBytes int64
-			if len(paths) > 0 {
-				info, err := os.Stat(paths[0])
-				if err == nil {
-					sizeBytes = info.Size()
-					// Wasted space is (count - 1) * size for this set
-					if len(paths) > 1 {
-						totalWasted += sizeBytes * int64(len(paths)-1)
-					}
-				} else {
-					log.Printf("Warning: Could not stat file %s to get size: %v", paths[0], err)
-					sizeBytes = -1 // Indicate error
+// generateReport builds the sections shared by all reports. Sizes are read from tree when it
+// is not nil, so scans of sources other than the local disk can be reported.
+func generateReport(
+	filteredFileDuplicates,
+	filteredFolderDuplicates,
+	allFileDuplicates,
+	allFolderDuplicates map[string][]string,
+	Options ReportOptions,
+	tree *types.DirTree) reporttypes.ReportOutput {
+
+	// Generate only the essential data - no raw data to save memory
+	finalFileSets, wastedSpace := convertFileMapToSets(filteredFileDuplicates, tree)
+	var topLevelFolderSets []reporttypes.FolderSet
+	if Options.IncludeNested {
+		topLevelFolderSets = buildNestedFolderSets(filteredFileDuplicates, filteredFolderDuplicates, allFileDuplicates, allFolderDuplicates, tree)
+	} else {
+		topLevelFolderSets = convertFolderMapToSets(filteredFolderDuplicates, tree)
+	}
+
+	// Assemble the optimized JSON object with minimal fields
+	report := reporttypes.ReportOutput{
+		Summary: reporttypes.SummaryInfo{
+			FileSets:         len(filteredFileDuplicates),
+			FolderSets:       len(filteredFolderDuplicates),
+			WastedSpaceBytes: wastedSpace,
+		},
+		FileDuplicates:   finalFileSets,
+		FolderDuplicates: topLevelFolderSets,
+	}
+	if Options.Ignore != nil {
+		suppressIgnored(&report, Options.Ignore)
+	}
+	if len(Options.KeepPolicy) > 0 {
+		MarkKeepers(&report, Options.KeepPolicy)
+	}
+	return report
+}
+
+// GenerateReportFromResults formats the results of RunFinderWithResults, including the
+// optional sections of analyses that were enabled for the scan.
+func GenerateReportFromResults(Results *types.ScanResults, Options ReportOptions) reporttypes.ReportOutput {
+	report := generateReport(Results.FilteredFileDuplicates, Results.FilteredFolderDuplicates, Results.AllFileDuplicates, Results.AllFolderDuplicates, Options, Results.Tree)
+	if !Results.StartedAt.IsZero() {
+		startedAt := Results.StartedAt
+		report.Summary.ScanStartedAt = &startedAt
+	}
+
+	if len(Results.ArchiveFolderDuplicates) > 0 {
+		report.ArchiveDuplicates = convertArchiveMapToSets(Results.ArchiveFolderDuplicates, Results.Tree)
+		report.Summary.ArchiveSets = len(report.ArchiveDuplicates)
+	}
+
+	if len(Results.CompressedEquivalents) > 0 {
+		var wastedSpace int64
+		report.CompressedEquivalents, wastedSpace = convertCompressedMapToSets(Results.CompressedEquivalents, Results.Tree)
+		report.Summary.CompressedSets = len(report.CompressedEquivalents)
+		report.Summary.WastedSpaceBytes += wastedSpace
+	}
+
+	if len(Results.SimilarImages) > 0 {
+		report.SimilarImages = convertImageClustersToSets(Results.SimilarImages, Results.Tree)
+		report.Summary.SimilarImageSets = len(report.SimilarImages)
+	}
+
+	if len(Results.SimilarTexts) > 0 {
+		report.SimilarTexts = convertTextPairs(Results.SimilarTexts, Results.Tree)
+		report.Summary.SimilarTextPairs = len(report.SimilarTexts)
+	}
+
+	if len(Results.TruncatedCopies) > 0 {
+		report.TruncatedCopies = convertTruncatedCopies(Results.TruncatedCopies)
+		report.Summary.TruncatedCopies = len(report.TruncatedCopies)
+	}
+
+	if Results.SharedChunks != nil {
+		report.SharedChunks = convertChunkAnalysis(*Results.SharedChunks, Results.Tree)
+		report.Summary.SharedChunkPairs = len(report.SharedChunks.SharedPairs)
+	}
+
+	if len(Results.ContentDuplicates) > 0 {
+		report.ContentDuplicates = convertContentMapToSets(Results.ContentDuplicates, Results.Tree)
+		report.Summary.ContentSets = len(report.ContentDuplicates)
+	}
+
+	return report
+}
+
+// convertArchiveMapToSets converts the archive-to-folder map to a slice of ArchiveSet.
+// Archive sizes are read from the Phase 1 snapshot when available.
+func convertArchiveMapToSets(dupes map[string][]string, tree *types.DirTree) []reporttypes.ArchiveSet {
+	sets := make([]reporttypes.ArchiveSet, 0, len(dupes))
+	for archivePath, folderPaths := range dupes {
+		sets = append(sets, reporttypes.ArchiveSet{
+			ArchivePath:      archivePath,
+			FolderPaths:      folderPaths,
+			ReclaimableBytes: fileSize(archivePath, tree),
+		})
+	}
+	// Sort by path for deterministic output
+	sort.Slice(sets, func(i, j int) bool { return sets[i].ArchivePath < sets[j].ArchivePath })
+	return sets
+}
+
+// convertCompressedMapToSets converts the compressed equivalents map to a slice of CompressedSet.
+// Wasted space is computed on the compressed files: all of them are redundant when a plain copy
+// exists, otherwise all but the smallest one.
+func convertCompressedMapToSets(groups map[string][]string, tree *types.DirTree) ([]reporttypes.CompressedSet, int64) {
+	var totalWasted int64
+	sets := make([]reporttypes.CompressedSet, 0, len(groups))
+	for key, paths := range groups {
+		// The key is "size-hash" of the decompressed content.
+		keyParts := strings.SplitN(key, "-", 2)
+		if len(keyParts) != 2 {
+			continue
+		}
+		decompressedSize, _ := strconv.ParseInt(keyParts[0], 10, 64)
+
+		set := reporttypes.CompressedSet{
+			Hash:                  truncateHash(keyParts[1]),
+			Paths:                 []string{},
+			DecompressedSizeBytes: decompressedSize,
+		}
+		var compressedTotal, smallest int64 = 0, -1
+		for _, path := range paths {
+			if CompressionFormat(path) == "" {
+				set.Paths = append(set.Paths, path)
+				continue
+			}
+			set.CompressedPaths = append(set.CompressedPaths, path)
+			if size := fileSize(path, tree); size > 0 {
+				compressedTotal += size
+				if smallest < 0 || size < smallest {
+					smallest = size
 				}
 			}
-			// Truncate hash to first 12 characters to save memory
-			truncatedHash := hash
-			if len(hash) > 12 {
-				truncatedHash = hash[:12]
+		}
+		set.WastedBytes = compressedTotal
+		if len(set.Paths) == 0 && smallest > 0 {
+			set.WastedBytes -= smallest
+		}
+		sort.Strings(set.Paths)
+		sort.Strings(set.CompressedPaths)
+
+		totalWasted += set.WastedBytes
+		sets = append(sets, set)
+	}
+	// Sort by hash for deterministic output
+	sort.Slice(sets, func(i, j int) bool { return sets[i].Hash < sets[j].Hash })
+	return sets, totalWasted
+}
+
+// convertImageClustersToSets converts image clusters to SimilarImageSets, measuring
+// distances against the first (largest) image of each cluster.
+func convertImageClustersToSets(clusters [][]types.ImageFingerprint, tree *types.DirTree) []reporttypes.SimilarImageSet {
+	sets := make([]reporttypes.SimilarImageSet, 0, len(clusters))
+	for _, cluster := range clusters {
+		set := reporttypes.SimilarImageSet{Images: make([]reporttypes.SimilarImage, 0, len(cluster))}
+		for _, fingerprint := range cluster {
+			distance := HammingDistance(cluster[0].PHash, fingerprint.PHash)
+			if distance > set.MaxDistance {
+				set.MaxDistance = distance
 			}
-			sets = append(sets, reporttypes.FileSet{
-				Hash:      truncatedHash,
-				Paths:     paths,
-				SizeBytes: sizeBytes,
+			set.Images = append(set.Images, reporttypes.SimilarImage{
+				Path:      fingerprint.Path,
+				Width:     fingerprint.Width,
+				Height:    fingerprint.Height,
+				SizeBytes: fileSize(fingerprint.Path, tree),
+				Distance:  distance,
 			})
 		}
-		// Sort by hash for deterministic output
-		sort.Slice(sets, func(i, j int) bool { return sets[i].Hash < sets[j].Hash })
-		return sets, totalWasted
+		sets = append(sets, set)
+	}
+	return sets
+}
+
+// convertTextPairs converts near-duplicate text pairs to SimilarTextPairs, keeping their order.
+func convertTextPairs(pairs []types.SimilarTextPair, tree *types.DirTree) []reporttypes.SimilarTextPair {
+	converted := make([]reporttypes.SimilarTextPair, 0, len(pairs))
+	for _, pair := range pairs {
+		converted = append(converted, reporttypes.SimilarTextPair{
+			Paths:      [2]string{pair.PathA, pair.PathB},
+			SizeBytes:  [2]int64{fileSize(pair.PathA, tree), fileSize(pair.PathB, tree)},
+			Similarity: pair.Similarity,
+		})
+	}
+	return converted
+}
+
+// convertTruncatedCopies converts truncated copies to their report form, keeping their order.
+func convertTruncatedCopies(copies []types.TruncatedCopy) []reporttypes.TruncatedCopy {
+	converted := make([]reporttypes.TruncatedCopy, 0, len(copies))
+	for _, truncated := range copies {
+		converted = append(converted, reporttypes.TruncatedCopy{
+			Path:              truncated.Path,
+			SizeBytes:         truncated.Size,
+			CompletePath:      truncated.CompletePath,
+			CompleteSizeBytes: truncated.CompleteSize,
+			PercentComplete:   float64(truncated.Size) * 100 / float64(truncated.CompleteSize),
+		})
+	}
+	return converted
+}
+
+// convertChunkAnalysis converts the shared chunk analysis to its report form, keeping the pair order.
+func convertChunkAnalysis(analysis types.ChunkAnalysis, tree *types.DirTree) *reporttypes.ChunkAnalysis {
+	converted := &reporttypes.ChunkAnalysis{
+		FilesAnalysed:         analysis.FilesAnalysed,
+		TotalBytes:            analysis.TotalBytes,
+		UniqueBytes:           analysis.UniqueBytes,
+		EstimatedSavingsBytes: analysis.TotalBytes - analysis.UniqueBytes,
+		SharedPairs:           make([]reporttypes.SharedChunkPair, 0, len(analysis.SharedPairs)),
+	}
+	for _, pair := range analysis.SharedPairs {
+		sizes := [2]int64{fileSize(pair.PathA, tree), fileSize(pair.PathB, tree)}
+		var sharedPercent float64
+		if smaller := min(sizes[0], sizes[1]); smaller > 0 {
+			sharedPercent = float64(pair.SharedBytes) * 100 / float64(smaller)
+		}
+		converted.SharedPairs = append(converted.SharedPairs, reporttypes.SharedChunkPair{
+			Paths:         [2]string{pair.PathA, pair.PathB},
+			SizeBytes:     sizes,
+			SharedBytes:   pair.SharedBytes,
+			SharedPercent: sharedPercent,
+		})
 	}
+	return converted
+}
 
-	// Helper function to convert a map of folder duplicates to a slice of FolderSet.
-	// Truncates signature to 12 characters to save memory.
-	convertFolderMapToSets := func(dupes map[string][]string) []reporttypes.FolderSet {
-		sets := make([]reporttypes.FolderSet, 0, len(dupes))
-		for signature, paths := range dupes {
-			// Calculate folder size by getting the size of the first folder
-			var sizeBytes int64
-			if len(paths) > 0 {
-				sizeBytes = calculateFolderSize(paths[0])
+// convertContentMapToSets converts content duplicates of all categories to a slice of ContentSet,
+// ordered by category and hash.
+func convertContentMapToSets(categories map[string]map[string][]string, tree *types.DirTree) []reporttypes.ContentSet {
+	var sets []reporttypes.ContentSet
+	for category, groups := range categories {
+		for hash, paths := range groups {
+			set := reporttypes.ContentSet{
+				Category:    category,
+				Description: types.ContentCategoryDescriptions[category],
+				Hash:        truncateHash(hash),
+				Files:       make([]reporttypes.ContentFile, 0, len(paths)),
 			}
-			// Truncate signature to first 12 characters to save memory
-			truncatedSignature := signature
-			if len(signature) > 12 {
-				truncatedSignature = signature[:12]
+			sortedPaths := append([]string(nil), paths...)
+			sort.Strings(sortedPaths)
+			for _, path := range sortedPaths {
+				set.Files = append(set.Files, reporttypes.ContentFile{Path: path, SizeBytes: fileSize(path, tree)})
 			}
-			sets = append(sets, reporttypes.FolderSet{
-				Signature: truncatedSignature,
-				Paths:     paths,
-				SizeBytes: sizeBytes,
-			})
+			if category == types.ContentCategoryAudioTags {
+				summariseTagDifferences(&set)
+			}
+			sets = append(sets, set)
+		}
+	}
+	// Sort by category and hash for deterministic output
+	sort.Slice(sets, func(i, j int) bool {
+		if sets[i].Category != sets[j].Category {
+			return sets[i].Category < sets[j].Category
+		}
+		return sets[i].Hash < sets[j].Hash
+	})
+	return sets
+}
+
+// summariseTagDifferences reads the tags of every file in an audio set and keeps, per file,
+// only the fields whose values are not the same across the set.
+func summariseTagDifferences(set *reporttypes.ContentSet) {
+	allTags := make([]map[string]string, len(set.Files))
+	fields := make(map[string]struct{})
+	for i, file := range set.Files {
+		tags, err := ReadAudioTags(file.Path)
+		if err != nil {
+			log.Printf("Warning: Could not read tags of %s: %v", file.Path, err)
+		}
+		allTags[i] = tags
+		for field := range tags {
+			fields[field] = struct{}{}
 		}
-		// Sort by signature for deterministic output
-		sort.Slice(sets, func(i, j int) bool { return sets[i].Signature < sets[j].Signature })
-		return sets
 	}
 
-	// Generate only the essential data - no raw data to save memory
-	finalFileSets, wastedSpace := convertFileMapToSets(filteredFileDuplicates)
-	topLevelFolderSets := convertFolderMapToSets(filteredFolderDuplicates)
+	for field := range fields {
+		for _, tags := range allTags[1:] {
+			if tags[field] != allTags[0][field] {
+				set.DifferingTags = append(set.DifferingTags, field)
+				break
+			}
+		}
+	}
+	sort.Strings(set.DifferingTags)
 
-	// Assemble the optimized JSON object with minimal fields
-	return reporttypes.ReportOutput{
-		Summary: reporttypes.SummaryInfo{
-			FileSets:         len(filteredFileDuplicates),
-			FolderSets:       len(filteredFolderDuplicates),
-			WastedSpaceBytes: wastedSpace,
-		},
-		FileDuplicates:   finalFileSets,
-		FolderDuplicates: topLevelFolderSets,
+	for i := range set.Files {
+		if len(set.DifferingTags) == 0 {
+			break
+		}
+		set.Files[i].Tags = make(map[string]string, len(set.DifferingTags))
+		for _, field := range set.DifferingTags {
+			set.Files[i].Tags[field] = allTags[i][field]
+		}
+	}
+}
+
+// snapshotFileSize returns the size of a file from the Phase 1 snapshot, falling back to FileSize
+// for files that aren't in it, such as archive members and S3 objects.
+func snapshotFileSize(filePath string, tree *types.DirTree) (int64, error) {
+	if tree != nil {
+		if node, ok := tree.Get(filepath.Dir(filePath)); ok {
+			if size, ok := node.Files[filepath.Base(filePath)]; ok {
+				return size, nil
+			}
+		}
+	}
+	return FileSize(filePath)
+}
+
+// fileSize returns the size of a file from the Phase 1 snapshot, falling back to os.Stat.
+// It returns -1 if the size can't be determined.
+func fileSize(filePath string, tree *types.DirTree) int64 {
+	if tree != nil {
+		if node, ok := tree.Get(filepath.Dir(filePath)); ok {
+			if size, ok := node.Files[filepath.Base(filePath)]; ok {
+				return size
+			}
+		}
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		log.Printf("Warning: Could not stat file %s to get size: %v", filePath, err)
+		return -1
 	}
+	return info.Size()
 }
@@ -0,0 +1,89 @@
+package helpers
+
+import (
+	"os"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/archives"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/s3"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/selection"
+	reporttypes "github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types/report_types"
+)
+
+// MarkWithExpression evaluates an expression for the copies of every file set of a report,
+// nested sets included, and marks the copies it selects for removal and the others as kept.
+// The kept copy the expression sees as keeper is the one the report already marks as kept, or
+// else the first copy on disk. Archive members, S3 objects and copies missing from disk are
+// never selected.
+// If the expression selects every copy on disk of a set, the whole set is marked as kept and its
+// hash is returned, so applying the marks never removes all copies of a set.
+func MarkWithExpression(Report *reporttypes.ReportOutput, Expression *selection.Expression, Roots []selection.Root) []string {
+	var skipped []string
+	infos := make(map[string]os.FileInfo)
+	markSet := func(set *reporttypes.FileSet) {
+		if !markSetWithExpression(set, Expression, Roots, infos) {
+			skipped = append(skipped, set.Hash)
+		}
+	}
+
+	var markFolders func(folderSets []reporttypes.FolderSet)
+	markFolders = func(folderSets []reporttypes.FolderSet) {
+		for _, folderSet := range folderSets {
+			if folderSet.Nested != nil {
+				for i := range folderSet.Nested.FileDuplicates {
+					markSet(&folderSet.Nested.FileDuplicates[i])
+				}
+				markFolders(folderSet.Nested.FolderDuplicates)
+			}
+		}
+	}
+	for i := range Report.FileDuplicates {
+		markSet(&Report.FileDuplicates[i])
+	}
+	markFolders(Report.FolderDuplicates)
+	return skipped
+}
+
+// markSetWithExpression marks the copies of a single file set. It returns false if the
+// expression selected every copy on disk, in which case all copies are marked as kept.
+// infos caches the Lstat result of each path across sets, nil for paths that can't be found.
+func markSetWithExpression(set *reporttypes.FileSet, expression *selection.Expression, roots []selection.Root, infos map[string]os.FileInfo) bool {
+	marks := make(map[string]string, len(set.Paths))
+	var files []selection.File
+	keeper := -1
+	for _, path := range set.Paths {
+		marks[path] = reporttypes.MarkKeep
+		if archives.IsVirtualPath(path) || s3.IsObjectPath(path) {
+			continue
+		}
+		info, ok := infos[path]
+		if !ok {
+			var err error
+			if info, err = os.Lstat(path); err != nil {
+				info = nil
+			}
+			infos[path] = info
+		}
+		if info == nil {
+			continue
+		}
+		if keeper < 0 || set.Marks[path] == reporttypes.MarkKeep && set.Marks[files[keeper].Path] != reporttypes.MarkKeep {
+			keeper = len(files)
+		}
+		files = append(files, selection.File{
+			Path:    path,
+			Size:    set.SizeBytes,
+			ModTime: info.ModTime(),
+			Root:    selection.RootLabel(roots, path),
+		})
+	}
+
+	selected := expression.Select(files, max(keeper, 0))
+	complete := len(selected) < len(files) || len(files) == 0
+	if complete {
+		for _, path := range selected {
+			marks[path] = reporttypes.MarkRemove
+		}
+	}
+	set.Marks = marks
+	return complete
+}
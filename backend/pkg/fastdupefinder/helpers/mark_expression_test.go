@@ -0,0 +1,39 @@
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/selection"
+	reporttypes "github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types/report_types"
+)
+
+func TestMarkWithExpressionKeepsMissingCopies(t *testing.T) {
+	dir := t.TempDir()
+	kept := filepath.Join(dir, "a.txt")
+	copyPath := filepath.Join(dir, "b.txt")
+	missing := filepath.Join(dir, "c.txt")
+	for _, path := range []string{kept, copyPath} {
+		if err := os.WriteFile(path, []byte("content"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	report := reporttypes.ReportOutput{
+		FileDuplicates: []reporttypes.FileSet{{Hash: "3f2a9c1b77d0", Paths: []string{kept, copyPath, missing}}},
+	}
+	expression, err := selection.CompileExpression(`name != "a.txt"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if skipped := MarkWithExpression(&report, expression, nil); len(skipped) != 0 {
+		t.Errorf("sets %v skipped, want none", skipped)
+	}
+	marks := report.FileDuplicates[0].Marks
+	for path, want := range map[string]string{kept: reporttypes.MarkKeep, copyPath: reporttypes.MarkRemove, missing: reporttypes.MarkKeep} {
+		if marks[path] != want {
+			t.Errorf("%s marked %q, want %q", filepath.Base(path), marks[path], want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package helpers
+
+import (
+	"os"
+	"time"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/archives"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/s3"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/selection"
+	reporttypes "github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types/report_types"
+)
+
+// MarkKeepers ranks the copies of every file and folder set of a report, nested sets included,
+// with a keep policy. Each set lists its kept copy first and marks the other copies for removal.
+// Archive members and S3 objects can't be removed, so they are always marked as kept and never
+// chosen as the kept copy of a set that also has copies on disk.
+// Nested sets of a folder set keep a copy inside its kept folder whenever they have one, so
+// applying the nested marks never removes content the kept folder relies on.
+func MarkKeepers(Report *reporttypes.ReportOutput, Policy selection.Policy) {
+	modTimes := make(map[string]time.Time)
+	for i := range Report.FileDuplicates {
+		markFileSet(&Report.FileDuplicates[i], Policy, modTimes)
+	}
+	markFolderSets(Report.FolderDuplicates, Policy, modTimes)
+}
+
+// markFolderSets marks the folder sets and, recursively, their nested sets, whose policy
+// first prefers copies inside the kept folder of the set.
+func markFolderSets(sets []reporttypes.FolderSet, policy selection.Policy, modTimes map[string]time.Time) {
+	for i := range sets {
+		set := &sets[i]
+		set.Paths, set.Marks = rankPaths(set.Paths, policy, modTimes)
+		if set.Nested != nil && len(set.Paths) > 0 {
+			nestedPolicy := append(selection.Policy{{Kind: selection.RulePrefer, Prefix: set.Paths[0]}}, policy...)
+			for j := range set.Nested.FileDuplicates {
+				markFileSet(&set.Nested.FileDuplicates[j], nestedPolicy, modTimes)
+			}
+			markFolderSets(set.Nested.FolderDuplicates, nestedPolicy, modTimes)
+		}
+	}
+}
+
+// markFileSet marks the copies of a single file set.
+func markFileSet(set *reporttypes.FileSet, policy selection.Policy, modTimes map[string]time.Time) {
+	set.Paths, set.Marks = rankPaths(set.Paths, policy, modTimes)
+}
+
+// rankPaths returns the paths of a set ordered by the policy, followed by the archive members and
+// S3 objects, and the mark of every path. The paths slice is not modified, since the scan results
+// share it between the top-level and the nested sets.
+func rankPaths(paths []string, policy selection.Policy, modTimes map[string]time.Time) ([]string, map[string]string) {
+	var candidates []selection.Candidate
+	var fixed []string
+	for _, path := range paths {
+		if archives.IsVirtualPath(path) || s3.IsObjectPath(path) {
+			fixed = append(fixed, path)
+			continue
+		}
+		modTime, ok := modTimes[path]
+		if !ok {
+			if info, err := os.Lstat(path); err == nil {
+				modTime = info.ModTime()
+			}
+			modTimes[path] = modTime
+		}
+		candidates = append(candidates, selection.Candidate{Path: path, ModTime: modTime})
+	}
+
+	ranked := make([]string, 0, len(paths))
+	marks := make(map[string]string, len(paths))
+	for i, candidate := range policy.Rank(candidates) {
+		ranked = append(ranked, candidate.Path)
+		marks[candidate.Path] = reporttypes.MarkRemove
+		if i == 0 {
+			marks[candidate.Path] = reporttypes.MarkKeep
+		}
+	}
+	for _, path := range fixed {
+		ranked = append(ranked, path)
+		marks[path] = reporttypes.MarkKeep
+	}
+	return ranked, marks
+}
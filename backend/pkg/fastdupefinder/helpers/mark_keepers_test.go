@@ -0,0 +1,47 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/selection"
+	reporttypes "github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types/report_types"
+)
+
+func TestMarkKeepersKeepsNestedCopiesInsideTheKeptFolder(t *testing.T) {
+	report := reporttypes.ReportOutput{
+		FolderDuplicates: []reporttypes.FolderSet{{
+			Paths: []string{"/a/x", "/b/keep"},
+			Nested: &reporttypes.NestedDuplicates{
+				FileDuplicates: []reporttypes.FileSet{{Paths: []string{"/a/x/f.txt", "/b/keep/f.txt"}}},
+				FolderDuplicates: []reporttypes.FolderSet{{
+					Paths: []string{"/a/x/sub", "/b/keep/sub"},
+					Nested: &reporttypes.NestedDuplicates{
+						FileDuplicates: []reporttypes.FileSet{{Paths: []string{"/a/x/sub/g.txt", "/b/keep/sub/g.txt"}}},
+					},
+				}},
+			},
+		}},
+	}
+	policy, err := selection.ParsePolicy([]string{"match=keep$"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	MarkKeepers(&report, policy)
+
+	folderSet := report.FolderDuplicates[0]
+	nestedFolderSet := folderSet.Nested.FolderDuplicates[0]
+	for _, kept := range []struct{ got, want string }{
+		{folderSet.Paths[0], "/b/keep"},
+		{folderSet.Nested.FileDuplicates[0].Paths[0], "/b/keep/f.txt"},
+		{nestedFolderSet.Paths[0], "/b/keep/sub"},
+		{nestedFolderSet.Nested.FileDuplicates[0].Paths[0], "/b/keep/sub/g.txt"},
+	} {
+		if kept.got != kept.want {
+			t.Errorf("kept %s, want %s", kept.got, kept.want)
+		}
+	}
+	if mark := folderSet.Nested.FileDuplicates[0].Marks["/a/x/f.txt"]; mark != reporttypes.MarkRemove {
+		t.Errorf("copy outside the kept folder marked %q, want %q", mark, reporttypes.MarkRemove)
+	}
+}
@@ -0,0 +1,113 @@
+package helpers
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/cespare/xxhash"
+)
+
+// utf8BOM is the byte order mark some editors put at the start of UTF-8 files.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// NewTextNormalisingHasher returns a HashFunc for UTF-8 text files that ignores a leading BOM
+// and the difference between CRLF, CR and LF line endings. If TrimTrailingWhitespace is true,
+// spaces and tabs at the end of lines are ignored as well.
+// Files that don't look like text (see IsTextFile) or exceed MaxTextSize return ErrNotApplicable.
+// If 'Partial' is true, only the first PartialHashSize bytes of normalised text are hashed.
+func NewTextNormalisingHasher(TrimTrailingWhitespace bool) HashFunc {
+	return func(FilePath string, Partial bool) (string, error) {
+		return calculateNormalisedTextHash(FilePath, Partial, TrimTrailingWhitespace)
+	}
+}
+
+// calculateNormalisedTextHash implements the HashFunc returned by NewTextNormalisingHasher.
+func calculateNormalisedTextHash(FilePath string, Partial bool, TrimTrailingWhitespace bool) (string, error) {
+	file, err := os.Open(FilePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+	if info.Size() > MaxTextSize {
+		return "", ErrNotApplicable
+	}
+
+	reader := bufio.NewReaderSize(file, TextSniffSize)
+	sniffed, err := reader.Peek(TextSniffSize)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	if !looksLikeText(sniffed, len(sniffed) == TextSniffSize) {
+		return "", ErrNotApplicable
+	}
+	if bytes.HasPrefix(sniffed, utf8BOM) {
+		reader.Discard(len(utf8BOM))
+	}
+
+	hash := xxhash.New()
+	limit := -1
+	if Partial {
+		limit = PartialHashSize
+	}
+	if err := normaliseText(reader, hash, limit, TrimTrailingWhitespace); err != nil {
+		return "", fmt.Errorf("%s: %w", FilePath, err)
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+// normaliseText copies text to out with all line endings turned into LF, optionally dropping
+// trailing spaces and tabs. It writes at most limit bytes unless limit is negative.
+func normaliseText(reader *bufio.Reader, out io.Writer, limit int, TrimTrailingWhitespace bool) error {
+	var written int
+	buffer := make([]byte, 0, 32*1024)
+	var pendingSpace []byte
+	afterCR := false
+
+	for limit < 0 || written+len(buffer) < limit {
+		c, err := reader.ReadByte()
+		if err == io.EOF {
+			if !TrimTrailingWhitespace {
+				buffer = append(buffer, pendingSpace...)
+			}
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case c == '\n' && afterCR:
+			// Second half of a CRLF, already written as LF.
+		case c == '\r' || c == '\n':
+			pendingSpace = pendingSpace[:0]
+			buffer = append(buffer, '\n')
+		case TrimTrailingWhitespace && (c == ' ' || c == '\t'):
+			pendingSpace = append(pendingSpace, c)
+		default:
+			buffer = append(buffer, pendingSpace...)
+			pendingSpace = pendingSpace[:0]
+			buffer = append(buffer, c)
+		}
+		afterCR = c == '\r'
+
+		if len(buffer) >= cap(buffer)/2 {
+			out.Write(buffer)
+			written += len(buffer)
+			buffer = buffer[:0]
+		}
+	}
+
+	if limit >= 0 && len(buffer) > limit-written {
+		buffer = buffer[:limit-written]
+	}
+	_, err := out.Write(buffer)
+	return err
+}
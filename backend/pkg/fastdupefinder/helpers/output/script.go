@@ -0,0 +1,179 @@
+package output
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/archives"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/s3"
+	reporttypes "github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types/report_types"
+)
+
+// ScriptAction selects what a removal script does with the redundant copies.
+type ScriptAction int
+
+const (
+	// ScriptRemove deletes the redundant copies.
+	ScriptRemove ScriptAction = iota
+	// ScriptHardlink replaces the redundant copies with hardlinks to the kept copy.
+	ScriptHardlink
+	// ScriptSymlink replaces the redundant copies with symlinks to the kept copy.
+	ScriptSymlink
+)
+
+// scriptFunctions are the names of the shell functions applying each action.
+var scriptFunctions = map[ScriptAction]string{
+	ScriptRemove:   "remove_copy",
+	ScriptHardlink: "hardlink_copy",
+	ScriptSymlink:  "symlink_copy",
+}
+
+// scriptHeader defines the checks and actions used by every line of a removal script.
+// Sizes are read with wc -c and contents compared with cmp, since the xxhash of the report
+// isn't available to POSIX tools; comparing with the kept copy is the stronger check anyway.
+const scriptHeader = `set -u
+
+DRY_RUN=
+[ "${1:-}" = "-n" ] && DRY_RUN=1
+FAILED=0
+
+# skip COPY REASON: reports a copy that is left alone.
+skip() {
+	printf 'skipped %s: %s\n' "$1" "$2" >&2
+	FAILED=1
+}
+
+# check_copy KEEPER COPY SIZE: both must still be regular files of SIZE bytes with the same content.
+check_copy() {
+	if [ ! -f "$1" ] || [ -h "$1" ]; then skip "$2" "the kept copy $1 is gone"; return 1; fi
+	if [ ! -f "$2" ] || [ -h "$2" ]; then skip "$2" "not a regular file anymore"; return 1; fi
+	if [ "$(wc -c < "$1" | tr -d ' ')" != "$3" ] || [ "$(wc -c < "$2" | tr -d ' ')" != "$3" ]; then
+		skip "$2" "size changed since the scan"; return 1
+	fi
+	if ! cmp -s -- "$1" "$2"; then skip "$2" "content differs from $1"; return 1; fi
+}
+
+# file_metadata FILE: prints the permissions, owner and group of a file as numbers of ls -ln.
+file_metadata() {
+	ls -ln -- "$1" | awk 'NR == 1 { print $1, $3, $4 }'
+}
+
+# keep KEEPER: marks the copy of a set that stays untouched.
+keep() {
+	:
+}
+
+# replace_with LINK COPY: renames LINK, created next to COPY, over COPY.
+replace_with() {
+	if mv -f -- "$1" "$2"; then printf 'linked %s\n' "$2"; else rm -f -- "$1"; FAILED=1; fi
+}
+
+remove_copy() {
+	check_copy "$1" "$2" "$3" || return 0
+	if [ -n "$DRY_RUN" ]; then printf 'would remove %s\n' "$2"; return 0; fi
+	if rm -f -- "$2"; then printf 'removed %s\n' "$2"; else FAILED=1; fi
+}
+
+# A hardlink takes over the permissions and owner of the kept copy, so copies that differ are skipped.
+hardlink_copy() {
+	check_copy "$1" "$2" "$3" || return 0
+	if [ "$(file_metadata "$1")" != "$(file_metadata "$2")" ]; then
+		skip "$2" "permissions or owner differ from $1"; return 0
+	fi
+	if [ -n "$DRY_RUN" ]; then printf 'would hardlink %s\n' "$2"; return 0; fi
+	if ln -- "$1" "$2.fdf-link"; then replace_with "$2.fdf-link" "$2"; else FAILED=1; fi
+}
+
+symlink_copy() {
+	check_copy "$1" "$2" "$3" || return 0
+	if [ -n "$DRY_RUN" ]; then printf 'would symlink %s\n' "$2"; return 0; fi
+	if ln -s -- "$1" "$2.fdf-link"; then replace_with "$2.fdf-link" "$2"; else FAILED=1; fi
+}
+`
+
+// ShellScript renders a POSIX shell script that applies an action to the copies a report marks
+// for removal, or to every copy but the first of file sets without marks, nested sets included.
+// Each set lists its kept copy followed by one guarded command per redundant copy, which only acts
+// if both files still have the size of the report and the same content, and for hardlinks the same
+// permissions and owner. Paths are made absolute and single-quoted, so any file name is passed
+// through unchanged. Running the script with -n only reports what it would do.
+func ShellScript(report reporttypes.ReportOutput, action ScriptAction) string {
+	var script strings.Builder
+	script.WriteString("#!/bin/sh\n")
+	fmt.Fprintf(&script, "# Generated by fast-dupe-finder on %s. Review before running;\n", time.Now().Format("2006-01-02 15:04:05"))
+	script.WriteString("# run with -n to see what would be done without changing anything.\n")
+	script.WriteString(scriptHeader)
+
+	handled := make(map[string]bool)
+	number := 0
+	writeSet := func(set reporttypes.FileSet) {
+		keeper, copies := scriptTargets(set, handled)
+		if keeper == "" || len(copies) == 0 {
+			return
+		}
+		number++
+		// File names only ever appear quoted, never in comments, where a newline would end the comment.
+		fmt.Fprintf(&script, "\n# Set %d (hash %s, %d bytes)\n", number, set.Hash, set.SizeBytes)
+		fmt.Fprintf(&script, "keep %s\n", shellQuote(absolutePath(keeper)))
+		for _, copyPath := range copies {
+			handled[copyPath] = true
+			fmt.Fprintf(&script, "%s %s %s %d\n", scriptFunctions[action], shellQuote(absolutePath(keeper)), shellQuote(absolutePath(copyPath)), set.SizeBytes)
+		}
+	}
+
+	var writeFolders func(folderSets []reporttypes.FolderSet)
+	writeFolders = func(folderSets []reporttypes.FolderSet) {
+		for _, folderSet := range folderSets {
+			if folderSet.Nested != nil {
+				for _, set := range folderSet.Nested.FileDuplicates {
+					writeSet(set)
+				}
+				writeFolders(folderSet.Nested.FolderDuplicates)
+			}
+		}
+	}
+	for _, set := range report.FileDuplicates {
+		writeSet(set)
+	}
+	writeFolders(report.FolderDuplicates)
+
+	script.WriteString("\nexit $FAILED\n")
+	return script.String()
+}
+
+// scriptTargets returns the kept copy of a set and the copies to act on, leaving out archive
+// members, S3 objects and copies already handled by an earlier set.
+func scriptTargets(set reporttypes.FileSet, handled map[string]bool) (string, []string) {
+	keeper := ""
+	var copies []string
+	for i, path := range set.Paths {
+		if archives.IsVirtualPath(path) || s3.IsObjectPath(path) {
+			continue
+		}
+		remove := set.Marks[path] == reporttypes.MarkRemove || set.Marks == nil && i > 0
+		switch {
+		case !remove && keeper == "":
+			keeper = path
+		case remove && !handled[path]:
+			copies = append(copies, path)
+		}
+	}
+	return keeper, copies
+}
+
+// absolutePath makes a path absolute, so the script can run from any folder and symlinks
+// point to the right file.
+func absolutePath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// shellQuote quotes a string for POSIX shells. Single quotes keep every other character,
+// newlines included, literal; embedded single quotes are closed, escaped and reopened.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
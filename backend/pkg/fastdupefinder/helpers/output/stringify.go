@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"strings"
 
 	reporttypes "github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types/report_types"
 )
@@ -29,7 +30,7 @@ func StringifyFileResults(fileSets []reporttypes.FileSet) string {
 		}
 
 		for _, path := range set.Paths {
-			temp += fmt.Sprintf("  - %s\n", path)
+			temp += fmt.Sprintf("  - %s%s%s\n", path, virtualMarker(set, path), keepMarker(set.Marks, path))
 		}
 	}
 
@@ -48,11 +49,217 @@ func StringifyFolderResults(folderSets []reporttypes.FolderSet) string {
 
 	for i, set := range folderSets {
 		temp += fmt.Sprintf("\nSet %d (Folder Signature Hash: %s...):\n", i+1, set.Signature)
+		for _, path := range set.Paths {
+			temp += fmt.Sprintf("  - %s%s\n", path, keepMarker(set.Marks, path))
+		}
+		temp += stringifyNested(set.Nested, "  ")
+	}
+
+	return temp
+}
+
+// virtualMarker returns a note for paths that are members inside an archive or S3 objects.
+func virtualMarker(set reporttypes.FileSet, path string) string {
+	for _, virtualPath := range set.VirtualPaths {
+		if virtualPath == path {
+			return " (inside archive)"
+		}
+	}
+	for _, remotePath := range set.RemotePaths {
+		if remotePath == path {
+			return " (in S3)"
+		}
+	}
+	return ""
+}
+
+// keepMarker returns a note for the copies a keep policy marked as kept.
+func keepMarker(marks map[string]string, path string) string {
+	if marks[path] == reporttypes.MarkKeep {
+		return " [keep]"
+	}
+	return ""
+}
+
+// stringifyNested renders the nested duplicate tree of a folder set, indenting each level.
+func stringifyNested(nested *reporttypes.NestedDuplicates, indent string) string {
+	if nested == nil {
+		return ""
+	}
+
+	temp := ""
+	for _, set := range nested.FolderDuplicates {
+		temp += fmt.Sprintf("%sNested folder set (Folder Signature Hash: %s...):\n", indent, set.Signature)
+		for _, path := range set.Paths {
+			temp += fmt.Sprintf("%s  - %s%s\n", indent, path, keepMarker(set.Marks, path))
+		}
+		temp += stringifyNested(set.Nested, indent+"  ")
+	}
+	for _, set := range nested.FileDuplicates {
+		temp += fmt.Sprintf("%sNested file set (SHA256: %s...):\n", indent, set.Hash)
+		for _, path := range set.Paths {
+			temp += fmt.Sprintf("%s  - %s%s%s\n", indent, path, virtualMarker(set, path), keepMarker(set.Marks, path))
+		}
+	}
+	return temp
+}
+
+// StringifyArchiveResults returns a formatted string representation of the archives
+// whose content duplicates a folder.
+func StringifyArchiveResults(archiveSets []reporttypes.ArchiveSet) string {
+	if len(archiveSets) == 0 {
+		return "\n--- No archives duplicating folders found. ---"
+	}
+
+	temp := "\n--- Found Archives Duplicating Folders ---"
+	var totalReclaimable int64 = 0
+
+	for i, set := range archiveSets {
+		temp += fmt.Sprintf("\nSet %d: archive %s duplicates:\n", i+1, set.ArchivePath)
+		for _, path := range set.FolderPaths {
+			temp += fmt.Sprintf("  - folder %s\n", path)
+		}
+		if set.ReclaimableBytes > 0 {
+			totalReclaimable += set.ReclaimableBytes
+			temp += fmt.Sprintf("  Reclaimable: %d bytes\n", set.ReclaimableBytes)
+		}
+	}
+
+	temp += fmt.Sprintf("\nSummary: Found %d archives duplicating folders. Total reclaimable space: %d bytes.\n", len(archiveSets), totalReclaimable)
+	return temp
+}
+
+// StringifyCompressedResults returns a formatted string representation of files that
+// hold the same content as a compressed file once decompressed.
+func StringifyCompressedResults(compressedSets []reporttypes.CompressedSet) string {
+	if len(compressedSets) == 0 {
+		return "\n--- No compressed equivalents found. ---"
+	}
+
+	temp := "\n--- Found Compressed Equivalents ---"
+	var totalWastedSpace int64 = 0
+
+	for i, set := range compressedSets {
+		temp += fmt.Sprintf("\nSet %d (Content Hash: %s...):\n", i+1, set.Hash)
+		temp += fmt.Sprintf("  Decompressed size: %d bytes | Wasted: %d bytes\n", set.DecompressedSizeBytes, set.WastedBytes)
+		totalWastedSpace += set.WastedBytes
 		for _, path := range set.Paths {
 			temp += fmt.Sprintf("  - %s\n", path)
 		}
+		for _, path := range set.CompressedPaths {
+			temp += fmt.Sprintf("  - %s (compressed equivalent)\n", path)
+		}
+	}
+
+	temp += fmt.Sprintf("\nSummary: Found %d sets of compressed equivalents. Total wasted space: %d bytes.\n", len(compressedSets), totalWastedSpace)
+	return temp
+}
+
+// StringifySimilarImageResults returns a formatted string representation of clusters
+// of visually similar images.
+func StringifySimilarImageResults(imageSets []reporttypes.SimilarImageSet) string {
+	if len(imageSets) == 0 {
+		return "\n--- No similar images found. ---"
+	}
+
+	temp := "\n--- Found Similar Images ---"
+
+	for i, set := range imageSets {
+		temp += fmt.Sprintf("\nSet %d (max distance: %d):\n", i+1, set.MaxDistance)
+		for _, image := range set.Images {
+			temp += fmt.Sprintf("  - %s (%dx%d, %d bytes, distance %d)\n", image.Path, image.Width, image.Height, image.SizeBytes, image.Distance)
+		}
+	}
+
+	temp += fmt.Sprintf("\nSummary: Found %d sets of similar images.\n", len(imageSets))
+	return temp
+}
+
+// StringifySimilarTextResults returns a formatted string representation of pairs of
+// near-duplicate text files.
+func StringifySimilarTextResults(textPairs []reporttypes.SimilarTextPair) string {
+	if len(textPairs) == 0 {
+		return "\n--- No similar text files found. ---"
+	}
+
+	temp := "\n--- Found Similar Text Files ---"
+
+	for i, pair := range textPairs {
+		temp += fmt.Sprintf("\nPair %d (estimated similarity: %.1f%%):\n", i+1, pair.Similarity*100)
+		for j, path := range pair.Paths {
+			temp += fmt.Sprintf("  - %s (%d bytes)\n", path, pair.SizeBytes[j])
+		}
+	}
+
+	temp += fmt.Sprintf("\nSummary: Found %d pairs of similar text files.\n", len(textPairs))
+	return temp
+}
+
+// StringifyContentResults returns a formatted string representation of files that hold
+// the same content once format-specific noise such as metadata is ignored.
+func StringifyContentResults(contentSets []reporttypes.ContentSet) string {
+	if len(contentSets) == 0 {
+		return "\n--- No content-identical files found. ---"
+	}
+
+	temp := "\n--- Found Content-Identical Files ---"
+	temp += "\nThese files are not byte-identical; review the differences before deleting any of them.\n"
+
+	for i, set := range contentSets {
+		temp += fmt.Sprintf("\nSet %d: %s (Content Hash: %s...):\n", i+1, set.Description, set.Hash)
+		if len(set.DifferingTags) > 0 {
+			temp += fmt.Sprintf("  Differing tags: %s\n", strings.Join(set.DifferingTags, ", "))
+		}
+		for _, file := range set.Files {
+			temp += fmt.Sprintf("  - %s (%d bytes)\n", file.Path, file.SizeBytes)
+			for _, field := range set.DifferingTags {
+				temp += fmt.Sprintf("      %s: %q\n", field, file.Tags[field])
+			}
+		}
+	}
+
+	temp += fmt.Sprintf("\nSummary: Found %d sets of content-identical files.\n", len(contentSets))
+	return temp
+}
+
+// StringifyTruncatedResults returns a formatted string representation of files that are
+// truncated copies of a larger file.
+func StringifyTruncatedResults(truncatedCopies []reporttypes.TruncatedCopy) string {
+	if len(truncatedCopies) == 0 {
+		return "\n--- No truncated copies found. ---"
+	}
+
+	temp := "\n--- Found Truncated Copies ---\n"
+	var totalSize int64 = 0
+
+	for _, truncated := range truncatedCopies {
+		// Round down so that an incomplete file never shows as 100% complete.
+		temp += fmt.Sprintf("  - %s (%d bytes): truncated copy of %s (%d%% complete)\n",
+			truncated.Path, truncated.SizeBytes, truncated.CompletePath, int(truncated.PercentComplete))
+		totalSize += truncated.SizeBytes
+	}
+
+	temp += fmt.Sprintf("\nSummary: Found %d truncated copies. Total size: %d bytes.\n", len(truncatedCopies), totalSize)
+	return temp
+}
+
+// StringifyChunkResults returns a formatted string representation of the shared chunk analysis.
+func StringifyChunkResults(analysis *reporttypes.ChunkAnalysis) string {
+	if analysis == nil || len(analysis.SharedPairs) == 0 {
+		return "\n--- No large files sharing content found. ---"
+	}
+
+	temp := "\n--- Found Large Files Sharing Content ---"
+
+	for i, pair := range analysis.SharedPairs {
+		temp += fmt.Sprintf("\nPair %d (%d bytes shared, %.1f%% of the smaller file):\n", i+1, pair.SharedBytes, pair.SharedPercent)
+		for j, path := range pair.Paths {
+			temp += fmt.Sprintf("  - %s (%d bytes)\n", path, pair.SizeBytes[j])
+		}
 	}
 
+	temp += fmt.Sprintf("\nSummary: Analysed %d files (%d bytes). Storing shared chunks once would save an estimated %d bytes.\n",
+		analysis.FilesAnalysed, analysis.TotalBytes, analysis.EstimatedSavingsBytes)
 	return temp
 }
 
@@ -0,0 +1,285 @@
+package helpers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// id3v2FrameNames maps ID3v2 text frames to the Vorbis comment field names used for all formats.
+var id3v2FrameNames = map[string]string{
+	"TIT2": "title", "TT2": "title",
+	"TPE1": "artist", "TP1": "artist",
+	"TPE2": "albumartist", "TP2": "albumartist",
+	"TALB": "album", "TAL": "album",
+	"TRCK": "tracknumber", "TRK": "tracknumber",
+	"TPOS": "discnumber", "TPA": "discnumber",
+	"TDRC": "date", "TYER": "date", "TYE": "date",
+	"TCON": "genre", "TCO": "genre",
+	"TCOM": "composer", "TCM": "composer",
+}
+
+// ReadAudioTags returns the common tag fields (title, artist, album, date...) of an MP3 or FLAC
+// file, keyed by lower-case Vorbis comment names. ID3v2 values take precedence over Vorbis
+// comments, APE items and ID3v1 fields. Unreadable tags are skipped.
+func ReadAudioTags(FilePath string) (map[string]string, error) {
+	file, err := os.Open(FilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]string)
+	start := readID3v2Tags(file, tags)
+	readVorbisComments(file, start, tags)
+
+	end := info.Size()
+	var id3v1 [id3v1TagSize]byte
+	hasID3v1 := false
+	if end >= id3v1TagSize {
+		if _, err := file.ReadAt(id3v1[:], end-id3v1TagSize); err == nil && string(id3v1[:3]) == "TAG" {
+			hasID3v1 = true
+			end -= id3v1TagSize
+		}
+	}
+	if end >= apeFooterSize {
+		readAPEItems(file, end, tags)
+	}
+	if hasID3v1 {
+		readID3v1Tag(id3v1[:], tags)
+	}
+
+	return tags, nil
+}
+
+// setTag stores a tag value unless the field is already set or the value is empty.
+func setTag(tags map[string]string, name string, value string) {
+	value = strings.TrimSpace(strings.TrimRight(value, "\x00"))
+	if _, found := tags[name]; found || value == "" {
+		return
+	}
+	tags[name] = value
+}
+
+// readID3v2Tags reads text frames of the ID3v2 tags at the start of the file and returns
+// the offset after them.
+func readID3v2Tags(file *os.File, tags map[string]string) int64 {
+	var offset int64
+	for {
+		var header [id3v2HeaderSize]byte
+		if _, err := file.ReadAt(header[:], offset); err != nil || string(header[:3]) != "ID3" {
+			return offset
+		}
+		size := int64(syncsafeInt(header[6:10]))
+		body := make([]byte, size)
+		if _, err := file.ReadAt(body, offset+id3v2HeaderSize); err == nil {
+			parseID3v2Frames(body, header[3], header[5], tags)
+		}
+		offset += id3v2HeaderSize + size
+		if header[5]&0x10 != 0 {
+			offset += id3v2HeaderSize
+		}
+	}
+}
+
+// parseID3v2Frames extracts the text frames of an ID3v2.2, v2.3 or v2.4 tag body.
+func parseID3v2Frames(body []byte, version byte, flags byte, tags map[string]string) {
+	if flags&0x80 != 0 && version < 4 {
+		// Tag-wide unsynchronisation: 0xFF 0x00 stands for 0xFF.
+		body = bytes.ReplaceAll(body, []byte{0xFF, 0x00}, []byte{0xFF})
+	}
+	if flags&0x40 != 0 && version >= 3 && len(body) >= 4 {
+		// Skip the extended header.
+		if version == 3 {
+			body = body[min(len(body), 4+int(binary.BigEndian.Uint32(body[:4]))):]
+		} else {
+			body = body[min(len(body), syncsafeInt(body[:4])):]
+		}
+	}
+
+	idSize, headerSize := 4, 10
+	if version == 2 {
+		idSize, headerSize = 3, 6
+	}
+	for len(body) >= headerSize && body[0] != 0 {
+		id := string(body[:idSize])
+		var size int
+		switch version {
+		case 2:
+			size = int(body[3])<<16 | int(body[4])<<8 | int(body[5])
+		case 3:
+			size = int(binary.BigEndian.Uint32(body[4:8]))
+		default:
+			size = syncsafeInt(body[4:8])
+		}
+		if size < 0 || headerSize+size > len(body) {
+			return
+		}
+		if name, found := id3v2FrameNames[id]; found && size > 1 {
+			setTag(tags, name, decodeID3v2Text(body[headerSize:headerSize+size]))
+		}
+		body = body[headerSize+size:]
+	}
+}
+
+// decodeID3v2Text decodes a text frame: an encoding byte followed by the text.
+// Multiple values are joined with "/".
+func decodeID3v2Text(data []byte) string {
+	encoding, text := data[0], data[1:]
+	var value string
+	switch encoding {
+	case 1, 2:
+		// UTF-16 with BOM, or big-endian UTF-16 without.
+		order := binary.ByteOrder(binary.BigEndian)
+		if encoding == 1 && len(text) >= 2 {
+			if text[0] == 0xFF && text[1] == 0xFE {
+				order = binary.LittleEndian
+			}
+			text = text[2:]
+		}
+		units := make([]uint16, 0, len(text)/2)
+		for i := 0; i+1 < len(text); i += 2 {
+			units = append(units, order.Uint16(text[i:]))
+		}
+		value = string(utf16.Decode(units))
+	case 3:
+		value = string(text)
+	default:
+		value = latin1ToString(text)
+	}
+	return strings.ReplaceAll(strings.TrimRight(value, "\x00"), "\x00", "/")
+}
+
+// readVorbisComments reads the VORBIS_COMMENT block of a FLAC stream starting at offset.
+func readVorbisComments(file *os.File, offset int64, tags map[string]string) {
+	var magic [4]byte
+	if _, err := file.ReadAt(magic[:], offset); err != nil || string(magic[:]) != "fLaC" {
+		return
+	}
+	offset += 4
+	for {
+		var header [flacBlockHeaderSize]byte
+		if _, err := file.ReadAt(header[:], offset); err != nil {
+			return
+		}
+		length := int64(header[1])<<16 | int64(header[2])<<8 | int64(header[3])
+		if header[0]&0x7F == 4 {
+			block := make([]byte, length)
+			if _, err := file.ReadAt(block, offset+flacBlockHeaderSize); err == nil {
+				parseVorbisComments(block, tags)
+			}
+			return
+		}
+		if header[0]&0x80 != 0 {
+			return
+		}
+		offset += flacBlockHeaderSize + length
+	}
+}
+
+// parseVorbisComments extracts "NAME=value" fields from a Vorbis comment block.
+func parseVorbisComments(block []byte, tags map[string]string) {
+	reader := bytes.NewReader(block)
+	var vendorLength uint32
+	if binary.Read(reader, binary.LittleEndian, &vendorLength) != nil {
+		return
+	}
+	if _, err := reader.Seek(int64(vendorLength), io.SeekCurrent); err != nil {
+		return
+	}
+	var count uint32
+	if binary.Read(reader, binary.LittleEndian, &count) != nil {
+		return
+	}
+	for i := uint32(0); i < count; i++ {
+		var length uint32
+		if binary.Read(reader, binary.LittleEndian, &length) != nil || int64(length) > int64(reader.Len()) {
+			return
+		}
+		comment := make([]byte, length)
+		reader.Read(comment)
+		if name, value, found := strings.Cut(string(comment), "="); found {
+			setTag(tags, strings.ToLower(name), value)
+		}
+	}
+}
+
+// readAPEItems reads the text items of an APE tag whose footer ends at end.
+func readAPEItems(file *os.File, end int64, tags map[string]string) {
+	var footer [apeFooterSize]byte
+	if _, err := file.ReadAt(footer[:], end-apeFooterSize); err != nil || string(footer[:8]) != "APETAGEX" {
+		return
+	}
+	size := int64(binary.LittleEndian.Uint32(footer[12:16]))
+	count := binary.LittleEndian.Uint32(footer[16:20])
+	if size < apeFooterSize || size > end {
+		return
+	}
+	items := make([]byte, size-apeFooterSize)
+	if _, err := file.ReadAt(items, end-size); err != nil {
+		return
+	}
+
+	for i := uint32(0); i < count && len(items) > 8; i++ {
+		valueSize := int(binary.LittleEndian.Uint32(items[:4]))
+		itemFlags := binary.LittleEndian.Uint32(items[4:8])
+		keyEnd := bytes.IndexByte(items[8:], 0)
+		if keyEnd < 0 || valueSize < 0 || 8+keyEnd+1+valueSize > len(items) {
+			return
+		}
+		key := string(items[8 : 8+keyEnd])
+		value := items[8+keyEnd+1 : 8+keyEnd+1+valueSize]
+		if itemFlags&0x06 == 0 {
+			// UTF-8 text item; binary and external items are ignored.
+			name := strings.ToLower(key)
+			switch name {
+			case "year":
+				name = "date"
+			case "track":
+				name = "tracknumber"
+			case "disc":
+				name = "discnumber"
+			case "album artist":
+				name = "albumartist"
+			}
+			setTag(tags, name, strings.ReplaceAll(string(value), "\x00", "/"))
+		}
+		items = items[8+keyEnd+1+valueSize:]
+	}
+}
+
+// readID3v1Tag extracts the fixed-width fields of a 128-byte ID3v1 tag.
+func readID3v1Tag(tag []byte, tags map[string]string) {
+	setTag(tags, "title", latin1ToString(tag[3:33]))
+	setTag(tags, "artist", latin1ToString(tag[33:63]))
+	setTag(tags, "album", latin1ToString(tag[63:93]))
+	setTag(tags, "date", latin1ToString(tag[93:97]))
+	comment := tag[97:127]
+	if comment[28] == 0 && comment[29] != 0 {
+		// ID3v1.1 stores the track number in the last byte of the comment.
+		setTag(tags, "tracknumber", strconv.Itoa(int(comment[29])))
+		comment = comment[:28]
+	}
+	setTag(tags, "comment", latin1ToString(comment))
+}
+
+// latin1ToString decodes ISO-8859-1 text, stopping at the first NUL byte.
+func latin1ToString(data []byte) string {
+	if end := bytes.IndexByte(data, 0); end >= 0 {
+		data = data[:end]
+	}
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
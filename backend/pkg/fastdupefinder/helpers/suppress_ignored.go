@@ -0,0 +1,55 @@
+package helpers
+
+import (
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/ignore"
+	reporttypes "github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types/report_types"
+)
+
+// suppressIgnored removes the file and folder sets matching the ignore database from a report,
+// nested sets included, and counts them in the summary. The file and folder set counts and the
+// wasted space only cover the sets that are left.
+func suppressIgnored(report *reporttypes.ReportOutput, database *ignore.Database) {
+	fileSets := report.FileDuplicates[:0]
+	for _, set := range report.FileDuplicates {
+		if database.Matches(set.Hash, set.Paths) {
+			report.Summary.SuppressedSets++
+			report.Summary.FileSets--
+			if set.SizeBytes > 0 && len(set.Paths) > 1 {
+				report.Summary.WastedSpaceBytes -= set.SizeBytes * int64(len(set.Paths)-1)
+			}
+			continue
+		}
+		fileSets = append(fileSets, set)
+	}
+	report.FileDuplicates = fileSets
+
+	before := len(report.FolderDuplicates)
+	report.FolderDuplicates = suppressFolderSets(report.FolderDuplicates, database, &report.Summary.SuppressedSets)
+	report.Summary.FolderSets -= before - len(report.FolderDuplicates)
+}
+
+// suppressFolderSets returns the folder sets not matching the database, with the matching sets
+// removed from their nested sets as well, and adds the number of removed sets to suppressed.
+func suppressFolderSets(sets []reporttypes.FolderSet, database *ignore.Database, suppressed *int) []reporttypes.FolderSet {
+	kept := sets[:0]
+	for _, set := range sets {
+		if database.Matches(set.Signature, set.Paths) {
+			*suppressed++
+			continue
+		}
+		if set.Nested != nil {
+			var fileSets []reporttypes.FileSet
+			for _, fileSet := range set.Nested.FileDuplicates {
+				if database.Matches(fileSet.Hash, fileSet.Paths) {
+					*suppressed++
+					continue
+				}
+				fileSets = append(fileSets, fileSet)
+			}
+			set.Nested.FileDuplicates = fileSets
+			set.Nested.FolderDuplicates = suppressFolderSets(set.Nested.FolderDuplicates, database, suppressed)
+		}
+		kept = append(kept, set)
+	}
+	return kept
+}
@@ -0,0 +1,192 @@
+package ignore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MinHashLength is the number of hex characters an entry's hash needs at least, as many as
+// reports list for each set, so that a short hash can't match unrelated sets by chance.
+const MinHashLength = 12
+
+var (
+	// ErrEmptyEntry is returned for entries with neither a hash nor a pattern, which would match every set.
+	ErrEmptyEntry = errors.New("an ignore entry needs a hash or a pattern")
+	// ErrShortHash is returned for entry hashes shorter than MinHashLength or with other than lowercase hex digits.
+	ErrShortHash = fmt.Errorf("an ignore hash needs at least %d lowercase hex digits, as listed in reports", MinHashLength)
+)
+
+// Entry describes duplicate sets that are duplicated on purpose, such as vendored libraries or
+// test fixtures, and are left out of reports.
+// A set matches if its hash or folder signature and Hash agree on their common prefix, which
+// is at least MinHashLength digits long, and every path of the set matches Pattern. Empty fields match any set, but not both may be empty.
+type Entry struct {
+	Hash    string    `json:"hash,omitempty"`    // Hash of a file set or signature of a folder set
+	Pattern string    `json:"pattern,omitempty"` // Regular expression matched against each path
+	Note    string    `json:"note,omitempty"`    // Why the set is duplicated, or one of its paths
+	AddedAt time.Time `json:"addedAt"`
+
+	pattern *regexp.Regexp // Compiled Pattern, set by Validate
+}
+
+// Validate checks that an entry matches something, that its hash is long enough, and compiles its pattern.
+func (e *Entry) Validate() error {
+	if e.Hash == "" && e.Pattern == "" {
+		return ErrEmptyEntry
+	}
+	if e.Hash != "" && (len(e.Hash) < MinHashLength || strings.Trim(e.Hash, "0123456789abcdef") != "") {
+		return fmt.Errorf("invalid hash %q: %w", e.Hash, ErrShortHash)
+	}
+	e.pattern = nil
+	if e.Pattern != "" {
+		pattern, err := regexp.Compile(e.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", e.Pattern, err)
+		}
+		e.pattern = pattern
+	}
+	return nil
+}
+
+// Matches reports whether a set with the given hash or signature and paths matches the entry.
+// The entry must have been validated.
+func (e *Entry) Matches(hash string, paths []string) bool {
+	if e.Hash != "" {
+		// Entries may hold a hash as listed in reports or a full one, and either may be the longer.
+		n := min(len(hash), len(e.Hash))
+		if n < MinHashLength || hash[:n] != e.Hash[:n] {
+			return false
+		}
+	}
+	if e.pattern != nil {
+		if len(paths) == 0 {
+			return false
+		}
+		for _, path := range paths {
+			if !e.pattern.MatchString(filepath.ToSlash(path)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Database is the list of ignore entries persisted across scans.
+type Database struct {
+	Entries []Entry `json:"entries"`
+}
+
+// DefaultPath returns the location of the ignore database in the user's configuration directory,
+// e.g. ~/.config/fast-dupe-finder/ignore.json on Linux.
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "fast-dupe-finder", "ignore.json"), nil
+}
+
+// Load reads the database at Path. A missing file is an empty database.
+func Load(Path string) (*Database, error) {
+	database := &Database{}
+	data, err := os.ReadFile(Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return database, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, database); err != nil {
+		return nil, fmt.Errorf("reading ignore database %s: %w", Path, err)
+	}
+	for i := range database.Entries {
+		if err := database.Entries[i].Validate(); err != nil {
+			return nil, fmt.Errorf("reading ignore database %s: entry %d: %w", Path, i+1, err)
+		}
+	}
+	return database, nil
+}
+
+// Save writes the database to Path, creating its folder if needed. The file is replaced
+// atomically, so an interrupted save never loses the previous entries.
+func (d *Database) Save(Path string) error {
+	if err := os.MkdirAll(filepath.Dir(Path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	temp := Path + ".tmp"
+	if err := os.WriteFile(temp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(temp, Path)
+}
+
+// Add validates an entry and appends it, stamping AddedAt if it is zero. It returns false
+// without adding anything if an entry with the same hash and pattern already exists.
+func (d *Database) Add(Entry Entry) (bool, error) {
+	if err := Entry.Validate(); err != nil {
+		return false, err
+	}
+	for _, existing := range d.Entries {
+		if existing.Hash == Entry.Hash && existing.Pattern == Entry.Pattern {
+			return false, nil
+		}
+	}
+	if Entry.AddedAt.IsZero() {
+		Entry.AddedAt = time.Now()
+	}
+	d.Entries = append(d.Entries, Entry)
+	return true, nil
+}
+
+// Remove deletes the entries whose hash or pattern equals one of Values, or that are numbered
+// like one of them as listed (starting at 1, before any removal). It returns the values that
+// matched no entry.
+func (d *Database) Remove(Values []string) []string {
+	matched := make(map[string]bool)
+	kept := make([]Entry, 0, len(d.Entries))
+	for i, entry := range d.Entries {
+		removed := false
+		for _, value := range Values {
+			if entry.Hash == value || entry.Pattern == value || strconv.Itoa(i+1) == value {
+				matched[value] = true
+				removed = true
+			}
+		}
+		if !removed {
+			kept = append(kept, entry)
+		}
+	}
+	d.Entries = kept
+
+	var unmatched []string
+	for _, value := range Values {
+		if !matched[value] {
+			unmatched = append(unmatched, value)
+		}
+	}
+	return unmatched
+}
+
+// Matches reports whether any entry matches a set with the given hash or signature and paths.
+func (d *Database) Matches(Hash string, Paths []string) bool {
+	if d == nil {
+		return false
+	}
+	for i := range d.Entries {
+		if d.Entries[i].Matches(Hash, Paths) {
+			return true
+		}
+	}
+	return false
+}
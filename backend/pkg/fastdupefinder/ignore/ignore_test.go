@@ -0,0 +1,43 @@
+package ignore
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEntryMatches(t *testing.T) {
+	paths := []string{"/src/vendor/lib.go", "/old/vendor/lib.go"}
+	tests := []struct {
+		name    string
+		entry   Entry
+		hash    string
+		wantErr error
+		want    bool
+	}{
+		{name: "exact hash", entry: Entry{Hash: "3f2a9c1b77d0"}, hash: "3f2a9c1b77d0", want: true},
+		{name: "hash prefix", entry: Entry{Hash: "3f2a9c1b77d0"}, hash: "3f2a9c1b77d0e5a1", want: true},
+		{name: "other hash", entry: Entry{Hash: "3f2a9c1b77d0"}, hash: "3f2a9c1b77d1", want: false},
+		{name: "longer than the set's hash", entry: Entry{Hash: "3f2a9c1b77d0e5a1"}, hash: "3f2a9c1b77d0", want: true},
+		{name: "longer and different", entry: Entry{Hash: "3f2a9c1b77d0e5a1"}, hash: "3f2a9c1b77d0e5a2", want: false},
+		{name: "set's hash too short", entry: Entry{Hash: "3f2a9c1b77d0"}, hash: "3f2a9c", want: false},
+		{name: "pattern", entry: Entry{Pattern: "/vendor/"}, hash: "3f2a9c1b77d0", want: true},
+		{name: "pattern not matching every path", entry: Entry{Pattern: "^/src/"}, hash: "3f2a9c1b77d0", want: false},
+		{name: "short hash", entry: Entry{Hash: "3f2a"}, wantErr: ErrShortHash},
+		{name: "not hex", entry: Entry{Hash: "3F2A9C1B77D0"}, wantErr: ErrShortHash},
+		{name: "empty", entry: Entry{}, wantErr: ErrEmptyEntry},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.entry.Validate()
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("Validate returned %v, want %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := test.entry.Matches(test.hash, paths); got != test.want {
+				t.Errorf("Matches(%q) = %t, want %t", test.hash, got, test.want)
+			}
+		})
+	}
+}
@@ -5,9 +5,13 @@ import (
 	"fmt"
 
 	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/actions"
 	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/helpers"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/ignore"
 	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/logger"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/selection"
 	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/status"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types"
 )
 
 // DuplicateFinderResult represents the complete result of a duplicate finding operation
@@ -24,6 +28,49 @@ type StatusCallback func(statusJSON string)
 var globalStatusCallback StatusCallback
 var lastReport string // Cache the last report JSON
 
+// lastResults keeps the raw results of the last successful scan,
+// so the report can be regenerated with different options.
+var lastResults *types.ScanResults
+
+// keepPolicy decides which copy of each set the reports mark as kept and the actions keep.
+var keepPolicy selection.Policy
+
+// ignoreDatabase lists the sets left out of the reports, loaded from ignore.DefaultPath on first use.
+var ignoreDatabase *ignore.Database
+
+// reportOptions returns the options of every report: the keep policy and the ignore database.
+func reportOptions(includeNested bool) helpers.ReportOptions {
+	if ignoreDatabase == nil {
+		database, err := loadIgnoreDatabase()
+		if err != nil {
+			logger.Error("Failed to load the ignore database: "+err.Error(), "Library")
+			database = &ignore.Database{}
+		}
+		ignoreDatabase = database
+	}
+	return helpers.ReportOptions{IncludeNested: includeNested, KeepPolicy: keepPolicy, Ignore: ignoreDatabase}
+}
+
+// loadIgnoreDatabase reads the ignore database from its default location.
+func loadIgnoreDatabase() (*ignore.Database, error) {
+	path, err := ignore.DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	return ignore.Load(path)
+}
+
+// refreshLastReport regenerates the cached report after the options of the reports changed.
+func refreshLastReport() {
+	if lastResults == nil {
+		return
+	}
+	report := helpers.GenerateReportFromResults(lastResults, reportOptions(false))
+	if reportJSON, err := json.Marshal(report); err == nil {
+		lastReport = string(reportJSON)
+	}
+}
+
 // SetStatusCallback sets the global status callback function
 // This function will be called by the C binding layer
 func SetStatusCallback(callback StatusCallback) {
@@ -66,18 +113,20 @@ func RunDuplicateFinderWithFullConfig(rootDir string, config fastdupefinder.Phas
 
 	// Clear the cached report from previous scan
 	lastReport = ""
+	lastResults = nil
+	ignoreDatabase = nil // Pick up changes made to the ignore database since the last scan
 
 	result := DuplicateFinderResult{}
 
 	// Run the duplicate finder
-	filteredFileDuplicates, filteredFolderDuplicates, allFileDuplicates, allFolderDuplicates, err := fastdupefinder.RunFinderWithConfig(rootDir, config)
+	results, err := fastdupefinder.RunFinderWithResults(rootDir, config)
 	if err != nil {
 		result.Success = false
 		result.Error = err.Error()
 		logger.Error("Duplicate finder failed: "+err.Error(), "Library")
 	} else {
 		// Generate the report
-		report := helpers.GenerateReport(filteredFileDuplicates, filteredFolderDuplicates, allFileDuplicates, allFolderDuplicates)
+		report := helpers.GenerateReportFromResults(results, reportOptions(false))
 		reportJSON, err := json.Marshal(report)
 		if err != nil {
 			result.Success = false
@@ -87,6 +136,7 @@ func RunDuplicateFinderWithFullConfig(rootDir string, config fastdupefinder.Phas
 			result.Success = true
 			result.Report = string(reportJSON)
 			lastReport = string(reportJSON) // Cache the report
+			lastResults = results
 			logger.Info("Duplicate finder completed successfully", "Library")
 		}
 	}
@@ -102,6 +152,21 @@ func RunDuplicateFinderWithFullConfig(rootDir string, config fastdupefinder.Phas
 	return string(resultJSON)
 }
 
+// RunDuplicateFinderWithJSONConfig runs the duplicate finder with a configuration given as JSON
+// Fields use the JSON names of fastdupefinder.Phase1Config; missing fields keep their defaults
+func RunDuplicateFinderWithJSONConfig(rootDir string, configJSON string) string {
+	config := fastdupefinder.DefaultConfig()
+	if configJSON != "" {
+		if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+			logger.Error("Failed to parse config JSON: "+err.Error(), "Library")
+			result := DuplicateFinderResult{Success: false, Error: "Invalid config JSON: " + err.Error()}
+			resultJSON, _ := json.Marshal(result)
+			return string(resultJSON)
+		}
+	}
+	return RunDuplicateFinderWithFullConfig(rootDir, config)
+}
+
 // GetCurrentStatus returns the current status as JSON string
 // This can be called by Flutter to get the current status
 func GetCurrentStatus() string {
@@ -149,6 +214,501 @@ func GetLastReport() string {
 	return lastReport
 }
 
+// GetLastReportWithNested returns the report from the last successful scan with
+// the nested duplicate tree expanded under each top-level folder set
+func GetLastReportWithNested() string {
+	if lastResults == nil {
+		return `{"error": "No report available"}`
+	}
+
+	options := reportOptions(true)
+	report := helpers.GenerateReportFromResults(lastResults, options)
+	reportJSON, err := json.Marshal(report)
+	if err != nil {
+		logger.Error("Failed to marshal nested report to JSON: "+err.Error(), "Library")
+		return `{"error": "Failed to generate report"}`
+	}
+	return string(reportJSON)
+}
+
+// SetKeepPolicy sets the rules deciding which copy of each duplicate set is kept
+// policyJSON is a JSON array of rules in order of priority, e.g. ["prefer=/photos", "oldest"];
+// an empty string or array clears the policy. Reports then list the kept copy first and mark
+// each path "keep" or "remove", and LinkDuplicates without paths replaces the copies marked for removal.
+// Returns an ActionResult JSON without outcomes
+func SetKeepPolicy(policyJSON string) string {
+	var specs []string
+	if policyJSON != "" {
+		if err := json.Unmarshal([]byte(policyJSON), &specs); err != nil {
+			return marshalActionResult(ActionResult{Success: false, Error: "Invalid policy JSON: " + err.Error()})
+		}
+	}
+	policy, err := selection.ParsePolicy(specs)
+	if err != nil {
+		return marshalActionResult(ActionResult{Success: false, Error: err.Error()})
+	}
+	keepPolicy = policy
+
+	// Keep the cached report in line with the new policy
+	refreshLastReport()
+	logger.Info(fmt.Sprintf("Keep policy set to %d rules", len(policy)), "Library")
+	return marshalActionResult(ActionResult{Success: true})
+}
+
+// IgnoreResult is the result of the functions managing the ignore database
+type IgnoreResult struct {
+	Success bool           `json:"success"`
+	Error   string         `json:"error,omitempty"`
+	Entries []ignore.Entry `json:"entries"` // Entries of the database after the call
+}
+
+// GetIgnoreEntries returns the entries of the ignore database, whose sets are left out of the reports
+func GetIgnoreEntries() string {
+	database, err := loadIgnoreDatabase()
+	if err != nil {
+		return marshalIgnoreResult(IgnoreResult{Success: false, Error: err.Error()})
+	}
+	return marshalIgnoreResult(IgnoreResult{Success: true, Entries: database.Entries})
+}
+
+// AddIgnoreEntries adds entries to the ignore database and regenerates the cached report without their sets
+// entriesJSON is a JSON array of entries with a hash and/or a path pattern, e.g.
+// [{"hash": "3f2a9c1b77d0", "note": "test fixtures"}, {"pattern": "/vendor/"}]
+func AddIgnoreEntries(entriesJSON string) string {
+	var entries []ignore.Entry
+	if err := json.Unmarshal([]byte(entriesJSON), &entries); err != nil {
+		return marshalIgnoreResult(IgnoreResult{Success: false, Error: "Invalid entries JSON: " + err.Error()})
+	}
+	return updateIgnoreDatabase(func(database *ignore.Database) error {
+		for _, entry := range entries {
+			if _, err := database.Add(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RemoveIgnoreEntries removes entries from the ignore database and regenerates the cached report
+// valuesJSON is a JSON array of hashes, patterns or entry numbers (starting at 1) to remove
+func RemoveIgnoreEntries(valuesJSON string) string {
+	var values []string
+	if err := json.Unmarshal([]byte(valuesJSON), &values); err != nil {
+		return marshalIgnoreResult(IgnoreResult{Success: false, Error: "Invalid values JSON: " + err.Error()})
+	}
+	return updateIgnoreDatabase(func(database *ignore.Database) error {
+		if unmatched := database.Remove(values); len(unmatched) > 0 {
+			return fmt.Errorf("no entry matches %s", unmatched[0])
+		}
+		return nil
+	})
+}
+
+// updateIgnoreDatabase loads the ignore database, applies update and saves it unless update fails
+func updateIgnoreDatabase(update func(database *ignore.Database) error) string {
+	path, err := ignore.DefaultPath()
+	if err != nil {
+		return marshalIgnoreResult(IgnoreResult{Success: false, Error: err.Error()})
+	}
+	database, err := ignore.Load(path)
+	if err == nil {
+		err = update(database)
+	}
+	if err == nil {
+		err = database.Save(path)
+	}
+	if err != nil {
+		return marshalIgnoreResult(IgnoreResult{Success: false, Error: err.Error()})
+	}
+
+	ignoreDatabase = database
+	refreshLastReport()
+	logger.Info(fmt.Sprintf("Ignore database updated, %d entries", len(database.Entries)), "Library")
+	return marshalIgnoreResult(IgnoreResult{Success: true, Entries: database.Entries})
+}
+
+// marshalIgnoreResult converts an IgnoreResult to JSON
+func marshalIgnoreResult(result IgnoreResult) string {
+	if result.Entries == nil {
+		result.Entries = []ignore.Entry{}
+	}
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		logger.Error("Failed to marshal ignore result to JSON: "+err.Error(), "Library")
+		return `{"success": false, "error": "Failed to serialize result"}`
+	}
+	return string(resultJSON)
+}
+
+// MarkResult is the result of MarkDuplicates
+type MarkResult struct {
+	Success     bool     `json:"success"`
+	Error       string   `json:"error,omitempty"`
+	Paths       []string `json:"paths"`                 // Copies selected by the expression
+	SkippedSets []string `json:"skippedSets,omitempty"` // Hashes of sets in which every copy was selected
+}
+
+// MarkDuplicates evaluates a selection expression for every copy of the file sets of the last scan,
+// e.g. `path =~ "/Downloads/" && mtime > keeper.mtime`, and returns the selected copies, which can be
+// passed to LinkDuplicates. Sets in which every copy is selected are skipped
+// rootsJSON optionally maps labels to scanned folders for the root variable, e.g. {"photos": "/mnt/photos"}
+func MarkDuplicates(expression string, rootsJSON string) string {
+	result := MarkResult{Success: true, Paths: []string{}}
+	compiled, compileErr := selection.CompileExpression(expression)
+	var roots map[string]string
+	var rootsErr error
+	if rootsJSON != "" {
+		rootsErr = json.Unmarshal([]byte(rootsJSON), &roots)
+	}
+
+	switch {
+	case lastResults == nil:
+		result = MarkResult{Success: false, Error: "No report available"}
+	case compileErr != nil:
+		result = MarkResult{Success: false, Error: "Invalid expression: " + compileErr.Error()}
+	case rootsErr != nil:
+		result = MarkResult{Success: false, Error: "Invalid roots JSON: " + rootsErr.Error()}
+	default:
+		var labelled []selection.Root
+		for label, dir := range roots {
+			labelled = append(labelled, selection.Root{Label: label, Dir: dir})
+		}
+		report := helpers.GenerateReportFromResults(lastResults, reportOptions(true))
+		skipped := helpers.MarkWithExpression(&report, compiled, labelled)
+		seen := make(map[string]bool)
+		for _, hash := range skipped {
+			if !seen[hash] {
+				seen[hash] = true
+				result.SkippedSets = append(result.SkippedSets, hash)
+			}
+		}
+		for _, path := range actions.PathsToRemove(report) {
+			if !seen[path] {
+				seen[path] = true
+				result.Paths = append(result.Paths, path)
+			}
+		}
+		logger.Info(fmt.Sprintf("Expression selected %d duplicate files", len(result.Paths)), "Library")
+	}
+
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		logger.Error("Failed to marshal mark result to JSON: "+err.Error(), "Library")
+		return `{"success": false, "error": "Failed to serialize result"}`
+	}
+	return string(resultJSON)
+}
+
+// ActionResult is the result of an action on the files of the last scan
+type ActionResult struct {
+	Success  bool            `json:"success"`
+	Error    string          `json:"error,omitempty"`
+	Outcomes []ActionOutcome `json:"outcomes,omitempty"`
+	Manifest string          `json:"manifest,omitempty"` // Quarantine manifest to pass to RestoreQuarantine
+}
+
+// ActionOutcome reports what happened to a single path
+type ActionOutcome struct {
+	Path        string `json:"path"`
+	Destination string `json:"destination,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// LinkDuplicates replaces duplicate files of the last scan with links to a kept copy
+// pathsJSON is a JSON array of the copies to replace; if empty, the copies marked for removal are replaced
+// Sets whose copies changed since the scan are left alone, see RevalidateDuplicates
+// kind is "hard", "symlink" or "relative" (a relative symlink)
+func LinkDuplicates(pathsJSON string, kind string, dryRun bool) string {
+	if lastResults == nil {
+		return marshalActionResult(ActionResult{Success: false, Error: "No report available"})
+	}
+
+	options := actions.LinkOptions{DryRun: dryRun}
+	switch kind {
+	case "hard", "":
+		options.Kind = actions.LinkHard
+	case "symlink":
+		options.Kind = actions.LinkSymbolic
+	case "relative":
+		options.Kind = actions.LinkRelativeSymbolic
+	default:
+		return marshalActionResult(ActionResult{Success: false, Error: "Unknown link kind: " + kind})
+	}
+
+	var paths []string
+	if pathsJSON != "" {
+		if err := json.Unmarshal([]byte(pathsJSON), &paths); err != nil {
+			return marshalActionResult(ActionResult{Success: false, Error: "Invalid paths JSON: " + err.Error()})
+		}
+	}
+
+	report := helpers.GenerateReportFromResults(lastResults, reportOptions(true))
+	if len(paths) == 0 {
+		paths = actions.PathsToRemove(report)
+	}
+
+	if err := enableAuditLog(dryRun); err != nil {
+		return marshalActionResult(ActionResult{Success: false, Error: err.Error()})
+	}
+	logger.Info(fmt.Sprintf("Linking %d duplicate files (kind: %s, dry run: %t)", len(paths), kind, dryRun), "Library")
+	return marshalActionResult(newActionResult(actions.LinkFromReport(report, paths, options)))
+}
+
+// DeleteDuplicates removes duplicate files and folders of the last scan, keeping at least one copy
+// of every set on disk; sets whose copies changed since the scan are left alone
+// pathsJSON is a JSON array of the copies to remove; if empty, the copies the report marks for removal
+// are removed and sets without marks are left alone
+// trash moves the copies to the freedesktop.org trash (Linux, BSD) instead of deleting them permanently
+func DeleteDuplicates(pathsJSON string, trash bool, dryRun bool) string {
+	if lastResults == nil {
+		return marshalActionResult(ActionResult{Success: false, Error: "No report available"})
+	}
+
+	var paths []string
+	if pathsJSON != "" {
+		if err := json.Unmarshal([]byte(pathsJSON), &paths); err != nil {
+			return marshalActionResult(ActionResult{Success: false, Error: "Invalid paths JSON: " + err.Error()})
+		}
+	}
+
+	report := helpers.GenerateReportFromResults(lastResults, reportOptions(true))
+	if len(paths) == 0 {
+		paths = actions.MarkedPaths(report)
+	}
+	options := actions.Options{Method: actions.MethodDelete, DryRun: dryRun}
+	if trash {
+		options.Method = actions.MethodTrash
+	}
+
+	if err := enableAuditLog(dryRun); err != nil {
+		return marshalActionResult(ActionResult{Success: false, Error: err.Error()})
+	}
+	logger.Info(fmt.Sprintf("Removing %d duplicates (trash: %t, dry run: %t)", len(paths), trash, dryRun), "Library")
+	return marshalActionResult(newActionResult(actions.RemoveFromReport(report, paths, options)))
+}
+
+// QuarantineDuplicates moves duplicate files of the last scan into directory instead of deleting them,
+// keeping at least one copy of every set; sets whose copies changed since the scan are left alone
+// pathsJSON is a JSON array of the copies to move; if empty, the copies marked for removal are moved
+// The moves are recorded in the manifest returned in the result, which RestoreQuarantine undoes
+func QuarantineDuplicates(pathsJSON string, directory string, dryRun bool) string {
+	if lastResults == nil {
+		return marshalActionResult(ActionResult{Success: false, Error: "No report available"})
+	}
+	if directory == "" {
+		return marshalActionResult(ActionResult{Success: false, Error: "No quarantine directory given"})
+	}
+
+	var paths []string
+	if pathsJSON != "" {
+		if err := json.Unmarshal([]byte(pathsJSON), &paths); err != nil {
+			return marshalActionResult(ActionResult{Success: false, Error: "Invalid paths JSON: " + err.Error()})
+		}
+	}
+
+	report := helpers.GenerateReportFromResults(lastResults, reportOptions(true))
+	if len(paths) == 0 {
+		paths = actions.PathsToRemove(report)
+	}
+
+	if err := enableAuditLog(dryRun); err != nil {
+		return marshalActionResult(ActionResult{Success: false, Error: err.Error()})
+	}
+	logger.Info(fmt.Sprintf("Quarantining %d duplicate files in %s (dry run: %t)", len(paths), directory, dryRun), "Library")
+	manifestPath, outcomes, err := actions.QuarantineFromReport(report, paths, actions.QuarantineOptions{Directory: directory, DryRun: dryRun})
+	result := newActionResult(outcomes)
+	result.Manifest = manifestPath
+	if err != nil {
+		logger.Error("Quarantine stopped: "+err.Error(), "Library")
+		result.Success = false
+		result.Error = err.Error()
+	}
+	return marshalActionResult(result)
+}
+
+// RestoreQuarantine moves the files of a quarantine manifest back to their original paths
+// Files whose original path is occupied again or whose content changed in quarantine are left there
+func RestoreQuarantine(manifestPath string, dryRun bool) string {
+	if err := enableAuditLog(dryRun); err != nil {
+		return marshalActionResult(ActionResult{Success: false, Error: err.Error()})
+	}
+	logger.Info(fmt.Sprintf("Restoring quarantined files of %s (dry run: %t)", manifestPath, dryRun), "Library")
+	outcomes, err := actions.Restore(manifestPath, dryRun)
+	if err != nil {
+		logger.Error("Failed to read the quarantine manifest: "+err.Error(), "Library")
+		return marshalActionResult(ActionResult{Success: false, Error: err.Error()})
+	}
+	return marshalActionResult(newActionResult(outcomes))
+}
+
+// RevalidateResult is the result of RevalidateDuplicates
+type RevalidateResult struct {
+	Success bool             `json:"success"` // True if every set can be acted on
+	Error   string           `json:"error,omitempty"`
+	Sets    []SetCheckResult `json:"sets,omitempty"`
+}
+
+// SetCheckResult reports whether a single duplicate set still matches the disk
+type SetCheckResult struct {
+	Hash   string   `json:"hash,omitempty"`   // Hash or folder signature of the set, empty for paths in no set
+	Keeper string   `json:"keeper,omitempty"` // Copy that is kept, checked along with Paths
+	Paths  []string `json:"paths"`            // Copies that would be removed or replaced
+	Error  string   `json:"error,omitempty"`  // Why the set must not be acted on
+}
+
+// RevalidateDuplicates re-checks the duplicate sets of the last scan holding the given paths right
+// before they are removed or replaced: each path and its kept copy are stat'ed and hashed again, and a
+// set fails if a size, modification time or content changed since the scan or no kept copy is left
+// pathsJSON is a JSON array of the paths about to be acted on
+func RevalidateDuplicates(pathsJSON string) string {
+	var result RevalidateResult
+	var paths []string
+	if lastResults == nil {
+		result.Error = "No report available"
+	} else if err := json.Unmarshal([]byte(pathsJSON), &paths); err != nil {
+		result.Error = "Invalid paths JSON: " + err.Error()
+	} else {
+		report := helpers.GenerateReportFromResults(lastResults, reportOptions(true))
+		result.Success = true
+		for _, check := range actions.Revalidate(report, paths) {
+			converted := SetCheckResult{Hash: check.Hash, Keeper: check.Keeper, Paths: check.Paths}
+			if check.Err != nil {
+				converted.Error = check.Err.Error()
+				result.Success = false
+			}
+			result.Sets = append(result.Sets, converted)
+		}
+		logger.Info(fmt.Sprintf("Revalidated %d duplicate sets (all valid: %t)", len(result.Sets), result.Success), "Library")
+	}
+
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		logger.Error("Failed to marshal revalidation result to JSON: "+err.Error(), "Library")
+		return `{"success": false, "error": "Failed to serialize result"}`
+	}
+	return string(resultJSON)
+}
+
+// DedupeResult is the result of DedupeDuplicates
+type DedupeResult struct {
+	Success        bool              `json:"success"`
+	Error          string            `json:"error,omitempty"`
+	Sets           []DedupeSetResult `json:"sets,omitempty"`
+	ReclaimedBytes int64             `json:"reclaimedBytes"`
+}
+
+// DedupeSetResult reports what happened to a single duplicate set
+type DedupeSetResult struct {
+	Source         string          `json:"source"`
+	Outcomes       []ActionOutcome `json:"outcomes"`
+	ReclaimedBytes int64           `json:"reclaimedBytes"`
+}
+
+// DedupeDuplicates makes the copies of every file set of the last scan share their extents
+// on copy-on-write filesystems (btrfs, XFS); copies on other filesystems report an error
+func DedupeDuplicates(dryRun bool) string {
+	result := DedupeResult{Success: true}
+	if lastResults == nil {
+		result = DedupeResult{Success: false, Error: "No report available"}
+	} else if err := enableAuditLog(dryRun); err != nil {
+		result = DedupeResult{Success: false, Error: err.Error()}
+	} else {
+		report := helpers.GenerateReportFromResults(lastResults, reportOptions(true))
+		for _, setResult := range actions.Dedupe(actions.FileSetsFromReport(report), actions.DedupeOptions{DryRun: dryRun}) {
+			converted := newActionResult(setResult.Outcomes)
+			result.Success = result.Success && converted.Success
+			result.ReclaimedBytes += setResult.ReclaimedBytes
+			result.Sets = append(result.Sets, DedupeSetResult{
+				Source:         setResult.Source,
+				Outcomes:       converted.Outcomes,
+				ReclaimedBytes: setResult.ReclaimedBytes,
+			})
+		}
+		logger.Info(fmt.Sprintf("Deduplicated %d sets, %d bytes reclaimed (dry run: %t)", len(result.Sets), result.ReclaimedBytes, dryRun), "Library")
+	}
+
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		logger.Error("Failed to marshal dedupe result to JSON: "+err.Error(), "Library")
+		return `{"success": false, "error": "Failed to serialize result"}`
+	}
+	return string(resultJSON)
+}
+
+// enableAuditLog opens the audit log named by FDF_AUDIT_LOG, or the default one, in which the actions
+// record their operations unless dryRun is set; an action stops with this error before touching any file
+func enableAuditLog(dryRun bool) error {
+	if dryRun {
+		return nil
+	}
+	log, err := actions.OpenAuditLogFromEnv()
+	if err != nil {
+		logger.Error("Failed to open the audit log: "+err.Error(), "Library")
+		return fmt.Errorf("can't write the audit log: %w", err)
+	}
+	actions.SetAuditLog(log)
+	return nil
+}
+
+// AuditResult is the result of VerifyAuditLog
+type AuditResult struct {
+	Success   bool   `json:"success"`
+	Error     string `json:"error,omitempty"`
+	Entries   int    `json:"entries"`             // Entries verified, up to the first that failed
+	LastChain string `json:"lastChain,omitempty"` // Chain value of the last verified entry
+	Warning   string `json:"warning,omitempty"`   // Set if the log has no key and could have been rewritten
+}
+
+// VerifyAuditLog checks the hash chain of the audit log in which the actions record their operations
+// expected, if not empty, is a chain value noted earlier that must still be in the log, which detects
+// entries removed from its end
+func VerifyAuditLog(expected string) string {
+	result := AuditResult{}
+	path, err := actions.AuditLogPathFromEnv()
+	if err == nil {
+		result.Entries, result.LastChain, err = actions.VerifyAuditLog(path, actions.AuditKeyFromEnv(), expected)
+	}
+	if err != nil {
+		result.Error = err.Error()
+	} else {
+		result.Success = true
+		if len(actions.AuditKeyFromEnv()) == 0 {
+			result.Warning = actions.UnkeyedAuditWarning
+		}
+	}
+
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		logger.Error("Failed to marshal audit result to JSON: "+err.Error(), "Library")
+		return `{"success": false, "error": "Failed to serialize result"}`
+	}
+	return string(resultJSON)
+}
+
+// newActionResult converts the outcomes of an action; it succeeds if every path was handled
+func newActionResult(outcomes []actions.Outcome) ActionResult {
+	result := ActionResult{Success: true, Outcomes: make([]ActionOutcome, 0, len(outcomes))}
+	for _, outcome := range outcomes {
+		converted := ActionOutcome{Path: outcome.Path, Destination: outcome.Destination}
+		if outcome.Err != nil {
+			converted.Error = outcome.Err.Error()
+			result.Success = false
+		}
+		result.Outcomes = append(result.Outcomes, converted)
+	}
+	return result
+}
+
+// marshalActionResult converts an action result to JSON
+func marshalActionResult(result ActionResult) string {
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		logger.Error("Failed to marshal action result to JSON: "+err.Error(), "Library")
+		return `{"success": false, "error": "Failed to serialize result"}`
+	}
+	return string(resultJSON)
+}
+
 // GetVersion returns the version information
 func GetVersion() string {
 	version := map[string]string{
@@ -2,17 +2,19 @@ package fastdupefinder
 
 import (
 	"fmt"
+	"io/fs"
 	"log"
-	"os"
 	"path/filepath"
 	"sync"
 
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/archives"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/s3"
 	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/status"
 	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types"
 )
 
 // Phase1GroupBySize walks the filesystem and groups files by their size.
-// It uses a pool of workers to perform `os.Stat` calls concurrently.
+// It uses a pool of workers to stat files concurrently.
 // This is the legacy function that maintains backward compatibility.
 func Phase1GroupBySize(RootDir string, NumWorkers int) map[int64][]string {
 	config := DefaultConfig().WithCpuCores(NumWorkers)
@@ -20,12 +22,22 @@ func Phase1GroupBySize(RootDir string, NumWorkers int) map[int64][]string {
 }
 
 // Phase1GroupBySizeWithConfig walks the filesystem and groups files by their size and optionally filename.
-// It uses a pool of workers to perform `os.Stat` calls concurrently.
-// When config.FilterByFilename is true, files are grouped by both size and filename.
-// Phase1GroupBySizeWithConfig walks the filesystem and groups files by their size and optionally filename.
-// It uses a pool of workers to perform `os.Stat` calls concurrently.
+// It uses a pool of workers to stat files concurrently.
 // When config.FilterByFilename is true, files are grouped by both size and filename.
 func Phase1GroupBySizeWithConfig(RootDir string, config Phase1Config) map[int64][]string {
+	filesBySize, _ := Phase1ScanWithConfig(RootDir, config)
+	return filesBySize
+}
+
+// Phase1ScanWithConfig groups files like Phase1GroupBySizeWithConfig and also records
+// an in-memory snapshot of the walked directory tree (children and file sizes).
+// Phase 4 builds folder signatures from this snapshot instead of reading directories again.
+func Phase1ScanWithConfig(RootDir string, config Phase1Config) (map[int64][]string, *types.DirTree) {
+	return phase1Scan(localSource(RootDir), config)
+}
+
+// phase1Scan walks a scan source, groups its files like Phase1ScanWithConfig and records the tree.
+func phase1Scan(source scanSource, config Phase1Config) (map[int64][]string, *types.DirTree) {
 	// Determine number of workers
 	var numWorkers int
 	if config.CpuCores <= 0 {
@@ -38,16 +50,44 @@ func Phase1GroupBySizeWithConfig(RootDir string, config Phase1Config) map[int64]
 	infoChan := make(chan types.FileInfo, numWorkers)
 	var processedFiles int64
 
+	// The tree is only written by the walker goroutine below and is complete
+	// once infoChan has been drained.
+	tree := types.NewDirTree()
+
 	// Start a single goroutine to walk the filesystem.
 	go func() {
 		defer close(pathsChan)
-		err := filepath.Walk(RootDir, func(path string, info os.FileInfo, err error) error {
+		err := fs.WalkDir(source.source, ".", func(name string, entry fs.DirEntry, err error) error {
+			path := source.path(name)
 			if err != nil {
 				log.Printf("Error accessing path %s: %v\n", path, err)
+				// An unreadable entry means its directory can't be fully accounted for.
+				// Unreadable directories are still linked to their parent first, so the
+				// parent's summary sees them and is incomplete as well.
+				if entry != nil && entry.IsDir() {
+					tree.AddDir(path)
+					tree.MarkIncomplete(path)
+				} else {
+					tree.MarkIncomplete(filepath.Dir(path))
+				}
 				return nil // Continue walking
 			}
-			if info.Mode().IsRegular() && info.Size() > 0 {
-				pathsChan <- path
+			switch {
+			case entry.IsDir():
+				tree.AddDir(path)
+			case entry.Type().IsRegular():
+				info, err := entry.Info()
+				if err != nil {
+					log.Printf("Error accessing path %s: %v\n", path, err)
+					tree.MarkIncomplete(filepath.Dir(path))
+					return nil
+				}
+				tree.AddFile(path, info.Size())
+				if info.Size() > 0 {
+					pathsChan <- name
+				}
+			default:
+				tree.MarkIncomplete(filepath.Dir(path))
 			}
 			return nil
 		})
@@ -62,13 +102,27 @@ func Phase1GroupBySizeWithConfig(RootDir string, config Phase1Config) map[int64]
 		statWg.Add(1)
 		go func() {
 			defer statWg.Done()
-			for path := range pathsChan {
-				info, err := os.Stat(path)
+			for name := range pathsChan {
+				path := source.path(name)
+				info, err := source.source.StatFile(name)
 				if err != nil {
 					log.Printf("Error stating file %s: %v\n", path, err)
 					continue
 				}
-				infoChan <- types.FileInfo{Path: path, Size: info.Size()}
+				infoChan <- types.FileInfo{Path: path, Size: info.Size}
+
+				// Expand archive members into virtual files when requested.
+				if config.ExpandArchives && archives.IsArchive(path) {
+					members, err := archives.List(path)
+					if err != nil {
+						log.Printf("Error listing archive %s: %v\n", path, err)
+					}
+					for _, member := range members {
+						if member.Size > 0 {
+							infoChan <- types.FileInfo{Path: archives.VirtualPath(path, member.Name), Size: member.Size}
+						}
+					}
+				}
 
 				// Simple progress update every 1000 files
 				if processedFiles++; processedFiles%1000 == 0 {
@@ -88,6 +142,22 @@ func Phase1GroupBySizeWithConfig(RootDir string, config Phase1Config) map[int64]
 		}()
 	}
 
+	// List the objects of S3 sources; they are grouped together with local files.
+	statWg.Add(1)
+	go func() {
+		defer statWg.Done()
+		for _, source := range config.S3Sources {
+			objects, err := listS3Source(source)
+			if err != nil {
+				log.Printf("Error listing S3 source %s: %v\n", source, err)
+				continue
+			}
+			for _, object := range objects {
+				infoChan <- object
+			}
+		}
+	}()
+
 	// Start a goroutine to close the infoChan once all stat workers are done.
 	go func() {
 		statWg.Wait()
@@ -132,5 +202,24 @@ func Phase1GroupBySizeWithConfig(RootDir string, config Phase1Config) map[int64]
 		}
 	}
 
-	return filesBySize
+	return filesBySize, tree
+}
+
+// listS3Source lists the non-empty objects below an "s3://bucket/prefix" path.
+func listS3Source(Source string) ([]types.FileInfo, error) {
+	bucket, prefix, ok := s3.SplitObjectPath(Source)
+	if !ok {
+		return nil, fmt.Errorf("not an s3://bucket/prefix path")
+	}
+	objects, err := s3.DefaultClient().ListObjects(bucket, prefix)
+	if err != nil {
+		return nil, err
+	}
+	var infos []types.FileInfo
+	for _, object := range objects {
+		if object.Size > 0 {
+			infos = append(infos, types.FileInfo{Path: s3.ObjectPath(bucket, object.Key), Size: object.Size})
+		}
+	}
+	return infos, nil
 }
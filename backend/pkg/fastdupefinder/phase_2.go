@@ -3,11 +3,10 @@ package fastdupefinder
 import (
 	"fmt"
 	"log"
-	"os"
 	"sync"
 
-	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/helpers"
 	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/status"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types"
 )
 
 // Phase2FilterByPartialHash takes the size-grouped map and filters it further
@@ -16,6 +15,11 @@ import (
 // - Files < 10MB: hash first and last 4KB
 // - Files >= 10MB: hash first, middle, and last 4KB
 func Phase2FilterByPartialHash(FilesBySize map[int64][]string, NumWorkers int) map[string][]string {
+	return phase2FilterByPartialHash(FilesBySize, NumWorkers, localSource(""))
+}
+
+// phase2FilterByPartialHash filters like Phase2FilterByPartialHash, reading files from a scan source.
+func phase2FilterByPartialHash(FilesBySize map[int64][]string, NumWorkers int, source scanSource) map[string][]string {
 	// Count total files to process
 	var totalFiles int
 	for _, paths := range FilesBySize {
@@ -27,26 +31,26 @@ func Phase2FilterByPartialHash(FilesBySize map[int64][]string, NumWorkers int) m
 	var processedFiles int
 
 	var wg sync.WaitGroup
-	jobs := make(chan string, NumWorkers)
+	jobs := make(chan types.FileInfo, NumWorkers)
 
 	// Worker goroutines
 	for i := 0; i < NumWorkers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			for path := range jobs {
-				hash, err := helpers.CalculateHash(path, true) // true for partial hash
+			for job := range jobs {
+				hash, err := source.hash(job.Path, true) // true for partial hash
 				if err != nil {
-					log.Printf("Error partial hashing file %s: %v\n", path, err)
+					log.Printf("Error partial hashing file %s: %v\n", job.Path, err)
 					processedFiles++
 					continue
 				}
 
-				info, _ := os.Stat(path) // We know the file exists
-				compositeKey := fmt.Sprintf("%d-%s", info.Size(), hash)
+				// The size comes from Phase 1, which also covers archive members that can't be stat'ed.
+				compositeKey := fmt.Sprintf("%d-%s", job.Size, hash)
 
 				mu.Lock()
-				candidates[compositeKey] = append(candidates[compositeKey], path)
+				candidates[compositeKey] = append(candidates[compositeKey], job.Path)
 				processedFiles++
 
 				// Simple progress update every 500 files
@@ -60,9 +64,9 @@ func Phase2FilterByPartialHash(FilesBySize map[int64][]string, NumWorkers int) m
 	}
 
 	// Feed the jobs channel
-	for _, paths := range FilesBySize {
+	for size, paths := range FilesBySize {
 		for _, path := range paths {
-			jobs <- path
+			jobs <- types.FileInfo{Path: path, Size: size}
 		}
 	}
 	close(jobs)
@@ -2,12 +2,12 @@ package fastdupefinder
 
 import (
 	"log"
-	"os"
 	"strconv"
 	"strings"
 	"sync"
 
-	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/helpers"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/archives"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/s3"
 	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/status"
 	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types"
 )
@@ -15,6 +15,12 @@ import (
 // Phase3FindDuplicatesByFullHash is the final confirmation step. It calculates the full
 // hash for the remaining candidates.
 func Phase3FindDuplicatesByFullHash(Candidates map[string][]string, NumWorkers int) map[string][]string {
+	return phase3FindDuplicatesByFullHash(Candidates, NumWorkers, localSource(""))
+}
+
+// phase3FindDuplicatesByFullHash confirms duplicates like Phase3FindDuplicatesByFullHash,
+// reading files from a scan source.
+func phase3FindDuplicatesByFullHash(Candidates map[string][]string, NumWorkers int, source scanSource) map[string][]string {
 	// Count total files
 	var totalFiles int
 	for _, paths := range Candidates {
@@ -33,19 +39,23 @@ func Phase3FindDuplicatesByFullHash(Candidates map[string][]string, NumWorkers i
 		go func() {
 			defer wg.Done()
 			for job := range jobs {
-				currentInfo, err := os.Stat(job.Path)
-				if err != nil {
-					log.Printf("Error stating file %s before full hash: %v", job.Path, err)
-					processedFiles++
-					continue
-				}
-				if currentInfo.Size() != job.Size {
-					log.Printf("File changed size during scan, skipping: %s", job.Path)
-					processedFiles++
-					continue
+				// Archive members and S3 objects can't be stat'ed; a changed archive fails when the
+				// member is opened and a changed object hashes differently.
+				if !archives.IsVirtualPath(job.Path) && !s3.IsObjectPath(job.Path) {
+					currentSize, err := source.size(job.Path)
+					if err != nil {
+						log.Printf("Error stating file %s before full hash: %v", job.Path, err)
+						processedFiles++
+						continue
+					}
+					if currentSize != job.Size {
+						log.Printf("File changed size during scan, skipping: %s", job.Path)
+						processedFiles++
+						continue
+					}
 				}
 
-				hash, err := helpers.CalculateHash(job.Path, false) // false for full hash
+				hash, err := source.hash(job.Path, false) // false for full hash
 				if err != nil {
 					log.Printf("Error full hashing file %s: %v\n", job.Path, err)
 					processedFiles++
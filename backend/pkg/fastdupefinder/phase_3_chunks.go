@@ -0,0 +1,128 @@
+package fastdupefinder
+
+import (
+	"log"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/helpers"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/status"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types"
+)
+
+// Chunk analysis thresholds.
+const (
+	// minSharedFraction is the share of the smaller file's size two files must have in common to be reported.
+	minSharedFraction = 0.1
+	// maxChunkSharers caps the files paired through a single chunk, e.g. a block of zeroes found
+	// in every disk image, which would otherwise make pairing quadratic. Such chunks still count
+	// towards the estimated savings.
+	maxChunkSharers = 64
+)
+
+// Phase3AnalyseSharedChunks estimates how much of the content of large files is shared at block
+// level, e.g. VM images or database dumps that are mostly but not entirely identical.
+// Files of at least MinFileSize bytes are split into content-defined chunks (FastCDC) and their
+// chunk hashes are indexed. Pairs of files sharing at least a tenth of the smaller file are
+// reported, and the savings of storing every distinct chunk once are estimated.
+// Only one file of each set of byte-identical files is analysed.
+// It returns errScanCancelled if the scan is cancelled, since a partial analysis would
+// understate the savings.
+func Phase3AnalyseSharedChunks(Tree *types.DirTree, FileDuplicates map[string][]string, MinFileSize int64, NumWorkers int) (types.ChunkAnalysis, error) {
+	// Step 1: Collect large files, skipping extra copies of byte-identical files.
+	extraCopies := redundantCopies(FileDuplicates)
+	sizes := make(map[string]int64)
+	var paths []string
+	for dirPath, node := range Tree.Nodes {
+		for name, size := range node.Files {
+			path := filepath.Join(dirPath, name)
+			if size > 0 && size >= MinFileSize && !extraCopies[path] {
+				sizes[path] = size
+				paths = append(paths, path)
+			}
+		}
+	}
+	sort.Strings(paths)
+
+	// Step 2: Chunk every file and keep its distinct chunks.
+	type chunkSet map[uint64]int
+	fileChunks := make(map[string]chunkSet)
+	var analysis types.ChunkAnalysis
+	var mu sync.Mutex
+	var processedFiles int
+	runJobs(paths, NumWorkers, func(path string) {
+		chunks := make(chunkSet)
+		var totalBytes int64
+		err := helpers.ChunkFile(path, func(hash uint64, size int) {
+			chunks[hash] = size
+			totalBytes += int64(size)
+		})
+
+		mu.Lock()
+		defer mu.Unlock()
+		processedFiles++
+		if err != nil {
+			log.Printf("Error chunking file %s: %v\n", path, err)
+			return
+		}
+		fileChunks[path] = chunks
+		analysis.FilesAnalysed++
+		analysis.TotalBytes += totalBytes
+		status.UpdateDetailedStatus("phase3", 60.0, "Chunking large files", len(FileDuplicates), 0, processedFiles, len(paths), "Files")
+	})
+	if IsCancelled() {
+		return types.ChunkAnalysis{}, errScanCancelled
+	}
+
+	// Step 3: Index chunks by hash and sum up the shared bytes of each pair of files.
+	index := make(map[uint64][]int)
+	var analysedPaths []string
+	for _, path := range paths {
+		if chunks, found := fileChunks[path]; found {
+			for hash := range chunks {
+				index[hash] = append(index[hash], len(analysedPaths))
+			}
+			analysedPaths = append(analysedPaths, path)
+		}
+	}
+
+	type pairKey struct{ a, b int }
+	sharedBytes := make(map[pairKey]int64)
+	for hash, files := range index {
+		if IsCancelled() {
+			return types.ChunkAnalysis{}, errScanCancelled
+		}
+		// Every distinct chunk is stored once, however often it occurs.
+		size := int64(fileChunks[analysedPaths[files[0]]][hash])
+		analysis.UniqueBytes += size
+		if len(files) < 2 || len(files) > maxChunkSharers {
+			continue
+		}
+		for x := 0; x < len(files); x++ {
+			for y := x + 1; y < len(files); y++ {
+				sharedBytes[pairKey{files[x], files[y]}] += size
+			}
+		}
+	}
+	// Step 4: Keep pairs sharing a significant part of the smaller file.
+	for pair, shared := range sharedBytes {
+		pathA, pathB := analysedPaths[pair.a], analysedPaths[pair.b]
+		smaller := min(sizes[pathA], sizes[pathB])
+		if float64(shared) >= minSharedFraction*float64(smaller) {
+			analysis.SharedPairs = append(analysis.SharedPairs, types.SharedChunkPair{PathA: pathA, PathB: pathB, SharedBytes: shared})
+		}
+	}
+	sort.Slice(analysis.SharedPairs, func(i, j int) bool {
+		pairI, pairJ := analysis.SharedPairs[i], analysis.SharedPairs[j]
+		if pairI.SharedBytes != pairJ.SharedBytes {
+			return pairI.SharedBytes > pairJ.SharedBytes
+		}
+		if pairI.PathA != pairJ.PathA {
+			return pairI.PathA < pairJ.PathA
+		}
+		return pairI.PathB < pairJ.PathB
+	})
+
+	return analysis, nil
+}
@@ -0,0 +1,139 @@
+package fastdupefinder
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"sync"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/helpers"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/status"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types"
+)
+
+// Phase3FindCompressedEquivalents links gzip, bzip2 and zlib files to files holding the same
+// decompressed bytes, e.g. "logs/app.log" and "archive/app.log.gz".
+// Every compressed file in the Phase 1 snapshot is decompressed once to get its content size
+// and hash; only plain files of a matching size are then hashed, reusing Phase 3 hashes.
+// Keys of the returned map are "size-hash" of the decompressed content, like Phase 2 keys.
+// Every returned group holds at least one compressed file. Files with byte-identical copies
+// are already reported in FileDuplicates, so only the first path of each such set joins a group
+// and its copies aren't counted as wasted space twice.
+func Phase3FindCompressedEquivalents(Tree *types.DirTree, FileDuplicates map[string][]string, NumWorkers int) map[string][]string {
+	knownHashes := make(map[string]string)
+	for hash, paths := range FileDuplicates {
+		for _, path := range paths {
+			knownHashes[path] = hash
+		}
+	}
+	copies := redundantCopies(FileDuplicates)
+
+	// Step 1: Split the snapshot into compressed files and plain files by size,
+	// leaving out the copies of byte-identical sets.
+	var compressedPaths []string
+	plainBySize := make(map[int64][]string)
+	for dirPath, node := range Tree.Nodes {
+		for name, size := range node.Files {
+			path := filepath.Join(dirPath, name)
+			if copies[path] {
+				continue
+			}
+			if helpers.CompressionFormat(name) != "" {
+				compressedPaths = append(compressedPaths, path)
+			} else if size > 0 {
+				plainBySize[size] = append(plainBySize[size], path)
+			}
+		}
+	}
+
+	groups := make(map[string][]string)
+	if len(compressedPaths) == 0 {
+		return groups
+	}
+
+	// Step 2: Decompress every compressed file to learn its content size and hash.
+	decompressedSizes := make(map[int64]struct{})
+	var mu sync.Mutex
+	var processedFiles int
+	runJobs(compressedPaths, NumWorkers, func(path string) {
+		hash, size, err := helpers.CalculateDecompressedHash(path)
+
+		mu.Lock()
+		defer mu.Unlock()
+		processedFiles++
+		if err != nil {
+			log.Printf("Error decompressing file %s: %v\n", path, err)
+			return
+		}
+		if size > 0 {
+			key := fmt.Sprintf("%d-%s", size, hash)
+			groups[key] = append(groups[key], path)
+			decompressedSizes[size] = struct{}{}
+		}
+		if processedFiles%100 == 0 {
+			status.UpdateDetailedStatus("phase3", 60.0, "Decompressing files", len(FileDuplicates), 0, processedFiles, len(compressedPaths), "Compressed Files")
+		}
+	})
+
+	// Step 3: Hash plain files whose size matches a decompressed size.
+	var plainPaths []string
+	for size := range decompressedSizes {
+		plainPaths = append(plainPaths, plainBySize[size]...)
+	}
+	runJobs(plainPaths, NumWorkers, func(path string) {
+		hash, found := knownHashes[path]
+		if !found {
+			var err error
+			hash, err = helpers.CalculateHash(path, false) // false for full hash
+			if err != nil {
+				log.Printf("Error full hashing file %s: %v\n", path, err)
+				return
+			}
+		}
+		size := Tree.Nodes[filepath.Dir(path)].Files[filepath.Base(path)]
+		key := fmt.Sprintf("%d-%s", size, hash)
+
+		mu.Lock()
+		defer mu.Unlock()
+		// Only join groups that already hold a compressed file.
+		if _, ok := groups[key]; ok {
+			groups[key] = append(groups[key], path)
+		}
+	})
+
+	// Step 4: A group needs at least two members to be an equivalence.
+	for key, paths := range groups {
+		if len(paths) < 2 {
+			delete(groups, key)
+		}
+	}
+
+	return groups
+}
+
+// runJobs processes paths with a pool of NumWorkers goroutines and waits for them to finish.
+// It stops handing out new paths once the scan is cancelled.
+func runJobs(paths []string, NumWorkers int, process func(path string)) {
+	var wg sync.WaitGroup
+	jobs := make(chan string, NumWorkers)
+
+	for i := 0; i < NumWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				process(path)
+			}
+		}()
+	}
+
+	for _, path := range paths {
+		if IsCancelled() {
+			break
+		}
+		jobs <- path
+	}
+	close(jobs)
+
+	wg.Wait()
+}
@@ -0,0 +1,119 @@
+package fastdupefinder
+
+import (
+	"log"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/helpers"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/status"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types"
+)
+
+// Phase3FindSimilarImages clusters JPEG, PNG and GIF images that look alike even though their
+// bytes differ, e.g. re-encoded, resized or metadata-stripped copies of a photo.
+// Every image in the Phase 1 snapshot is decoded once and fingerprinted. Clusters are built
+// around representatives, largest image first: each image not yet clustered takes the other
+// unclustered images whose pHash lies within MaxDistance bits of its own, found through a
+// BK-tree. Images are never grouped transitively, so every image of a cluster is within
+// MaxDistance of the first one. Clusters made only of byte-identical files are left to Phase 3.
+// Images in each cluster are ordered by resolution, largest first.
+func Phase3FindSimilarImages(Tree *types.DirTree, FileDuplicates map[string][]string, MaxDistance int, NumWorkers int) [][]types.ImageFingerprint {
+	// Step 1: Collect images from the snapshot.
+	var imagePaths []string
+	for dirPath, node := range Tree.Nodes {
+		for name, size := range node.Files {
+			if size > 0 && helpers.IsSupportedImage(name) {
+				imagePaths = append(imagePaths, filepath.Join(dirPath, name))
+			}
+		}
+	}
+
+	// Step 2: Decode and fingerprint every image.
+	var fingerprints []types.ImageFingerprint
+	var mu sync.Mutex
+	var processedImages int
+	runJobs(imagePaths, NumWorkers, func(path string) {
+		fingerprint, err := helpers.CalculateImageFingerprint(path)
+
+		mu.Lock()
+		defer mu.Unlock()
+		processedImages++
+		if err != nil {
+			log.Printf("Error decoding image %s: %v\n", path, err)
+			return
+		}
+		fingerprints = append(fingerprints, fingerprint)
+		if processedImages%100 == 0 {
+			status.UpdateDetailedStatus("phase3", 60.0, "Fingerprinting images", len(FileDuplicates), 0, processedImages, len(imagePaths), "Images")
+		}
+	})
+	// Largest first, so each representative is the largest image of its cluster.
+	sort.Slice(fingerprints, func(i, j int) bool {
+		pixelsI, pixelsJ := fingerprints[i].Width*fingerprints[i].Height, fingerprints[j].Width*fingerprints[j].Height
+		if pixelsI != pixelsJ {
+			return pixelsI > pixelsJ
+		}
+		return fingerprints[i].Path < fingerprints[j].Path
+	})
+
+	// Step 3: Cluster the images within MaxDistance of each representative using a BK-tree.
+	tree := helpers.NewBKTree()
+	for i, fingerprint := range fingerprints {
+		tree.Insert(fingerprint.PHash, i)
+	}
+	pathToHash := make(map[string]string)
+	for hash, paths := range FileDuplicates {
+		for _, path := range paths {
+			pathToHash[path] = hash
+		}
+	}
+	clustered := make([]bool, len(fingerprints))
+	var result [][]types.ImageFingerprint
+	for i, fingerprint := range fingerprints {
+		if clustered[i] {
+			continue
+		}
+		if IsCancelled() {
+			return nil
+		}
+		clustered[i] = true
+		members := []int{i}
+		for _, j := range tree.Query(fingerprint.PHash, MaxDistance) {
+			if !clustered[j] {
+				clustered[j] = true
+				members = append(members, j)
+			}
+		}
+		// Indices follow the resolution order, which leaves the representative first.
+		sort.Ints(members)
+		cluster := make([]types.ImageFingerprint, 0, len(members))
+		for _, j := range members {
+			cluster = append(cluster, fingerprints[j])
+		}
+
+		// Step 4: Skip clusters that are plain byte-identical duplicates.
+		if len(cluster) < 2 || allByteIdentical(cluster, pathToHash) {
+			continue
+		}
+		result = append(result, cluster)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i][0].Path < result[j][0].Path })
+
+	return result
+}
+
+// allByteIdentical reports whether all images of a cluster share the same full hash.
+func allByteIdentical(cluster []types.ImageFingerprint, pathToHash map[string]string) bool {
+	first, found := pathToHash[cluster[0].Path]
+	if !found {
+		return false
+	}
+	for _, fingerprint := range cluster[1:] {
+		if pathToHash[fingerprint.Path] != first {
+			return false
+		}
+	}
+	return true
+}
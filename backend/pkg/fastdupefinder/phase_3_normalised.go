@@ -0,0 +1,159 @@
+package fastdupefinder
+
+import (
+	"errors"
+	"log"
+	"path/filepath"
+	"sync"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/helpers"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/status"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types"
+)
+
+// contentComparison describes a format-aware comparison run by Phase3FindNormalisedDuplicates.
+type contentComparison struct {
+	Category string                                     // Content category of the matches, see types.ContentCategoryDescriptions
+	Status   string                                     // Status message while the comparison runs
+	Enabled  func(config Phase1Config) bool             // Whether the comparison was requested
+	Match    func(name string) bool                     // Selects the candidate files by name
+	Hasher   func(config Phase1Config) helpers.HashFunc // Returns the hasher of the normalised content
+}
+
+// contentComparisons lists the format-aware comparisons in the order they run.
+var contentComparisons = []contentComparison{
+	{
+		Category: types.ContentCategoryJPEGMetadata,
+		Status:   "Comparing JPEG image data",
+		Enabled:  func(config Phase1Config) bool { return config.IgnoreJPEGMetadata },
+		Match:    helpers.IsJPEG,
+		Hasher:   func(Phase1Config) helpers.HashFunc { return helpers.CalculateJPEGHash },
+	},
+	{
+		Category: types.ContentCategoryAudioTags,
+		Status:   "Comparing audio data",
+		Enabled:  func(config Phase1Config) bool { return config.IgnoreAudioTags },
+		Match:    helpers.IsAudio,
+		Hasher:   func(Phase1Config) helpers.HashFunc { return helpers.CalculateAudioHash },
+	},
+	{
+		Category: types.ContentCategoryNormalisedText,
+		Status:   "Comparing normalised text",
+		Enabled:  func(config Phase1Config) bool { return config.NormaliseText },
+		Match:    func(string) bool { return true }, // Text is detected by content
+		Hasher: func(config Phase1Config) helpers.HashFunc {
+			return helpers.NewTextNormalisingHasher(config.TrimTrailingWhitespace)
+		},
+	},
+	{
+		Category: types.ContentCategoryDocuments,
+		Status:   "Comparing office documents",
+		Enabled:  func(config Phase1Config) bool { return config.IgnoreDocumentMetadata },
+		Match:    helpers.IsOfficeDocument,
+		Hasher:   func(Phase1Config) helpers.HashFunc { return helpers.CalculateDocumentHash },
+	},
+}
+
+// Phase3FindNormalisedDuplicates groups files that hash the same with a format-aware Hasher,
+// e.g. helpers.CalculateJPEGHash, even though their bytes differ.
+// Like Phases 2 and 3, candidates are first grouped by partial hash and only groups with at
+// least two files are hashed in full. Groups whose files are all byte-identical are left to Phase 3.
+// Keys of the returned map are the normalised full hashes.
+func Phase3FindNormalisedDuplicates(Paths []string, Hasher helpers.HashFunc, FileDuplicates map[string][]string, NumWorkers int) map[string][]string {
+	// Step 1: Group candidates by partial normalised hash.
+	byPartialHash := hashPathsWith(Paths, Hasher, true, NumWorkers, len(FileDuplicates))
+
+	// Step 2: Hash groups with more than one file in full.
+	var candidates []string
+	for _, paths := range byPartialHash {
+		if len(paths) > 1 {
+			candidates = append(candidates, paths...)
+		}
+	}
+	byFullHash := hashPathsWith(candidates, Hasher, false, NumWorkers, len(FileDuplicates))
+
+	// Step 3: Keep groups that are not already plain duplicates.
+	pathToHash := make(map[string]string)
+	for hash, paths := range FileDuplicates {
+		for _, path := range paths {
+			pathToHash[path] = hash
+		}
+	}
+	for hash, paths := range byFullHash {
+		if len(paths) < 2 || allSameHash(paths, pathToHash) {
+			delete(byFullHash, hash)
+		}
+	}
+
+	return byFullHash
+}
+
+// hashPathsWith hashes paths with Hasher and groups them by hash, logging files that fail.
+func hashPathsWith(Paths []string, Hasher helpers.HashFunc, Partial bool, NumWorkers int, FileDuplicateCount int) map[string][]string {
+	groups := make(map[string][]string)
+	var mu sync.Mutex
+	var processedFiles int
+	runJobs(Paths, NumWorkers, func(path string) {
+		hash, err := Hasher(path, Partial)
+
+		mu.Lock()
+		defer mu.Unlock()
+		processedFiles++
+		if errors.Is(err, helpers.ErrNotApplicable) {
+			return
+		}
+		if err != nil {
+			log.Printf("Error normalised hashing file %s: %v\n", path, err)
+			return
+		}
+		groups[hash] = append(groups[hash], path)
+		if processedFiles%100 == 0 {
+			status.UpdateDetailedStatus("phase3", 60.0, "Comparing normalised content", FileDuplicateCount, 0, processedFiles, len(Paths), "Files")
+		}
+	})
+	return groups
+}
+
+// collectTreeFiles returns the non-empty files of the snapshot whose name satisfies Match.
+func collectTreeFiles(Tree *types.DirTree, Match func(name string) bool) []string {
+	var paths []string
+	for dirPath, node := range Tree.Nodes {
+		for name, size := range node.Files {
+			if size > 0 && Match(name) {
+				paths = append(paths, filepath.Join(dirPath, name))
+			}
+		}
+	}
+	return paths
+}
+
+// allSameHash reports whether all paths share the same Phase 3 full hash.
+func allSameHash(paths []string, pathToHash map[string]string) bool {
+	first, found := pathToHash[paths[0]]
+	if !found {
+		return false
+	}
+	for _, path := range paths[1:] {
+		if pathToHash[path] != first {
+			return false
+		}
+	}
+	return true
+}
+
+// redundantCopies returns the paths of FileDuplicates other than the first path of each set.
+// Analyses that report them again would only repeat Phase 3 and count their size twice.
+func redundantCopies(FileDuplicates map[string][]string) map[string]bool {
+	copies := make(map[string]bool)
+	for _, paths := range FileDuplicates {
+		first := paths[0]
+		for _, path := range paths[1:] {
+			if path < first {
+				copies[first], first = true, path
+			} else {
+				copies[path] = true
+			}
+		}
+	}
+	return copies
+}
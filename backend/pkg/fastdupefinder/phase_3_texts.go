@@ -0,0 +1,116 @@
+package fastdupefinder
+
+import (
+	"log"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/helpers"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/status"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types"
+)
+
+// maxTextBucketSize is the largest LSH bucket whose files are compared pairwise.
+const maxTextBucketSize = 200
+
+// Phase3FindSimilarTexts finds pairs of text files whose content is largely the same, e.g. copied
+// source files or documents that differ by a few lines.
+// Files of the Phase 1 snapshot are sniffed and binary files are skipped. Every text file is split
+// into shingles of consecutive tokens and summarised as a MinHash signature; LSH banding proposes
+// candidate pairs, which are kept when their estimated similarity reaches MinSimilarity.
+// Byte-identical files are left to Phase 3: only the first file of each Phase 3 set is compared.
+// Pairs are ordered by similarity, highest first.
+func Phase3FindSimilarTexts(Tree *types.DirTree, FileDuplicates map[string][]string, MinSimilarity float64, NumWorkers int) []types.SimilarTextPair {
+	// Step 1: Collect files small enough to be compared as text, one per byte-identical set.
+	copies := redundantCopies(FileDuplicates)
+	var paths []string
+	for dirPath, node := range Tree.Nodes {
+		for name, size := range node.Files {
+			path := filepath.Join(dirPath, name)
+			if size > 0 && size <= helpers.MaxTextSize && !copies[path] {
+				paths = append(paths, path)
+			}
+		}
+	}
+
+	// Step 2: Sniff each file and compute the MinHash signature of text files.
+	signatures := make(map[string][]uint64)
+	var mu sync.Mutex
+	var processedFiles int
+	runJobs(paths, NumWorkers, func(path string) {
+		isText, err := helpers.IsTextFile(path)
+		var signature []uint64
+		if err == nil && isText {
+			signature, err = helpers.CalculateMinHash(path)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		processedFiles++
+		if err != nil {
+			log.Printf("Error reading text file %s: %v\n", path, err)
+			return
+		}
+		if signature != nil {
+			signatures[path] = signature
+		}
+		if processedFiles%100 == 0 {
+			status.UpdateDetailedStatus("phase3", 60.0, "Comparing text files", len(FileDuplicates), 0, processedFiles, len(paths), "Files")
+		}
+	})
+
+	// Step 3: Bucket signatures by LSH band to find candidate pairs.
+	textPaths := make([]string, 0, len(signatures))
+	for path := range signatures {
+		textPaths = append(textPaths, path)
+	}
+	sort.Strings(textPaths)
+
+	type pairKey struct{ a, b int }
+	candidatePairs := make(map[pairKey]struct{})
+	for band := 0; band < helpers.MinHashBands; band++ {
+		if IsCancelled() {
+			return nil
+		}
+		buckets := make(map[uint64][]int)
+		for i, path := range textPaths {
+			key := helpers.MinHashBandKey(signatures[path], band)
+			buckets[key] = append(buckets[key], i)
+		}
+		for _, members := range buckets {
+			// Huge buckets come from boilerplate shared by many files (licence headers, generated
+			// code) and would cost a quadratic number of comparisons; they are skipped.
+			if len(members) > maxTextBucketSize {
+				log.Printf("Skipping %d text files sharing LSH band %d, more than %d\n", len(members), band, maxTextBucketSize)
+				continue
+			}
+			for x := 0; x < len(members); x++ {
+				for y := x + 1; y < len(members); y++ {
+					candidatePairs[pairKey{members[x], members[y]}] = struct{}{}
+				}
+			}
+		}
+	}
+
+	// Step 4: Keep candidates above the threshold.
+	var pairs []types.SimilarTextPair
+	for pair := range candidatePairs {
+		pathA, pathB := textPaths[pair.a], textPaths[pair.b]
+		similarity := helpers.EstimateSimilarity(signatures[pathA], signatures[pathB])
+		if similarity >= MinSimilarity {
+			pairs = append(pairs, types.SimilarTextPair{PathA: pathA, PathB: pathB, Similarity: similarity})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Similarity != pairs[j].Similarity {
+			return pairs[i].Similarity > pairs[j].Similarity
+		}
+		if pairs[i].PathA != pairs[j].PathA {
+			return pairs[i].PathA < pairs[j].PathA
+		}
+		return pairs[i].PathB < pairs[j].PathB
+	})
+
+	return pairs
+}
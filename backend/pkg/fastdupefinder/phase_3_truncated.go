@@ -0,0 +1,128 @@
+package fastdupefinder
+
+import (
+	"log"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/helpers"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/status"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types"
+)
+
+// maxLeadingBlockGroupSize is the largest group of files sharing a leading block that is
+// compared pairwise.
+const maxLeadingBlockGroupSize = 200
+
+// Phase3FindTruncatedCopies finds files whose content is a strict prefix of a larger file,
+// e.g. interrupted downloads or half-finished copies, which Phase 1 never groups since their
+// sizes differ.
+// Files of the Phase 1 snapshot are grouped by the hash of their first PartialHashSize bytes;
+// files shorter than that are ignored, and so are groups of more than maxLeadingBlockGroupSize
+// files. Within a group, every file is compared with the larger ones, smallest first, until it
+// is found to be a prefix of one, and reported against the largest file of that chain of prefixes.
+func Phase3FindTruncatedCopies(Tree *types.DirTree, FileDuplicates map[string][]string, NumWorkers int) []types.TruncatedCopy {
+	// Step 1: Collect files holding at least one full leading block.
+	sizes := make(map[string]int64)
+	var paths []string
+	for dirPath, node := range Tree.Nodes {
+		for name, size := range node.Files {
+			if size >= helpers.PartialHashSize {
+				path := filepath.Join(dirPath, name)
+				sizes[path] = size
+				paths = append(paths, path)
+			}
+		}
+	}
+
+	// Step 2: Group files by the hash of their leading block.
+	groups := make(map[string][]string)
+	var mu sync.Mutex
+	var processedFiles int
+	runJobs(paths, NumWorkers, func(path string) {
+		hash, err := helpers.CalculateLeadingBlockHash(path)
+
+		mu.Lock()
+		defer mu.Unlock()
+		processedFiles++
+		if err != nil {
+			log.Printf("Error hashing leading block of %s: %v\n", path, err)
+			return
+		}
+		groups[hash] = append(groups[hash], path)
+		if processedFiles%100 == 0 {
+			status.UpdateDetailedStatus("phase3", 60.0, "Looking for truncated copies", len(FileDuplicates), 0, processedFiles, len(paths), "Files")
+		}
+	})
+
+	// Step 3: Order each group by size, largest first, and queue the files that have a larger
+	// candidate. Huge groups come from headers shared by many files, such as the zeroes at the
+	// start of disk images, and would cost a quadratic number of comparisons; they are skipped.
+	groupOf := make(map[string][]string)
+	indexOf := make(map[string]int)
+	var truncatedCandidates []string
+	for hash, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		if len(group) > maxLeadingBlockGroupSize {
+			log.Printf("Skipping %d files sharing the leading block %s, more than %d\n", len(group), hash, maxLeadingBlockGroupSize)
+			continue
+		}
+		sort.Slice(group, func(i, j int) bool {
+			if sizes[group[i]] != sizes[group[j]] {
+				return sizes[group[i]] > sizes[group[j]]
+			}
+			return group[i] < group[j]
+		})
+		for i, path := range group {
+			if sizes[group[0]] > sizes[path] {
+				groupOf[path], indexOf[path] = group, i
+				truncatedCandidates = append(truncatedCandidates, path)
+			}
+		}
+	}
+
+	// Step 4: Verify the prefix relation byte by byte against the next larger files, smallest
+	// first, until one matches.
+	completedBy := make(map[string]string)
+	runJobs(truncatedCandidates, NumWorkers, func(path string) {
+		group := groupOf[path]
+		for i := indexOf[path] - 1; i >= 0; i-- {
+			larger := group[i]
+			if sizes[larger] == sizes[path] {
+				continue
+			}
+			isPrefix, err := helpers.IsPrefixOf(path, larger)
+			if err != nil {
+				log.Printf("Error comparing %s with %s: %v\n", path, larger, err)
+				continue
+			}
+			if isPrefix {
+				mu.Lock()
+				completedBy[path] = larger
+				mu.Unlock()
+				return
+			}
+		}
+	})
+
+	// Step 5: Follow each chain of prefixes to its largest file, since a prefix of a prefix
+	// is a prefix of the larger file as well.
+	var copies []types.TruncatedCopy
+	for path, complete := range completedBy {
+		for next, ok := completedBy[complete]; ok; next, ok = completedBy[complete] {
+			complete = next
+		}
+		copies = append(copies, types.TruncatedCopy{
+			Path:         path,
+			Size:         sizes[path],
+			CompletePath: complete,
+			CompleteSize: sizes[complete],
+		})
+	}
+	sort.Slice(copies, func(i, j int) bool { return copies[i].Path < copies[j].Path })
+
+	return copies
+}
@@ -0,0 +1,43 @@
+package fastdupefinder
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/helpers"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types"
+)
+
+func TestPhase3FindTruncatedCopies(t *testing.T) {
+	dir := t.TempDir()
+	block := int(helpers.PartialHashSize)
+	complete := bytes.Repeat([]byte("0123456789abcdef"), 4*block/16)
+	other := append(append([]byte(nil), complete[:block]...), bytes.Repeat([]byte("x"), 3*block)...)
+	files := map[string][]byte{
+		"complete.iso": complete,
+		"partial.iso":  complete[:3*block],   // Prefix of a prefix, reported against the complete file
+		"started.iso":  complete[:block+100], // Prefix of both larger files
+		"other.iso":    other,                // Same leading block, other content
+		"short.iso":    complete[:block/2],   // Shorter than a leading block
+	}
+	tree := types.NewDirTree()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		tree.AddFile(path, int64(len(content)))
+	}
+
+	got := make(map[string]string)
+	for _, copy := range Phase3FindTruncatedCopies(tree, nil, 2) {
+		got[filepath.Base(copy.Path)] = filepath.Base(copy.CompletePath)
+	}
+	want := map[string]string{"partial.iso": "complete.iso", "started.iso": "complete.iso"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("truncated copies %v, want %v", got, want)
+	}
+}
@@ -9,15 +9,20 @@ import (
 	"sync"
 	"sync/atomic"
 
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/archives"
 	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/helpers"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/s3"
 	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/status"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types"
 	"golang.org/x/sync/errgroup"
 )
 
 // Phase4FindDuplicateFolders identifies duplicate folders based on the file duplicates found.
+// Folder contents come from the directory snapshot recorded in Phase 1, so this phase
+// performs no filesystem I/O and sees the same tree as the earlier phases.
 // This version is optimized to run concurrently, significantly speeding up the analysis
 // of large directory structures.
-func Phase4FindDuplicateFolders(FileDuplicates map[string][]string) map[string][]string {
+func Phase4FindDuplicateFolders(FileDuplicates map[string][]string, Tree *types.DirTree) map[string][]string {
 	status.UpdateDetailedStatus("phase4", 60.0, "Preparing to analyze folders", len(FileDuplicates), 0, 0, 0, "Files")
 
 	// Step 1: Create a thread-safe reverse map for quick hash lookups (path -> hash).
@@ -34,9 +39,14 @@ func Phase4FindDuplicateFolders(FileDuplicates map[string][]string) map[string][
 	// Step 2: Identify all candidate folders and sort them by depth (deepest first).
 	// Sorting deepest first is a key optimization that maximizes cache hits during
 	// recursive signature calculation, making the concurrent processing more efficient.
+	// Archive members never make their archive's directories candidates, and S3 objects
+	// have no directories in the snapshot.
 	candidateFoldersSet := make(map[string]struct{})
 	for _, paths := range FileDuplicates {
 		for _, path := range paths {
+			if archives.IsVirtualPath(path) || s3.IsObjectPath(path) {
+				continue
+			}
 			dir := filepath.Dir(path)
 			candidateFoldersSet[dir] = struct{}{}
 		}
@@ -62,14 +72,14 @@ func Phase4FindDuplicateFolders(FileDuplicates map[string][]string) map[string][
 	var processedFolders int64
 	totalFolders := len(candidateFolders)
 	var g errgroup.Group
-	// Limit concurrency to a multiple of CPU cores to balance I/O and CPU work.
-	g.SetLimit(runtime.NumCPU() * 2)
+	// Signatures are computed in memory, so one worker per CPU core is enough.
+	g.SetLimit(runtime.NumCPU())
 
 	for _, folderPath := range candidateFolders {
 		fp := folderPath
 		g.Go(func() error {
 			// GetFolderSignature must be thread-safe.
-			signature, isDuplicable := helpers.GetFolderSignature(fp, pathToHashMap, folderSignatureCache)
+			signature, isDuplicable := helpers.GetFolderSignature(fp, Tree, pathToHashMap, folderSignatureCache)
 			if isDuplicable {
 				signatureToFoldersMap.Lock()
 				signatureToFoldersMap.m[signature] = append(signatureToFoldersMap.m[signature], fp)
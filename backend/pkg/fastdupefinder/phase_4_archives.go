@@ -0,0 +1,166 @@
+package fastdupefinder
+
+import (
+	"log"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/archives"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/helpers"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/status"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types"
+)
+
+// Phase4FindArchiveFolderDuplicates finds zip and tar(.gz) archives whose content equals a folder of the scan,
+// such as a "project.zip" sitting next to its extracted "project/" folder.
+// Each archive is read once and signed with the same code as Phase 4 folders; folders are only hashed
+// when their file count and total size (taken from the Phase 1 snapshot) match the archive.
+// An archive matches either with its root or with its single top-level directory.
+// It returns a map from archive path to the folders holding the same content.
+func Phase4FindArchiveFolderDuplicates(Tree *types.DirTree, FileDuplicates map[string][]string, NumWorkers int) map[string][]string {
+	// Step 1: Collect archives and index complete folders by file count and total size.
+	var archivePaths []string
+	folderSummaries := make(map[string]types.DirSummary)
+	foldersBySummary := make(map[types.DirSummary][]string)
+	for dirPath, node := range Tree.Nodes {
+		for name := range node.Files {
+			if archives.IsArchive(name) {
+				archivePaths = append(archivePaths, filepath.Join(dirPath, name))
+			}
+		}
+		summary := Tree.Summarize(dirPath, folderSummaries)
+		if summary.Complete && summary.FileCount > 0 {
+			foldersBySummary[summary] = append(foldersBySummary[summary], dirPath)
+		}
+	}
+	sort.Strings(archivePaths)
+
+	if len(archivePaths) == 0 {
+		return make(map[string][]string)
+	}
+
+	// Hashes already known from Phase 3 are reused; the rest are computed on demand.
+	pathToHashMap := &sync.Map{}
+	for hash, paths := range FileDuplicates {
+		for _, path := range paths {
+			pathToHashMap.Store(path, hash)
+		}
+	}
+	folderSignatureCache := &sync.Map{}
+
+	results := make(map[string][]string)
+	var mu sync.Mutex
+	var processedArchives int
+
+	var wg sync.WaitGroup
+	jobs := make(chan string, NumWorkers)
+
+	for i := 0; i < NumWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for archivePath := range jobs {
+				matches := matchArchiveWithFolders(archivePath, Tree, foldersBySummary, pathToHashMap, folderSignatureCache)
+
+				mu.Lock()
+				if len(matches) > 0 {
+					results[archivePath] = matches
+				}
+				processedArchives++
+				status.UpdateDetailedStatus("phase4", 80.0, "Comparing archives with folders", len(FileDuplicates), len(results), processedArchives, len(archivePaths), "Archives")
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, archivePath := range archivePaths {
+		if IsCancelled() {
+			break
+		}
+		jobs <- archivePath
+	}
+	close(jobs)
+
+	wg.Wait()
+
+	return results
+}
+
+// matchArchiveWithFolders returns the folders whose signature equals the archive's root
+// or its single top-level directory.
+func matchArchiveWithFolders(
+	archivePath string,
+	tree *types.DirTree,
+	foldersBySummary map[types.DirSummary][]string,
+	pathToHashMap *sync.Map,
+	folderSignatureCache *sync.Map,
+) []string {
+	archiveTree, archiveHashes, err := archives.ReadTree(archivePath, helpers.HashReader)
+	if err != nil {
+		log.Printf("Error reading archive %s: %v\n", archivePath, err)
+		return nil
+	}
+
+	// An archive either holds the folder's content directly or wraps it in a single directory.
+	root := archives.VirtualRoot(archivePath)
+	layers := []string{root}
+	if node, _ := archiveTree.Get(root); len(node.Files) == 0 && len(node.Dirs) == 1 {
+		layers = append(layers, filepath.Join(root, node.Dirs[0]))
+	}
+
+	archiveSummaries := make(map[string]types.DirSummary)
+	archiveSignatureCache := &sync.Map{}
+	var matches []string
+
+	for _, layer := range layers {
+		summary := archiveTree.Summarize(layer, archiveSummaries)
+		if !summary.Complete || summary.FileCount == 0 {
+			continue
+		}
+		archiveSignature, ok := helpers.GetFolderSignature(layer, archiveTree, archiveHashes, archiveSignatureCache)
+		if !ok {
+			continue
+		}
+
+		for _, folderPath := range foldersBySummary[summary] {
+			if !hashFolderFiles(folderPath, tree, pathToHashMap) {
+				continue
+			}
+			folderSignature, ok := helpers.GetFolderSignature(folderPath, tree, pathToHashMap, folderSignatureCache)
+			if ok && folderSignature == archiveSignature {
+				matches = append(matches, folderPath)
+			}
+		}
+	}
+
+	sort.Strings(matches)
+	return matches
+}
+
+// hashFolderFiles makes sure every file below a folder has a full hash in pathToHashMap.
+// It returns false if any file could not be hashed.
+func hashFolderFiles(folderPath string, tree *types.DirTree, pathToHashMap *sync.Map) bool {
+	node, ok := tree.Get(folderPath)
+	if !ok {
+		return false
+	}
+	for name := range node.Files {
+		filePath := filepath.Join(folderPath, name)
+		if _, found := pathToHashMap.Load(filePath); found {
+			continue
+		}
+		hash, err := helpers.CalculateHash(filePath, false) // false for full hash
+		if err != nil {
+			log.Printf("Error full hashing file %s: %v\n", filePath, err)
+			return false
+		}
+		pathToHashMap.Store(filePath, hash)
+	}
+	for _, name := range node.Dirs {
+		if !hashFolderFiles(filepath.Join(folderPath, name), tree, pathToHashMap) {
+			return false
+		}
+	}
+	return true
+}
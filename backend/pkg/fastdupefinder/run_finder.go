@@ -1,12 +1,22 @@
 package fastdupefinder
 
 import (
-	"fmt"
+	"errors"
+	"log"
 	"runtime"
+	"strings"
+	"time"
 
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/helpers"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/s3"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/sources"
 	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/status"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/types"
 )
 
+// errScanCancelled is returned when the user cancels a running scan.
+var errScanCancelled = errors.New("scan cancelled by user")
+
 // RunFinder orchestrates the entire duplicate finding process.
 func RunFinder(RootDir string) (map[string][]string, map[string][]string, map[string][]string, map[string][]string, error) {
 	return RunFinderWithConfig(RootDir, DefaultConfig())
@@ -21,6 +31,35 @@ func RunFinderWithCpuConfig(RootDir string, cpuCores int) (map[string][]string,
 
 // RunFinderWithConfig orchestrates the entire duplicate finding process with custom configuration.
 func RunFinderWithConfig(RootDir string, config Phase1Config) (map[string][]string, map[string][]string, map[string][]string, map[string][]string, error) {
+	results, err := RunFinderWithResults(RootDir, config)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	return results.FilteredFileDuplicates, results.FilteredFolderDuplicates, results.AllFileDuplicates, results.AllFolderDuplicates, nil
+}
+
+// RunFinderWithResults orchestrates the entire duplicate finding process with custom configuration
+// and returns everything the scan produced, including the optional analyses enabled in config.
+func RunFinderWithResults(RootDir string, config Phase1Config) (*types.ScanResults, error) {
+	return runFinder(localSource(RootDir), config)
+}
+
+// RunFinderWithSource runs the duplicate finder over any file tree, such as sources.Dir,
+// sources.Zip or an fs.FS wrapped with sources.New (e.g. testing/fstest.MapFS).
+// Paths in the results are the names of the source joined to Root, which may be empty.
+// The optional analyses and archive expansion open files by path, so they only run through
+// RunFinderWithResults; a warning is logged if config enables them. S3 sources in config are
+// scanned as usual.
+func RunFinderWithSource(Source sources.Source, Root string, config Phase1Config) (*types.ScanResults, error) {
+	config, disabled := config.withoutLocalAnalyses()
+	if len(disabled) > 0 {
+		log.Printf("Warning: %s only work for local directories and are disabled for this scan", strings.Join(disabled, ", "))
+	}
+	return runFinder(scanSource{source: Source, root: Root}, config)
+}
+
+// runFinder runs all phases over a scan source.
+func runFinder(source scanSource, config Phase1Config) (*types.ScanResults, error) {
 	// Determine number of workers
 	var numWorkers int
 	if config.CpuCores <= 0 {
@@ -35,6 +74,14 @@ func RunFinderWithConfig(RootDir string, config Phase1Config) (map[string][]stri
 
 	// Reset cancellation flag at start
 	SetCancelled(false)
+	startedAt := time.Now()
+	defer helpers.ClearArchiveHashes()
+
+	// S3 objects are listed and hashed through the default client, which is set up for the
+	// endpoint of this scan.
+	if len(config.S3Sources) > 0 {
+		s3.SetDefaultClient(s3.NewClientFromEnv(config.S3Endpoint))
+	}
 
 	// Phase 1: Group by size (and optionally filename) (0-20%)
 	statusMsg := "Scanning files"
@@ -43,44 +90,164 @@ func RunFinderWithConfig(RootDir string, config Phase1Config) (map[string][]stri
 	}
 	status.UpdateStatus("phase1", 0.0, statusMsg, 0, 0)
 	if IsCancelled() {
-		return nil, nil, nil, nil, fmt.Errorf("scan cancelled by user")
+		return nil, errScanCancelled
 	}
-	potentialDupesBySize := Phase1GroupBySizeWithConfig(RootDir, config)
+	potentialDupesBySize, dirTree := phase1Scan(source, config)
 
 	// Phase 2: Filter by partial hash (20-40%)
 	status.UpdateStatus("phase2", 20.0, "Computing partial hashes", 0, 0)
 	if IsCancelled() {
-		return nil, nil, nil, nil, fmt.Errorf("scan cancelled by user")
+		return nil, errScanCancelled
 	}
-	potentialDupesByPartialHash := Phase2FilterByPartialHash(potentialDupesBySize, numWorkers)
+	potentialDupesByPartialHash := phase2FilterByPartialHash(potentialDupesBySize, numWorkers, source)
 
 	// Phase 3: Find duplicates by full hash (40-60%)
 	status.UpdateStatus("phase3", 40.0, "Computing full hashes", 0, 0)
 	if IsCancelled() {
-		return nil, nil, nil, nil, fmt.Errorf("scan cancelled by user")
+		return nil, errScanCancelled
+	}
+	allFileDuplicates := phase3FindDuplicatesByFullHash(potentialDupesByPartialHash, numWorkers, source)
+
+	// Optional: match compressed files by their decompressed content
+	var compressedEquivalents map[string][]string
+	if config.CompareDecompressed {
+		status.UpdateStatus("phase3", 60.0, "Decompressing files", len(allFileDuplicates), 0)
+		if IsCancelled() {
+			return nil, errScanCancelled
+		}
+		compressedEquivalents = Phase3FindCompressedEquivalents(dirTree, allFileDuplicates, numWorkers)
+	}
+
+	// Optional: cluster visually similar images
+	var similarImages [][]types.ImageFingerprint
+	if config.FindSimilarImages {
+		status.UpdateStatus("phase3", 60.0, "Fingerprinting images", len(allFileDuplicates), 0)
+		if IsCancelled() {
+			return nil, errScanCancelled
+		}
+		similarImages = Phase3FindSimilarImages(dirTree, allFileDuplicates, config.ImageDistance, numWorkers)
+	}
+
+	// Optional: find text files that differ by a few lines
+	var similarTexts []types.SimilarTextPair
+	if config.FindSimilarTexts {
+		status.UpdateStatus("phase3", 60.0, "Comparing text files", len(allFileDuplicates), 0)
+		if IsCancelled() {
+			return nil, errScanCancelled
+		}
+		similarTexts = Phase3FindSimilarTexts(dirTree, allFileDuplicates, config.TextSimilarity, numWorkers)
+	}
+
+	// Optional: find interrupted downloads and half-finished copies
+	var truncatedCopies []types.TruncatedCopy
+	if config.DetectTruncatedCopies {
+		status.UpdateStatus("phase3", 60.0, "Looking for truncated copies", len(allFileDuplicates), 0)
+		if IsCancelled() {
+			return nil, errScanCancelled
+		}
+		truncatedCopies = Phase3FindTruncatedCopies(dirTree, allFileDuplicates, numWorkers)
+	}
+
+	// Optional: estimate block-level sharing between large files
+	var sharedChunks *types.ChunkAnalysis
+	if config.AnalyseChunks {
+		status.UpdateStatus("phase3", 60.0, "Chunking large files", len(allFileDuplicates), 0)
+		if IsCancelled() {
+			return nil, errScanCancelled
+		}
+		analysis, err := Phase3AnalyseSharedChunks(dirTree, allFileDuplicates, config.ChunkMinFileSize, numWorkers)
+		if err != nil {
+			return nil, err
+		}
+		sharedChunks = &analysis
+	}
+
+	// Optional: match files whose content is equal apart from metadata
+	contentDuplicates := make(map[string]map[string][]string)
+	for _, comparison := range contentComparisons {
+		if !comparison.Enabled(config) {
+			continue
+		}
+		status.UpdateStatus("phase3", 60.0, comparison.Status, len(allFileDuplicates), 0)
+		if IsCancelled() {
+			return nil, errScanCancelled
+		}
+		paths := collectTreeFiles(dirTree, comparison.Match)
+		contentDuplicates[comparison.Category] = Phase3FindNormalisedDuplicates(paths, comparison.Hasher(config), allFileDuplicates, numWorkers)
 	}
-	allFileDuplicates := Phase3FindDuplicatesByFullHash(potentialDupesByPartialHash, numWorkers)
 
 	// Phase 4: Find duplicate folders (60-80%)
 	status.UpdateStatus("phase4", 60.0, "Analyzing folders", len(allFileDuplicates), 0)
 	if IsCancelled() {
-		return nil, nil, nil, nil, fmt.Errorf("scan cancelled by user")
+		return nil, errScanCancelled
+	}
+	allFolderDuplicates := Phase4FindDuplicateFolders(allFileDuplicates, dirTree)
+
+	results := &types.ScanResults{
+		AllFileDuplicates:     allFileDuplicates,
+		AllFolderDuplicates:   allFolderDuplicates,
+		CompressedEquivalents: compressedEquivalents,
+		SimilarImages:         similarImages,
+		SimilarTexts:          similarTexts,
+		TruncatedCopies:       truncatedCopies,
+		SharedChunks:          sharedChunks,
+		ContentDuplicates:     contentDuplicates,
+		Tree:                  dirTree,
+		StartedAt:             startedAt,
+	}
+
+	// Optional: compare archives with folders
+	if config.DetectArchiveFolders {
+		status.UpdateStatus("phase4", 80.0, "Comparing archives with folders", len(allFileDuplicates), len(allFolderDuplicates))
+		if IsCancelled() {
+			return nil, errScanCancelled
+		}
+		results.ArchiveFolderDuplicates = Phase4FindArchiveFolderDuplicates(dirTree, allFileDuplicates, numWorkers)
 	}
-	allFolderDuplicates := Phase4FindDuplicateFolders(allFileDuplicates)
 
 	// Phase 5: Filter results (80-100%)
 	status.UpdateStatus("phase5", 80.0, "Filtering results", len(allFileDuplicates), len(allFolderDuplicates))
 	if IsCancelled() {
-		return nil, nil, nil, nil, fmt.Errorf("scan cancelled by user")
+		return nil, errScanCancelled
 	}
-	filteredFileDuplicates, filteredFolderDuplicates := Phase5FilterResults(allFolderDuplicates, allFileDuplicates)
+	results.FilteredFileDuplicates, results.FilteredFolderDuplicates = Phase5FilterResults(allFolderDuplicates, allFileDuplicates)
 
 	// Final completion check
 	if IsCancelled() {
-		return nil, nil, nil, nil, fmt.Errorf("scan cancelled by user")
+		return nil, errScanCancelled
 	}
 
-	status.UpdateStatus("completed", 100.0, "Search completed", len(filteredFileDuplicates), len(filteredFolderDuplicates))
+	status.UpdateStatus("completed", 100.0, "Search completed", len(results.FilteredFileDuplicates), len(results.FilteredFolderDuplicates))
 
-	return filteredFileDuplicates, filteredFolderDuplicates, allFileDuplicates, allFolderDuplicates, nil
+	return results, nil
+}
+
+// withoutLocalAnalyses returns a copy of the config with every analysis disabled that needs
+// the scanned files to exist on disk, and the JSON names of the options it disabled.
+func (c Phase1Config) withoutLocalAnalyses() (Phase1Config, []string) {
+	options := []struct {
+		name    string
+		enabled *bool
+	}{
+		{"detectArchiveFolders", &c.DetectArchiveFolders},
+		{"expandArchives", &c.ExpandArchives},
+		{"compareDecompressed", &c.CompareDecompressed},
+		{"findSimilarImages", &c.FindSimilarImages},
+		{"ignoreJpegMetadata", &c.IgnoreJPEGMetadata},
+		{"ignoreAudioTags", &c.IgnoreAudioTags},
+		{"findSimilarTexts", &c.FindSimilarTexts},
+		{"normaliseText", &c.NormaliseText},
+		{"trimTrailingWhitespace", &c.TrimTrailingWhitespace},
+		{"ignoreDocumentMetadata", &c.IgnoreDocumentMetadata},
+		{"detectTruncatedCopies", &c.DetectTruncatedCopies},
+		{"analyseChunks", &c.AnalyseChunks},
+	}
+	var disabled []string
+	for _, option := range options {
+		if *option.enabled {
+			disabled = append(disabled, option.name)
+			*option.enabled = false
+		}
+	}
+	return c, disabled
 }
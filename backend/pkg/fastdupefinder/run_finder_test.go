@@ -0,0 +1,74 @@
+package fastdupefinder
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/sources"
+)
+
+func TestRunFinderWithSourceFindsDuplicates(t *testing.T) {
+	fsys := fstest.MapFS{
+		"photos/a.jpg":        {Data: []byte("picture one")},
+		"photos/b.jpg":        {Data: []byte("picture two")},
+		"backup/photos/a.jpg": {Data: []byte("picture one")},
+		"backup/photos/b.jpg": {Data: []byte("picture two")},
+		"notes/todo.txt":      {Data: []byte("picture one")},
+		"notes/other.txt":     {Data: []byte("same size!!")}, // Same size as the pictures, other content
+		"empty.txt":           {Data: []byte{}},
+	}
+
+	results, err := RunFinderWithSource(sources.New(fsys), "root", DefaultConfig().WithCpuCores(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fileSets []string
+	for _, paths := range results.AllFileDuplicates {
+		sorted := append([]string(nil), paths...)
+		sort.Strings(sorted)
+		fileSets = append(fileSets, strings.Join(sorted, " "))
+	}
+	sort.Strings(fileSets)
+	wantFileSets := []string{
+		"root/backup/photos/a.jpg root/notes/todo.txt root/photos/a.jpg",
+		"root/backup/photos/b.jpg root/photos/b.jpg",
+	}
+	if strings.Join(fileSets, "\n") != strings.Join(wantFileSets, "\n") {
+		t.Errorf("file sets\n%s\nwant\n%s", strings.Join(fileSets, "\n"), strings.Join(wantFileSets, "\n"))
+	}
+
+	var folderSets []string
+	for _, paths := range results.AllFolderDuplicates {
+		sorted := append([]string(nil), paths...)
+		sort.Strings(sorted)
+		folderSets = append(folderSets, strings.Join(sorted, " "))
+	}
+	if len(folderSets) != 1 || folderSets[0] != "root/backup/photos root/photos" {
+		t.Errorf("folder sets %q, want [%q]", folderSets, "root/backup/photos root/photos")
+	}
+}
+
+func TestRunFinderWithSourceWarnsAboutLocalAnalyses(t *testing.T) {
+	var output bytes.Buffer
+	log.SetOutput(&output)
+	defer log.SetOutput(os.Stderr)
+
+	fsys := fstest.MapFS{"a.txt": {Data: []byte("text")}, "b.txt": {Data: []byte("text")}}
+	config := DefaultConfig().WithSimilarTexts(true, 0.8).WithChunkAnalysis(true, -1)
+	results, err := RunFinderWithSource(sources.New(fsys), "", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results.AllFileDuplicates) != 1 || results.SharedChunks != nil || results.SimilarTexts != nil {
+		t.Errorf("unexpected results %+v", results)
+	}
+	if !strings.Contains(output.String(), "Warning: findSimilarTexts, analyseChunks only work for local directories") {
+		t.Errorf("no warning about the disabled analyses in the log:\n%s", output.String())
+	}
+}
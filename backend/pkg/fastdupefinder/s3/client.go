@@ -0,0 +1,272 @@
+// Package s3 reads objects from S3-compatible object stores through their REST API, so that
+// buckets can be scanned alongside local directories. Objects are addressed by paths of the
+// form "s3://bucket/key".
+package s3
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client talks to an S3-compatible endpoint using path-style requests
+// (https://endpoint/bucket/key) signed with AWS Signature Version 4.
+// Requests are sent unsigned if AccessKey is empty, e.g. for public buckets.
+type Client struct {
+	Endpoint     string // Base URL, e.g. "https://s3.eu-west-1.amazonaws.com" or "http://localhost:9000"
+	Region       string // Signing region, e.g. "us-east-1"
+	AccessKey    string
+	SecretKey    string
+	SessionToken string // Optional, for temporary credentials
+
+	// HTTPClient sends the requests; http.DefaultClient is used if nil.
+	HTTPClient *http.Client
+	// Now returns the signing time; time.Now is used if nil.
+	Now func() time.Time
+}
+
+// Object describes an object listed in a bucket.
+type Object struct {
+	Key  string
+	Size int64
+	ETag string
+}
+
+// ResponseError is returned for requests the server answered with an error status.
+type ResponseError struct {
+	StatusCode int
+	Code       string // S3 error code, e.g. "NoSuchKey"
+	Message    string
+}
+
+func (e *ResponseError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("s3: unexpected status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("s3: %s (status %d): %s", e.Code, e.StatusCode, e.Message)
+}
+
+var (
+	defaultClientMu sync.Mutex
+	defaultClient   *Client
+)
+
+// NewClientFromEnv creates a client using the standard AWS environment variables
+// (AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN, AWS_REGION).
+// If Endpoint is empty, AWS_ENDPOINT_URL is used, falling back to the AWS endpoint of the region.
+func NewClientFromEnv(Endpoint string) *Client {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+	if Endpoint == "" {
+		Endpoint = os.Getenv("AWS_ENDPOINT_URL")
+	}
+	if Endpoint == "" {
+		Endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	return &Client{
+		Endpoint:     strings.TrimRight(Endpoint, "/"),
+		Region:       region,
+		AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+}
+
+// SetDefaultClient sets the client used for all "s3://" paths of a scan.
+// Passing nil makes DefaultClient create a client from the environment again.
+func SetDefaultClient(c *Client) {
+	defaultClientMu.Lock()
+	defer defaultClientMu.Unlock()
+	defaultClient = c
+}
+
+// DefaultClient returns the client set with SetDefaultClient, creating one from the
+// environment on first use.
+func DefaultClient() *Client {
+	defaultClientMu.Lock()
+	defer defaultClientMu.Unlock()
+	if defaultClient == nil {
+		defaultClient = NewClientFromEnv("")
+	}
+	return defaultClient
+}
+
+// listBucketResult is the XML response of ListObjectsV2.
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key  string `xml:"Key"`
+		Size int64  `xml:"Size"`
+		ETag string `xml:"ETag"`
+	} `xml:"Contents"`
+}
+
+// ListObjects returns all objects of a bucket whose key starts with Prefix, following
+// continuation tokens across pages. Zero-byte "folder" markers ending in "/" are skipped.
+func (c *Client) ListObjects(Bucket string, Prefix string) ([]Object, error) {
+	var objects []Object
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}}
+		if Prefix != "" {
+			query.Set("prefix", Prefix)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := c.do(http.MethodGet, Bucket, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("s3: decoding object list of %s: %w", Bucket, err)
+		}
+
+		for _, content := range result.Contents {
+			if strings.HasSuffix(content.Key, "/") && content.Size == 0 {
+				continue
+			}
+			objects = append(objects, Object{Key: content.Key, Size: content.Size, ETag: strings.Trim(content.ETag, `"`)})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// Open streams Length bytes of an object starting at Offset, or the rest of the object if
+// Length is negative, using a ranged GET. It also returns the total size of the object.
+func (c *Client) Open(Bucket string, Key string, Offset int64, Length int64) (io.ReadCloser, int64, error) {
+	header := http.Header{}
+	ranged := Offset > 0 || Length >= 0
+	if ranged {
+		if Length >= 0 {
+			header.Set("Range", fmt.Sprintf("bytes=%d-%d", Offset, Offset+Length-1))
+		} else {
+			header.Set("Range", fmt.Sprintf("bytes=%d-", Offset))
+		}
+	}
+
+	resp, err := c.do(http.MethodGet, Bucket, Key, nil, header)
+	if err != nil {
+		// Ranges starting at or beyond the end of the object are not satisfiable.
+		if responseErr, ok := err.(*ResponseError); ok && responseErr.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+			size, statErr := c.Stat(Bucket, Key)
+			if statErr != nil {
+				return nil, 0, statErr
+			}
+			return io.NopCloser(strings.NewReader("")), size, nil
+		}
+		return nil, 0, err
+	}
+
+	if resp.StatusCode == http.StatusPartialContent {
+		// Content-Range: bytes first-last/total
+		_, total, _ := strings.Cut(resp.Header.Get("Content-Range"), "/")
+		size, err := strconv.ParseInt(total, 10, 64)
+		if err != nil {
+			resp.Body.Close()
+			return nil, 0, fmt.Errorf("s3: invalid Content-Range %q for %s", resp.Header.Get("Content-Range"), ObjectPath(Bucket, Key))
+		}
+		return resp.Body, size, nil
+	}
+
+	// The server ignored the range and sent the whole object.
+	size := resp.ContentLength
+	if ranged {
+		if _, err := io.CopyN(io.Discard, resp.Body, Offset); err != nil && err != io.EOF {
+			resp.Body.Close()
+			return nil, 0, err
+		}
+		if Length >= 0 {
+			return readCloser{io.LimitReader(resp.Body, Length), resp.Body}, size, nil
+		}
+	}
+	return resp.Body, size, nil
+}
+
+// Stat returns the size of an object using a HEAD request.
+func (c *Client) Stat(Bucket string, Key string) (int64, error) {
+	resp, err := c.do(http.MethodHead, Bucket, Key, nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.ContentLength, nil
+}
+
+// do sends a signed request for a bucket or object and returns the response if its status is 2xx.
+func (c *Client) do(Method string, Bucket string, Key string, Query url.Values, Header http.Header) (*http.Response, error) {
+	endpoint, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("s3: invalid endpoint %q: %w", c.Endpoint, err)
+	}
+	objectPath := strings.TrimRight(endpoint.Path, "/") + "/" + Bucket
+	if Key != "" {
+		objectPath += "/" + Key
+	}
+	endpoint.Path = objectPath
+	endpoint.RawPath = uriEncode(objectPath, false)
+	endpoint.RawQuery = canonicalQuery(Query)
+
+	req, err := http.NewRequest(Method, endpoint.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range Header {
+		req.Header[name] = values
+	}
+	if c.AccessKey != "" {
+		now := time.Now
+		if c.Now != nil {
+			now = c.Now
+		}
+		c.signRequest(req, now())
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		responseErr := &ResponseError{StatusCode: resp.StatusCode}
+		var body struct {
+			Code    string `xml:"Code"`
+			Message string `xml:"Message"`
+		}
+		if xml.NewDecoder(resp.Body).Decode(&body) == nil {
+			responseErr.Code, responseErr.Message = body.Code, body.Message
+		}
+		return nil, responseErr
+	}
+	return resp, nil
+}
+
+// readCloser combines a limited reader with the closer of the underlying body.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
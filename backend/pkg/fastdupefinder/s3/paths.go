@@ -0,0 +1,31 @@
+package s3
+
+import "strings"
+
+// PathPrefix starts every path that addresses an object in an S3-compatible store.
+const PathPrefix = "s3://"
+
+// ObjectPath returns the path addressing an object, e.g. "s3://bucket/dir/file.txt".
+// Such paths don't exist on disk and must never be passed to code that modifies files.
+func ObjectPath(Bucket string, Key string) string {
+	return PathPrefix + Bucket + "/" + Key
+}
+
+// SplitObjectPath splits an object path into bucket and key. The key may be empty or a
+// prefix when the path addresses a whole bucket or a "folder" of it.
+// The last return value is false if the path is not an S3 path.
+func SplitObjectPath(Path string) (string, string, bool) {
+	if !IsObjectPath(Path) {
+		return "", "", false
+	}
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(Path, PathPrefix), "/")
+	if bucket == "" {
+		return "", "", false
+	}
+	return bucket, key, true
+}
+
+// IsObjectPath reports whether the path addresses an object in an S3-compatible store.
+func IsObjectPath(Path string) bool {
+	return strings.HasPrefix(Path, PathPrefix)
+}
@@ -0,0 +1,72 @@
+package fastdupefinder
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/helpers"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/s3"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/sources"
+)
+
+// scanSource is the file tree a scan runs over. Paths in the results are the names of the
+// source joined to root with native separators.
+type scanSource struct {
+	source sources.Source
+	root   string
+
+	// local is set when the paths are real paths on disk, so archive members, S3 objects and
+	// the optional analyses, which open files by path, can be used.
+	local bool
+}
+
+// localSource returns the scan source of a directory on disk.
+func localSource(RootDir string) scanSource {
+	return scanSource{source: sources.Dir(RootDir), root: RootDir, local: true}
+}
+
+// path returns the result path of a name of the source.
+func (s scanSource) path(name string) string {
+	return filepath.Join(s.root, filepath.FromSlash(name))
+}
+
+// name returns the name in the source of a result path.
+func (s scanSource) name(path string) (string, error) {
+	rel, err := filepath.Rel(s.root, path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// hash hashes a file like helpers.CalculateHash, reading files that aren't on disk from the source.
+func (s scanSource) hash(path string, partial bool) (string, error) {
+	if s.local || s3.IsObjectPath(path) {
+		return helpers.CalculateHash(path, partial)
+	}
+	name, err := s.name(path)
+	if err != nil {
+		return "", err
+	}
+	return helpers.CalculateSourceHash(s.source, name, partial)
+}
+
+// size returns the current size of a file.
+func (s scanSource) size(path string) (int64, error) {
+	if s.local {
+		info, err := os.Stat(path)
+		if err != nil {
+			return 0, err
+		}
+		return info.Size(), nil
+	}
+	name, err := s.name(path)
+	if err != nil {
+		return 0, err
+	}
+	stat, err := s.source.StatFile(name)
+	if err != nil {
+		return 0, err
+	}
+	return stat.Size, nil
+}
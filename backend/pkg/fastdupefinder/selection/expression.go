@@ -0,0 +1,211 @@
+package selection
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// File describes a copy of a duplicate set to an Expression.
+type File struct {
+	Path    string
+	Size    int64
+	ModTime time.Time // Zero if unknown
+	Root    string    // Label of the scan root holding the file, see RootLabel
+}
+
+// Root gives a label to a scanned folder, so expressions can tell copies apart by where they were found.
+type Root struct {
+	Label string
+	Dir   string
+}
+
+// RootLabel returns the label of the innermost root containing path, or "" if none does.
+func RootLabel(Roots []Root, path string) string {
+	label, longest := "", -1
+	for _, root := range Roots {
+		dir := filepath.Clean(root.Dir)
+		if isInside(path, dir) && len(dir) > longest {
+			label, longest = root.Label, len(dir)
+		}
+	}
+	return label
+}
+
+// Expression is a compiled selection expression. It is evaluated for each copy of a duplicate
+// set and selects the copies for which it holds, e.g.
+//
+//	path =~ "/Downloads/" && mtime < keeper.mtime
+//
+// An expression combines comparisons (==, !=, <, <=, >, >=), regular expression matches
+// (=~, !~ with a string literal on the right), &&, || and ! of the following values:
+//
+//	path, name, dir, ext, size, mtime, depth, root  the copy being evaluated
+//	keeper.path, keeper.name, ...                   the same values of the kept copy of the set
+//	set.count, set.size                             number of copies and size of each copy
+//	set.oldest, set.newest                          modification times of the oldest and newest copy
+//	set.mindepth, set.maxdepth                      depth of the least and most nested copy
+//
+// ext is lower case without the dot, depth is the number of path components and root the label
+// of the scan root holding the copy. Literals are "strings" (with Go escapes), 'raw strings',
+// numbers with an optional KB, MB, GB or TB suffix (powers of 1024), true and false. The functions
+// date("2006-01-02"), lower(s), contains(s, sub), hasprefix(s, prefix) and hassuffix(s, suffix)
+// are available. Expressions are type-checked when compiled, and evaluation neither loops nor
+// touches the filesystem, so user input can be evaluated safely.
+type Expression struct {
+	source string
+	root   node
+}
+
+// valueKind is the type of an expression value.
+type valueKind int
+
+const (
+	kindBool valueKind = iota
+	kindNumber
+	kindString
+	kindTime
+)
+
+var kindNames = map[valueKind]string{kindBool: "boolean", kindNumber: "number", kindString: "string", kindTime: "time"}
+
+// value holds the result of evaluating a node; only the field of its kind is set.
+type value struct {
+	b bool
+	n float64
+	s string
+	t time.Time
+}
+
+// node is a type-checked part of an expression.
+type node struct {
+	kind valueKind
+	eval func(s *scope) value
+}
+
+// scope is what an expression is evaluated against.
+type scope struct {
+	file   *File
+	keeper *File
+	set    *setStats
+}
+
+// setStats are the set-level aggregates available to expressions.
+type setStats struct {
+	count, size        float64
+	oldest, newest     time.Time
+	minDepth, maxDepth float64
+}
+
+// CompileExpression parses and type-checks an expression, which must be boolean.
+func CompileExpression(source string) (*Expression, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if next := p.peek(); next.kind != tokenEnd {
+		return nil, p.errorf(next, "unexpected %q", next.text)
+	}
+	if root.kind != kindBool {
+		return nil, fmt.Errorf("expression must be a condition, not a %s", kindNames[root.kind])
+	}
+	return &Expression{source: source, root: root}, nil
+}
+
+// String returns the source of the expression.
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Select returns the paths of the files of a duplicate set for which the expression holds,
+// in the order of Files. Keeper is the index of the kept copy in Files.
+func (e *Expression) Select(Files []File, Keeper int) []string {
+	if len(Files) == 0 {
+		return nil
+	}
+	stats := newSetStats(Files)
+	var selected []string
+	for i := range Files {
+		s := scope{file: &Files[i], keeper: &Files[Keeper], set: stats}
+		if e.root.eval(&s).b {
+			selected = append(selected, Files[i].Path)
+		}
+	}
+	return selected
+}
+
+// newSetStats computes the aggregates of a set.
+func newSetStats(files []File) *setStats {
+	stats := &setStats{count: float64(len(files)), size: float64(files[0].Size)}
+	for i, file := range files {
+		depth := float64(components(file.Path))
+		if i == 0 || depth < stats.minDepth {
+			stats.minDepth = depth
+		}
+		if i == 0 || depth > stats.maxDepth {
+			stats.maxDepth = depth
+		}
+		if file.ModTime.IsZero() {
+			continue
+		}
+		if stats.oldest.IsZero() || file.ModTime.Before(stats.oldest) {
+			stats.oldest = file.ModTime
+		}
+		if file.ModTime.After(stats.newest) {
+			stats.newest = file.ModTime
+		}
+	}
+	return stats
+}
+
+// fileVariable reads a value of a single copy.
+type fileVariable struct {
+	kind valueKind
+	get  func(f *File) value
+}
+
+// fileVariables are the values of a single copy, available directly and with the "keeper." prefix.
+var fileVariables = map[string]fileVariable{
+	"path":  {kindString, func(f *File) value { return value{s: f.Path} }},
+	"name":  {kindString, func(f *File) value { return value{s: filepath.Base(f.Path)} }},
+	"dir":   {kindString, func(f *File) value { return value{s: filepath.Dir(f.Path)} }},
+	"ext":   {kindString, func(f *File) value { return value{s: extension(f.Path)} }},
+	"size":  {kindNumber, func(f *File) value { return value{n: float64(f.Size)} }},
+	"mtime": {kindTime, func(f *File) value { return value{t: f.ModTime} }},
+	"depth": {kindNumber, func(f *File) value { return value{n: float64(components(f.Path))} }},
+	"root":  {kindString, func(f *File) value { return value{s: f.Root} }},
+}
+
+// setVariables are the aggregates of the whole set.
+var setVariables = map[string]node{
+	"set.count":    {kindNumber, func(s *scope) value { return value{n: s.set.count} }},
+	"set.size":     {kindNumber, func(s *scope) value { return value{n: s.set.size} }},
+	"set.oldest":   {kindTime, func(s *scope) value { return value{t: s.set.oldest} }},
+	"set.newest":   {kindTime, func(s *scope) value { return value{t: s.set.newest} }},
+	"set.mindepth": {kindNumber, func(s *scope) value { return value{n: s.set.minDepth} }},
+	"set.maxdepth": {kindNumber, func(s *scope) value { return value{n: s.set.maxDepth} }},
+}
+
+// extension returns the lower-case extension of a path without the dot.
+func extension(path string) string {
+	return strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+}
+
+// variable returns the node reading a variable.
+func variable(name string) (node, bool) {
+	if n, ok := setVariables[name]; ok {
+		return n, true
+	}
+	if rest, isKeeper := strings.CutPrefix(name, "keeper."); isKeeper {
+		v, ok := fileVariables[rest]
+		return node{v.kind, func(s *scope) value { return v.get(s.keeper) }}, ok
+	}
+	v, ok := fileVariables[name]
+	return node{v.kind, func(s *scope) value { return v.get(s.file) }}, ok
+}
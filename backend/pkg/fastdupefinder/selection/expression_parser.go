@@ -0,0 +1,409 @@
+package selection
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// tokenKind classifies the tokens of an expression.
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOperator
+)
+
+// token is a lexical element of an expression; offset is its position in the source.
+type token struct {
+	kind   tokenKind
+	text   string
+	offset int
+}
+
+// operators lists the operator tokens, longer ones first so "<=" isn't read as "<".
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "!~", "<", ">", "!", "(", ")", ","}
+
+// sizeSuffixes are the units accepted after a number.
+var sizeSuffixes = map[string]float64{"": 1, "KB": 1 << 10, "MB": 1 << 20, "GB": 1 << 30, "TB": 1 << 40}
+
+// dateLayouts are the formats accepted by date().
+var dateLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"}
+
+// tokenize splits an expression into tokens, ending with a tokenEnd.
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(source); {
+		c := rune(source[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(source) && source[end] != source[i] {
+				if c == '"' && source[end] == '\\' {
+					end++ // Skip the escaped character
+				}
+				end++
+			}
+			if end >= len(source) {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			tokens = append(tokens, token{tokenString, source[i : end+1], i})
+			i = end + 1
+		case c >= '0' && c <= '9':
+			start := i
+			for i < len(source) && (isIdentChar(rune(source[i])) || source[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokenNumber, source[start:i], start})
+		case isIdentChar(c):
+			start := i
+			for i < len(source) && (isIdentChar(rune(source[i])) || source[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokenIdent, source[start:i], start})
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(source[i:], op) {
+					tokens = append(tokens, token{tokenOperator, op, i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at offset %d", c, i)
+			}
+		}
+	}
+	return append(tokens, token{tokenEnd, "end of expression", len(source)}), nil
+}
+
+// isIdentChar reports whether c may be part of an identifier.
+func isIdentChar(c rune) bool {
+	return c == '_' || c < unicode.MaxASCII && (unicode.IsLetter(c) || unicode.IsDigit(c))
+}
+
+// parser builds the nodes of an expression by recursive descent, checking types as it goes.
+// From lowest to highest precedence: ||, &&, !, comparisons, operands.
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// peek returns the next token without consuming it.
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+// next consumes and returns the next token.
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEnd {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the given operator.
+func (p *parser) accept(op string) bool {
+	if t := p.peek(); t.kind == tokenOperator && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// expect consumes the given operator or fails.
+func (p *parser) expect(op string) error {
+	if !p.accept(op) {
+		t := p.peek()
+		return p.errorf(t, "expected %q, found %q", op, t.text)
+	}
+	return nil
+}
+
+// errorf returns an error located at a token.
+func (p *parser) errorf(t token, format string, args ...any) error {
+	return fmt.Errorf("%s at offset %d", fmt.Sprintf(format, args...), t.offset)
+}
+
+func (p *parser) parseOr() (node, error) {
+	return p.parseLogical("||", p.parseAnd, true)
+}
+
+func (p *parser) parseAnd() (node, error) {
+	return p.parseLogical("&&", p.parseNot, false)
+}
+
+// parseLogical parses operands joined by a boolean operator. Like in Go, the right operand is
+// only evaluated if the left one isn't decisive, i.e. doesn't equal decisive.
+func (p *parser) parseLogical(op string, operand func() (node, error), decisive bool) (node, error) {
+	left, err := operand()
+	if err != nil {
+		return node{}, err
+	}
+	for {
+		t := p.peek()
+		if !p.accept(op) {
+			return left, nil
+		}
+		right, err := operand()
+		if err != nil {
+			return node{}, err
+		}
+		if left.kind != kindBool || right.kind != kindBool {
+			return node{}, p.errorf(t, "%s needs conditions on both sides", op)
+		}
+		l, r := left.eval, right.eval
+		left = node{kindBool, func(s *scope) value {
+			if l(s).b == decisive {
+				return value{b: decisive}
+			}
+			return r(s)
+		}}
+	}
+}
+
+func (p *parser) parseNot() (node, error) {
+	t := p.peek()
+	if !p.accept("!") {
+		return p.parseComparison()
+	}
+	operand, err := p.parseNot()
+	if err != nil {
+		return node{}, err
+	}
+	if operand.kind != kindBool {
+		return node{}, p.errorf(t, "! needs a condition")
+	}
+	return node{kindBool, func(s *scope) value { return value{b: !operand.eval(s).b} }}, nil
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return node{}, err
+	}
+	t := p.peek()
+	if t.kind != tokenOperator {
+		return left, nil
+	}
+
+	switch t.text {
+	case "=~", "!~":
+		p.next()
+		patternToken := p.next()
+		if patternToken.kind != tokenString {
+			return node{}, p.errorf(patternToken, "%s needs a string literal pattern", t.text)
+		}
+		source, err := unquote(patternToken.text)
+		if err != nil {
+			return node{}, p.errorf(patternToken, "%s", err.Error())
+		}
+		pattern, err := regexp.Compile(source)
+		if err != nil {
+			return node{}, p.errorf(patternToken, "invalid pattern: %s", err.Error())
+		}
+		if left.kind != kindString {
+			return node{}, p.errorf(t, "%s needs a string on the left, not a %s", t.text, kindNames[left.kind])
+		}
+		want := t.text == "=~"
+		return node{kindBool, func(s *scope) value { return value{b: pattern.MatchString(left.eval(s).s) == want} }}, nil
+
+	case "==", "!=", "<", "<=", ">", ">=":
+		p.next()
+		right, err := p.parseOperand()
+		if err != nil {
+			return node{}, err
+		}
+		if left.kind != right.kind {
+			return node{}, p.errorf(t, "can't compare a %s with a %s", kindNames[left.kind], kindNames[right.kind])
+		}
+		if left.kind == kindBool && t.text != "==" && t.text != "!=" {
+			return node{}, p.errorf(t, "conditions can only be compared with == and !=")
+		}
+		test := comparisonTest(t.text)
+		kind := left.kind
+		return node{kindBool, func(s *scope) value { return value{b: test(compareValues(kind, left.eval(s), right.eval(s)))} }}, nil
+	}
+	return left, nil
+}
+
+// comparisonTest turns the result of compareValues into the outcome of a comparison operator.
+func comparisonTest(op string) func(order int) bool {
+	switch op {
+	case "==":
+		return func(order int) bool { return order == 0 }
+	case "!=":
+		return func(order int) bool { return order != 0 }
+	case "<":
+		return func(order int) bool { return order < 0 }
+	case "<=":
+		return func(order int) bool { return order <= 0 }
+	case ">":
+		return func(order int) bool { return order > 0 }
+	}
+	return func(order int) bool { return order >= 0 }
+}
+
+// compareValues orders two values of the same kind; false is ordered before true.
+func compareValues(kind valueKind, a value, b value) int {
+	switch kind {
+	case kindNumber:
+		switch {
+		case a.n < b.n:
+			return -1
+		case a.n > b.n:
+			return 1
+		}
+		return 0
+	case kindString:
+		return strings.Compare(a.s, b.s)
+	case kindTime:
+		return a.t.Compare(b.t)
+	}
+	return -preferTrue(a.b, b.b)
+}
+
+func (p *parser) parseOperand() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString:
+		text, err := unquote(t.text)
+		if err != nil {
+			return node{}, p.errorf(t, "%s", err.Error())
+		}
+		return constant(kindString, value{s: text}), nil
+
+	case tokenNumber:
+		digits := strings.TrimRightFunc(t.text, unicode.IsLetter)
+		multiplier, ok := sizeSuffixes[strings.ToUpper(t.text[len(digits):])]
+		n, err := strconv.ParseFloat(digits, 64)
+		if !ok || err != nil {
+			return node{}, p.errorf(t, "invalid number %q", t.text)
+		}
+		return constant(kindNumber, value{n: n * multiplier}), nil
+
+	case tokenIdent:
+		switch t.text {
+		case "true", "false":
+			return constant(kindBool, value{b: t.text == "true"}), nil
+		}
+		if p.peek().kind == tokenOperator && p.peek().text == "(" {
+			return p.parseCall(t)
+		}
+		if n, ok := variable(t.text); ok {
+			return n, nil
+		}
+		return node{}, p.errorf(t, "unknown variable %q", t.text)
+
+	case tokenOperator:
+		if t.text == "(" {
+			inner, err := p.parseOr()
+			if err != nil {
+				return node{}, err
+			}
+			return inner, p.expect(")")
+		}
+	}
+	return node{}, p.errorf(t, "unexpected %q", t.text)
+}
+
+// parseCall parses a call of one of the built-in functions.
+func (p *parser) parseCall(name token) (node, error) {
+	p.next() // "("
+	if name.text == "date" {
+		return p.parseDate(name)
+	}
+
+	var args []node
+	var argTokens []token
+	for !p.accept(")") {
+		if len(args) > 0 {
+			if err := p.expect(","); err != nil {
+				return node{}, err
+			}
+		}
+		argTokens = append(argTokens, p.peek())
+		arg, err := p.parseOr()
+		if err != nil {
+			return node{}, err
+		}
+		args = append(args, arg)
+	}
+
+	checkStrings := func(count int) error {
+		if len(args) != count {
+			return p.errorf(name, "%s() takes %d arguments, got %d", name.text, count, len(args))
+		}
+		for i, arg := range args {
+			if arg.kind != kindString {
+				return p.errorf(argTokens[i], "%s() needs string arguments, not a %s", name.text, kindNames[arg.kind])
+			}
+		}
+		return nil
+	}
+
+	switch name.text {
+	case "lower":
+		if err := checkStrings(1); err != nil {
+			return node{}, err
+		}
+		return node{kindString, func(s *scope) value { return value{s: strings.ToLower(args[0].eval(s).s)} }}, nil
+
+	case "contains", "hasprefix", "hassuffix":
+		if err := checkStrings(2); err != nil {
+			return node{}, err
+		}
+		test := map[string]func(s, sub string) bool{
+			"contains":  strings.Contains,
+			"hasprefix": strings.HasPrefix,
+			"hassuffix": strings.HasSuffix,
+		}[name.text]
+		return node{kindBool, func(s *scope) value { return value{b: test(args[0].eval(s).s, args[1].eval(s).s)} }}, nil
+	}
+	return node{}, p.errorf(name, "unknown function %q", name.text)
+}
+
+// parseDate parses the argument of date(), a string literal that is converted to a time once here.
+func (p *parser) parseDate(name token) (node, error) {
+	literal := p.next()
+	if literal.kind != tokenString {
+		return node{}, p.errorf(literal, "%s() needs a string literal", name.text)
+	}
+	if err := p.expect(")"); err != nil {
+		return node{}, err
+	}
+	text, err := unquote(literal.text)
+	if err != nil {
+		return node{}, p.errorf(literal, "%s", err.Error())
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, text, time.Local); err == nil {
+			return constant(kindTime, value{t: t}), nil
+		}
+	}
+	return node{}, p.errorf(literal, "invalid date %q, expected e.g. \"2006-01-02\" or \"2006-01-02 15:04\"", text)
+}
+
+// constant returns a node that always evaluates to v.
+func constant(kind valueKind, v value) node {
+	return node{kind, func(*scope) value { return v }}
+}
+
+// unquote returns the content of a "string" with Go escapes or a 'raw string'.
+func unquote(literal string) (string, error) {
+	if literal[0] == '\'' {
+		return literal[1 : len(literal)-1], nil
+	}
+	return strconv.Unquote(literal)
+}
@@ -0,0 +1,72 @@
+package selection
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpressionSelect(t *testing.T) {
+	files := []File{
+		{Path: "/home/me/photos/a.JPG", Size: 2 << 20, ModTime: time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local), Root: "photos"},
+		{Path: "/home/me/Downloads/a.jpg", Size: 2 << 20, ModTime: time.Date(2023, 6, 1, 0, 0, 0, 0, time.Local), Root: "home"},
+		{Path: "/home/me/Downloads/old/a (1).jpg", Size: 2 << 20, ModTime: time.Date(2019, 1, 1, 0, 0, 0, 0, time.Local), Root: "home"},
+	}
+	tests := []struct {
+		expression string
+		want       []string
+	}{
+		{`path =~ "/Downloads/"`, []string{files[1].Path, files[2].Path}},
+		{`path !~ '/Downloads/'`, []string{files[0].Path}},
+		{`path =~ "/Downloads/" && mtime > keeper.mtime`, []string{files[1].Path}},
+		{`!(root == "photos") || depth > 5`, []string{files[1].Path, files[2].Path}},
+		{`ext == "jpg" && name != "a.jpg"`, []string{files[0].Path, files[2].Path}},
+		{`size >= 2MB && set.size < 3MB && set.count == 3`, []string{files[0].Path, files[1].Path, files[2].Path}},
+		{`mtime == set.oldest`, []string{files[2].Path}},
+		{`mtime < date("2020-01-01 00:01")`, []string{files[0].Path, files[2].Path}},
+		{`depth == set.maxdepth`, []string{files[2].Path}},
+		{`contains(lower(name), "(1)") || hasprefix(dir, keeper.dir)`, []string{files[0].Path, files[2].Path}},
+		{`hassuffix(path, ".JPG") == true`, []string{files[0].Path}},
+		{`false || 1 < 2 && 2 < 1`, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			expression, err := CompileExpression(test.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := expression.Select(files, 0); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("selected %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCompileExpressionErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		wantErr    string // Part of the error message
+	}{
+		{`size`, "must be a condition"},
+		{`path == 1`, "number"},
+		{`path =~ name`, "literal"},
+		{`path =~ "("`, "missing closing )"},
+		{`owner == "me"`, `unknown variable "owner"`},
+		{`upper(name) == "A"`, `unknown function "upper"`},
+		{`contains(path)`, "takes 2 arguments"},
+		{`mtime > date("yesterday")`, "invalid date"},
+		{`size > 2XB`, "invalid number"},
+		{`(path == "a"`, `")"`},
+		{`path == "a" path`, "unexpected"},
+		{`path == "unterminated`, "unterminated"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			_, err := CompileExpression(test.expression)
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("CompileExpression returned %v, want an error containing %q", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+// Package selection decides which copy of a duplicate set is kept. A Policy ranks the copies
+// by ordered rules, each breaking the ties left by the rules before it; the best ranked copy is
+// kept and the others are marked for removal.
+package selection
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RuleKind is the criterion a Rule ranks copies by.
+type RuleKind int
+
+const (
+	// RuleOldest prefers the copy with the oldest modification time.
+	RuleOldest RuleKind = iota
+	// RuleNewest prefers the copy with the newest modification time.
+	RuleNewest
+	// RuleShortestPath prefers the copy with the shortest path.
+	RuleShortestPath
+	// RuleLongestPath prefers the copy with the longest path.
+	RuleLongestPath
+	// RuleFewestComponents prefers the copy with the fewest path components, i.e. the least nested one.
+	RuleFewestComponents
+	// RulePrefer prefers copies inside the folder given by Prefix.
+	RulePrefer
+	// RuleMatch prefers copies whose path matches Pattern.
+	RuleMatch
+)
+
+// ruleNames are the names of the rules without argument, as accepted by ParseRule.
+var ruleNames = map[string]RuleKind{
+	"oldest":   RuleOldest,
+	"newest":   RuleNewest,
+	"shortest": RuleShortestPath,
+	"longest":  RuleLongestPath,
+	"shallow":  RuleFewestComponents,
+}
+
+// Rule is a single ranking criterion of a Policy.
+type Rule struct {
+	Kind    RuleKind
+	Prefix  string         // Folder preferred by RulePrefer
+	Pattern *regexp.Regexp // Expression matched by RuleMatch
+}
+
+// Policy is an ordered list of rules. Copies that all rules rank equal are ordered by path,
+// so the decision never depends on the order of the scan.
+type Policy []Rule
+
+// Candidate is a copy of a duplicate set with the details the rules rank it by.
+type Candidate struct {
+	Path    string
+	ModTime time.Time // Zero if unknown; such copies rank last on the time rules
+}
+
+// ParseRule parses a rule written as "oldest", "newest", "shortest", "longest", "shallow",
+// "prefer=DIR" or "match=REGEX".
+func ParseRule(spec string) (Rule, error) {
+	name, value, hasValue := strings.Cut(spec, "=")
+	if kind, ok := ruleNames[name]; ok && !hasValue {
+		return Rule{Kind: kind}, nil
+	}
+
+	switch {
+	case name == "prefer" && value != "":
+		return Rule{Kind: RulePrefer, Prefix: filepath.Clean(value)}, nil
+	case name == "match" && value != "":
+		pattern, err := regexp.Compile(value)
+		if err != nil {
+			return Rule{}, fmt.Errorf("invalid keep rule %q: %w", spec, err)
+		}
+		return Rule{Kind: RuleMatch, Pattern: pattern}, nil
+	}
+	return Rule{}, fmt.Errorf("unknown keep rule %q", spec)
+}
+
+// ParsePolicy parses the rules of a policy, in order of priority.
+func ParsePolicy(Specs []string) (Policy, error) {
+	policy := make(Policy, 0, len(Specs))
+	for _, spec := range Specs {
+		rule, err := ParseRule(spec)
+		if err != nil {
+			return nil, err
+		}
+		policy = append(policy, rule)
+	}
+	return policy, nil
+}
+
+// String returns the rule in the form accepted by ParseRule.
+func (r Rule) String() string {
+	switch r.Kind {
+	case RulePrefer:
+		return "prefer=" + r.Prefix
+	case RuleMatch:
+		return "match=" + r.Pattern.String()
+	}
+	for name, kind := range ruleNames {
+		if kind == r.Kind {
+			return name
+		}
+	}
+	return fmt.Sprintf("rule(%d)", int(r.Kind))
+}
+
+// MarshalText writes the rule in the form accepted by ParseRule, so policies can be part of JSON options.
+func (r Rule) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText parses a rule with ParseRule.
+func (r *Rule) UnmarshalText(text []byte) error {
+	rule, err := ParseRule(string(text))
+	if err != nil {
+		return err
+	}
+	*r = rule
+	return nil
+}
+
+// Rank returns the candidates ordered from the copy to keep to the least preferred one.
+// The candidates are not modified.
+func (p Policy) Rank(Candidates []Candidate) []Candidate {
+	ranked := append([]Candidate(nil), Candidates...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		for _, rule := range p {
+			if order := rule.compare(ranked[i], ranked[j]); order != 0 {
+				return order < 0
+			}
+		}
+		return ranked[i].Path < ranked[j].Path
+	})
+	return ranked
+}
+
+// compare returns a negative number if the rule prefers a, a positive one if it prefers b and
+// zero if it ranks them equal.
+func (r Rule) compare(a Candidate, b Candidate) int {
+	switch r.Kind {
+	case RuleOldest:
+		return compareTimes(a.ModTime, b.ModTime, false)
+	case RuleNewest:
+		return compareTimes(a.ModTime, b.ModTime, true)
+	case RuleShortestPath:
+		return len(a.Path) - len(b.Path)
+	case RuleLongestPath:
+		return len(b.Path) - len(a.Path)
+	case RuleFewestComponents:
+		return components(a.Path) - components(b.Path)
+	case RulePrefer:
+		return preferTrue(isInside(a.Path, r.Prefix), isInside(b.Path, r.Prefix))
+	case RuleMatch:
+		return preferTrue(r.Pattern.MatchString(a.Path), r.Pattern.MatchString(b.Path))
+	}
+	return 0
+}
+
+// compareTimes orders earlier times first, or later ones if newestFirst is set.
+// Unknown (zero) times always rank last.
+func compareTimes(a time.Time, b time.Time, newestFirst bool) int {
+	if a.IsZero() || b.IsZero() {
+		return preferTrue(!a.IsZero(), !b.IsZero())
+	}
+	if newestFirst {
+		a, b = b, a
+	}
+	return a.Compare(b)
+}
+
+// preferTrue ranks a candidate for which the condition holds before one for which it doesn't.
+func preferTrue(a bool, b bool) int {
+	switch {
+	case a && !b:
+		return -1
+	case b && !a:
+		return 1
+	}
+	return 0
+}
+
+// components returns the number of elements of a path.
+func components(path string) int {
+	return len(strings.Split(strings.Trim(filepath.ToSlash(filepath.Clean(path)), "/"), "/"))
+}
+
+// isInside reports whether path is dir or lies below it.
+func isInside(path string, dir string) bool {
+	rel, err := filepath.Rel(dir, filepath.Clean(path))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,10 @@
+package sources
+
+import "os"
+
+// Dir returns a Source for the directory tree rooted at Root, backed by os.DirFS.
+// Symbolic links are not followed while walking, and files carry their device and inode numbers
+// on platforms that have them.
+func Dir(Root string) Source {
+	return New(os.DirFS(Root))
+}
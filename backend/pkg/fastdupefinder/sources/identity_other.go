@@ -0,0 +1,10 @@
+//go:build !unix
+
+package sources
+
+import "io/fs"
+
+// fileIdentity reports that no file identities are available on this platform.
+func fileIdentity(info fs.FileInfo) (uint64, uint64, bool) {
+	return 0, 0, false
+}
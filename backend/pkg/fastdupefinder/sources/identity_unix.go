@@ -0,0 +1,17 @@
+//go:build unix
+
+package sources
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// fileIdentity returns the device and inode numbers of a file stat'ed by the operating system.
+func fileIdentity(info fs.FileInfo) (uint64, uint64, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return uint64(stat.Dev), uint64(stat.Ino), true
+}
@@ -0,0 +1,85 @@
+// Package sources abstracts the file trees a scan can run over. A Source is an io/fs.FS
+// extended with file identities and offset reads, so local directories (Dir), zip files (Zip)
+// and any other fs.FS such as testing/fstest.MapFS (New) can be scanned the same way.
+package sources
+
+import (
+	"io"
+	"io/fs"
+	"time"
+)
+
+// Source is a read-only file tree the duplicate finder can scan.
+// Names are slash-separated and unrooted, as defined by io/fs.
+type Source interface {
+	fs.FS
+
+	// StatFile returns information about a file, following symbolic links.
+	StatFile(Name string) (FileStat, error)
+
+	// OpenAt opens a file for reading, positioned Offset bytes into its content.
+	OpenAt(Name string, Offset int64) (io.ReadCloser, error)
+}
+
+// FileStat describes a file of a Source.
+type FileStat struct {
+	Size    int64
+	Mode    fs.FileMode
+	ModTime time.Time
+
+	// Device and Inode identify the file on disk, e.g. to recognise hard links.
+	// They are only meaningful when HasIdentity is set.
+	Device      uint64
+	Inode       uint64
+	HasIdentity bool
+}
+
+// New wraps any fs.FS as a Source. Files are positioned by seeking when they implement
+// io.Seeker and by reading and discarding their leading bytes otherwise.
+// File identities are available when the file infos of fsys come from the operating system.
+func New(fsys fs.FS) Source {
+	if source, ok := fsys.(Source); ok {
+		return source
+	}
+	return fsSource{fsys}
+}
+
+// fsSource adapts an fs.FS to the Source interface.
+type fsSource struct {
+	fs.FS
+}
+
+func (s fsSource) StatFile(Name string) (FileStat, error) {
+	info, err := fs.Stat(s.FS, Name)
+	if err != nil {
+		return FileStat{}, err
+	}
+	stat := FileStat{Size: info.Size(), Mode: info.Mode(), ModTime: info.ModTime()}
+	stat.Device, stat.Inode, stat.HasIdentity = fileIdentity(info)
+	return stat, nil
+}
+
+func (s fsSource) OpenAt(Name string, Offset int64) (io.ReadCloser, error) {
+	file, err := s.Open(Name)
+	if err != nil {
+		return nil, err
+	}
+	if Offset <= 0 {
+		return file, nil
+	}
+
+	if seeker, ok := file.(io.Seeker); ok {
+		_, err = seeker.Seek(Offset, io.SeekStart)
+	} else {
+		// Offsets beyond the end leave the file at EOF, as seeking does.
+		_, err = io.CopyN(io.Discard, file, Offset)
+		if err == io.EOF {
+			err = nil
+		}
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
@@ -0,0 +1,24 @@
+package sources
+
+import "archive/zip"
+
+// ZipSource is a Source for the members of a zip file. It must be closed after the scan.
+type ZipSource struct {
+	Source
+	reader *zip.ReadCloser
+}
+
+// Zip opens a zip file as a Source. Members are decompressed as they are read, so
+// offsets are reached by reading and discarding the leading bytes.
+func Zip(ArchivePath string) (*ZipSource, error) {
+	reader, err := zip.OpenReader(ArchivePath)
+	if err != nil {
+		return nil, err
+	}
+	return &ZipSource{Source: New(reader), reader: reader}, nil
+}
+
+// Close closes the underlying zip file.
+func (s *ZipSource) Close() error {
+	return s.reader.Close()
+}
@@ -0,0 +1,16 @@
+package types
+
+// ChunkAnalysis summarises the content-defined chunks shared by large files.
+type ChunkAnalysis struct {
+	FilesAnalysed int
+	TotalBytes    int64 // Bytes of all analysed files
+	UniqueBytes   int64 // Bytes left when every distinct chunk is stored once
+	SharedPairs   []SharedChunkPair
+}
+
+// SharedChunkPair holds two files that have a significant amount of chunks in common.
+type SharedChunkPair struct {
+	PathA       string
+	PathB       string
+	SharedBytes int64 // Bytes of the distinct chunks found in both files
+}
@@ -0,0 +1,18 @@
+package types
+
+// Content categories group files that hold the same content once format-specific
+// noise such as metadata is ignored, even though their bytes differ.
+const (
+	ContentCategoryJPEGMetadata   = "jpeg-metadata"
+	ContentCategoryAudioTags      = "audio-tags"
+	ContentCategoryNormalisedText = "text-normalised"
+	ContentCategoryDocuments      = "documents"
+)
+
+// ContentCategoryDescriptions holds a human-readable description for every content category.
+var ContentCategoryDescriptions = map[string]string{
+	ContentCategoryJPEGMetadata:   "same image, different metadata",
+	ContentCategoryAudioTags:      "same audio, different tags",
+	ContentCategoryNormalisedText: "same text, different BOM, line endings or trailing whitespace",
+	ContentCategoryDocuments:      "content-identical documents",
+}
@@ -0,0 +1,104 @@
+package types
+
+import "path/filepath"
+
+// DirNode holds the recorded contents of a single directory.
+type DirNode struct {
+	Files map[string]int64 // File name -> size in bytes
+	Dirs  []string         // Names of direct subdirectories
+
+	// Incomplete is set when the directory could not be read in full or holds
+	// entries that are neither regular files nor directories (symlinks, sockets...).
+	// Such a directory can never be reported as a duplicate.
+	Incomplete bool
+
+	linked bool // Set once AddDir has listed the directory in its parent
+}
+
+// DirTree is an in-memory snapshot of the directory structure recorded during Phase 1.
+// Later phases read from it instead of touching the filesystem again, so the whole
+// scan reflects a single point in time.
+// It is not safe for concurrent writes; once Phase 1 returns it is only read.
+type DirTree struct {
+	Nodes map[string]*DirNode // Keyed by directory path
+}
+
+// NewDirTree creates an empty directory snapshot.
+func NewDirTree() *DirTree {
+	return &DirTree{Nodes: make(map[string]*DirNode)}
+}
+
+// node returns the node for a directory, creating it if needed.
+func (t *DirTree) node(DirPath string) *DirNode {
+	n, ok := t.Nodes[DirPath]
+	if !ok {
+		n = &DirNode{Files: make(map[string]int64)}
+		t.Nodes[DirPath] = n
+	}
+	return n
+}
+
+// AddDir records a directory and links it to its parent, also when MarkIncomplete recorded it first.
+func (t *DirTree) AddDir(DirPath string) {
+	n := t.node(DirPath)
+	if n.linked {
+		return
+	}
+	n.linked = true
+	parent := filepath.Dir(DirPath)
+	if parent != DirPath {
+		if p, ok := t.Nodes[parent]; ok {
+			p.Dirs = append(p.Dirs, filepath.Base(DirPath))
+		}
+	}
+}
+
+// AddFile records a regular file and its size in its parent directory.
+func (t *DirTree) AddFile(FilePath string, Size int64) {
+	t.node(filepath.Dir(FilePath)).Files[filepath.Base(FilePath)] = Size
+}
+
+// MarkIncomplete flags a directory as not fully known.
+func (t *DirTree) MarkIncomplete(DirPath string) {
+	t.node(DirPath).Incomplete = true
+}
+
+// Get returns the recorded node for a directory.
+func (t *DirTree) Get(DirPath string) (*DirNode, bool) {
+	n, ok := t.Nodes[DirPath]
+	return n, ok
+}
+
+// DirSummary aggregates the contents of a directory and all of its subdirectories.
+type DirSummary struct {
+	FileCount int
+	TotalSize int64
+	Complete  bool // False if any directory in the subtree is incomplete or missing
+}
+
+// Summarize computes the recursive summary of a directory.
+// Results for every visited directory are memoised in Cache, which must not be shared between goroutines.
+func (t *DirTree) Summarize(DirPath string, Cache map[string]DirSummary) DirSummary {
+	if summary, ok := Cache[DirPath]; ok {
+		return summary
+	}
+
+	node, ok := t.Nodes[DirPath]
+	if !ok {
+		return DirSummary{}
+	}
+
+	summary := DirSummary{FileCount: len(node.Files), Complete: !node.Incomplete}
+	for _, size := range node.Files {
+		summary.TotalSize += size
+	}
+	for _, name := range node.Dirs {
+		child := t.Summarize(filepath.Join(DirPath, name), Cache)
+		summary.FileCount += child.FileCount
+		summary.TotalSize += child.TotalSize
+		summary.Complete = summary.Complete && child.Complete
+	}
+
+	Cache[DirPath] = summary
+	return summary
+}
@@ -0,0 +1,20 @@
+package types
+
+import "testing"
+
+func TestDirTreeLinksDirectoriesMarkedIncompleteFirst(t *testing.T) {
+	tree := NewDirTree()
+	tree.AddDir("/root")
+	tree.AddFile("/root/a.txt", 3)
+	tree.MarkIncomplete("/root/unreadable")
+	tree.AddDir("/root/unreadable")
+	tree.AddDir("/root/unreadable") // Walkers may report a directory twice
+
+	node, _ := tree.Get("/root")
+	if len(node.Dirs) != 1 || node.Dirs[0] != "unreadable" {
+		t.Fatalf("parent lists %v, want [unreadable]", node.Dirs)
+	}
+	if summary := tree.Summarize("/root", make(map[string]DirSummary)); summary.Complete {
+		t.Errorf("parent of an unreadable directory is summarised as complete")
+	}
+}
@@ -0,0 +1,9 @@
+package types
+
+// ImageFingerprint holds the perceptual hash and resolution of a decoded image.
+type ImageFingerprint struct {
+	Path   string
+	Width  int
+	Height int
+	PHash  uint64 // DCT-based hash of a 32x32 grayscale thumbnail
+}
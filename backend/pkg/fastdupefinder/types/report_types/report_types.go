@@ -1,5 +1,7 @@
 package reporttypes
 
+import "time"
+
 // --- JSON Output Structures ---
 
 // ReportOutput is the top-level structure for the final JSON report.
@@ -8,6 +10,15 @@ type ReportOutput struct {
 	Summary          SummaryInfo `json:"summary"`
 	FileDuplicates   []FileSet   `json:"fileDuplicates"`
 	FolderDuplicates []FolderSet `json:"folderDuplicates"`
+
+	// Optional sections, only present when the matching analysis was enabled.
+	ArchiveDuplicates     []ArchiveSet      `json:"archiveDuplicates,omitempty"`
+	CompressedEquivalents []CompressedSet   `json:"compressedEquivalents,omitempty"`
+	SimilarImages         []SimilarImageSet `json:"similarImages,omitempty"`
+	SimilarTexts          []SimilarTextPair `json:"similarTexts,omitempty"`
+	ContentDuplicates     []ContentSet      `json:"contentDuplicates,omitempty"`
+	TruncatedCopies       []TruncatedCopy   `json:"truncatedCopies,omitempty"`
+	SharedChunks          *ChunkAnalysis    `json:"sharedChunks,omitempty"`
 }
 
 // SummaryInfo provides essential counts of the findings.
@@ -16,6 +27,18 @@ type SummaryInfo struct {
 	FileSets         int   `json:"fileSets"`         // Number of duplicate file sets found
 	FolderSets       int   `json:"folderSets"`       // Number of duplicate folder sets found
 	WastedSpaceBytes int64 `json:"wastedSpaceBytes"` // Total wasted space in bytes
+
+	ArchiveSets      int `json:"archiveSets,omitempty"`      // Number of archives duplicating a folder
+	CompressedSets   int `json:"compressedSets,omitempty"`   // Number of compressed equivalent sets
+	SimilarImageSets int `json:"similarImageSets,omitempty"` // Number of similar image clusters
+	SimilarTextPairs int `json:"similarTextPairs,omitempty"` // Number of near-duplicate text pairs
+	ContentSets      int `json:"contentSets,omitempty"`      // Number of content-identical sets
+	TruncatedCopies  int `json:"truncatedCopies,omitempty"`  // Number of files that are a prefix of a larger file
+	SharedChunkPairs int `json:"sharedChunkPairs,omitempty"` // Number of file pairs sharing large parts of their content
+	SuppressedSets   int `json:"suppressedSets,omitempty"`   // Number of sets left out because the ignore database lists them
+
+	// ScanStartedAt is when the scan began; files modified later may no longer match the report.
+	ScanStartedAt *time.Time `json:"scanStartedAt,omitempty"`
 }
 
 // FileSet represents a single group of identical files.
@@ -24,12 +47,132 @@ type FileSet struct {
 	Hash      string   `json:"hash"`      // Truncated to 12 characters
 	Paths     []string `json:"paths"`     // Full paths to duplicate files
 	SizeBytes int64    `json:"sizeBytes"` // Size of each file in bytes
+
+	// VirtualPaths lists the entries of Paths that are members inside an archive
+	// (e.g. "archive.zip!/dir/file.txt"). They don't exist on disk and must never be deleted.
+	VirtualPaths []string `json:"virtualPaths,omitempty"`
+
+	// RemotePaths lists the entries of Paths that are objects in an S3-compatible store
+	// (e.g. "s3://bucket/dir/file.txt"). They are never deleted by local file operations.
+	RemotePaths []string `json:"remotePaths,omitempty"`
+
+	// Marks tells for every path whether it is kept or removed; see MarkKeep and MarkRemove.
+	// Only set when the report was generated with a keep policy, which also lists the kept copy first.
+	Marks map[string]string `json:"marks,omitempty"`
 }
 
 // FolderSet represents a single group of identical folders.
 // Signature truncated to 12 characters to save memory.
 type FolderSet struct {
-	Signature string   `json:"signature"` // Truncated to 12 characters
-	Paths     []string `json:"paths"`     // Full paths to duplicate folders
-	SizeBytes int64    `json:"sizeBytes"` // Size of each folder in bytes
+	Signature string            `json:"signature"`        // Truncated to 12 characters
+	Paths     []string          `json:"paths"`            // Full paths to duplicate folders
+	SizeBytes int64             `json:"sizeBytes"`        // Size of each folder in bytes
+	Nested    *NestedDuplicates `json:"nested,omitempty"` // Only set when the nested tree is requested
+
+	// Marks tells for every path whether it is kept or removed, like FileSet.Marks.
+	Marks map[string]string `json:"marks,omitempty"`
+}
+
+// Values of the Marks of a duplicate set.
+const (
+	MarkKeep   = "keep"   // The copy is kept
+	MarkRemove = "remove" // The copy is redundant and may be removed
+)
+
+// NestedDuplicates lists the duplicate sets found inside a duplicate folder set
+// that Phase 5 removed from the top level. Folder sets carry their own nested
+// duplicates, forming a tree rooted at each top-level FolderSet.
+type NestedDuplicates struct {
+	FolderDuplicates []FolderSet `json:"folderDuplicates,omitempty"`
+	FileDuplicates   []FileSet   `json:"fileDuplicates,omitempty"`
+}
+
+// ArchiveSet represents an archive whose content equals one or more folders.
+// The archive is redundant next to its extracted copy, so its size can be reclaimed.
+type ArchiveSet struct {
+	ArchivePath      string   `json:"archivePath"`      // Path to the zip or tar(.gz) file
+	FolderPaths      []string `json:"folderPaths"`      // Folders holding the same content
+	ReclaimableBytes int64    `json:"reclaimableBytes"` // Size of the archive file in bytes
+}
+
+// CompressedSet represents files holding the same bytes once decompressed,
+// where at least one copy is a gzip, bzip2 or zlib file.
+type CompressedSet struct {
+	Hash                  string   `json:"hash"`                  // Hash of the decompressed content, truncated to 12 characters
+	Paths                 []string `json:"paths"`                 // Plain files with this content
+	CompressedPaths       []string `json:"compressedPaths"`       // Compressed files with this content
+	DecompressedSizeBytes int64    `json:"decompressedSizeBytes"` // Size of the content in bytes
+	WastedBytes           int64    `json:"wastedBytes"`           // Compressed bytes that could be removed
+}
+
+// SimilarImageSet represents images that look alike but are not byte-identical,
+// such as re-encoded or resized copies of the same picture.
+type SimilarImageSet struct {
+	Images      []SimilarImage `json:"images"`      // Largest resolution first
+	MaxDistance int            `json:"maxDistance"` // Largest distance to the first image
+}
+
+// SimilarImage describes one image of a SimilarImageSet.
+type SimilarImage struct {
+	Path      string `json:"path"`
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	SizeBytes int64  `json:"sizeBytes"`
+	Distance  int    `json:"distance"` // pHash Hamming distance (0-64) to the first image of the set
+}
+
+// SimilarTextPair represents two text files that differ by only part of their content,
+// such as copied source files or edited documents.
+type SimilarTextPair struct {
+	Paths      [2]string `json:"paths"`
+	SizeBytes  [2]int64  `json:"sizeBytes"`  // Sizes of both files, in the order of Paths
+	Similarity float64   `json:"similarity"` // Estimated share of common text (0-1)
+}
+
+// ContentSet represents files that hold the same content once format-specific noise
+// (e.g. JPEG metadata) is ignored, but that are not byte-identical.
+type ContentSet struct {
+	Category    string        `json:"category"`    // Content category, e.g. "jpeg-metadata"
+	Description string        `json:"description"` // Human-readable description of the category
+	Hash        string        `json:"hash"`        // Normalised content hash, truncated to 12 characters
+	Files       []ContentFile `json:"files"`
+
+	// DifferingTags lists the tag fields whose values differ between the files of an
+	// "audio-tags" set, e.g. "title" or "date". Each file carries its values of these fields.
+	DifferingTags []string `json:"differingTags,omitempty"`
+}
+
+// ContentFile describes one file of a ContentSet. Sizes may differ within a set.
+type ContentFile struct {
+	Path      string            `json:"path"`
+	SizeBytes int64             `json:"sizeBytes"`
+	Tags      map[string]string `json:"tags,omitempty"` // Values of the set's DifferingTags
+}
+
+// TruncatedCopy represents a file whose content is a strict prefix of a larger file,
+// such as an interrupted download. It can usually be removed once the complete file exists.
+type TruncatedCopy struct {
+	Path              string  `json:"path"`
+	SizeBytes         int64   `json:"sizeBytes"`
+	CompletePath      string  `json:"completePath"`      // Larger file starting with the same bytes
+	CompleteSizeBytes int64   `json:"completeSizeBytes"` // Size of the complete file in bytes
+	PercentComplete   float64 `json:"percentComplete"`   // SizeBytes relative to CompleteSizeBytes (0-100)
+}
+
+// ChunkAnalysis estimates what a deduplicating filesystem or backup tool would save on the
+// analysed large files by storing every distinct content-defined chunk once.
+type ChunkAnalysis struct {
+	FilesAnalysed         int               `json:"filesAnalysed"`
+	TotalBytes            int64             `json:"totalBytes"`            // Size of all analysed files
+	UniqueBytes           int64             `json:"uniqueBytes"`           // Size of the distinct chunks
+	EstimatedSavingsBytes int64             `json:"estimatedSavingsBytes"` // TotalBytes - UniqueBytes
+	SharedPairs           []SharedChunkPair `json:"sharedPairs"`
+}
+
+// SharedChunkPair represents two files sharing a significant part of their content.
+type SharedChunkPair struct {
+	Paths         [2]string `json:"paths"`
+	SizeBytes     [2]int64  `json:"sizeBytes"`     // Sizes of both files, in the order of Paths
+	SharedBytes   int64     `json:"sharedBytes"`   // Bytes of the chunks found in both files
+	SharedPercent float64   `json:"sharedPercent"` // SharedBytes relative to the smaller file (0-100)
 }
@@ -0,0 +1,47 @@
+package types
+
+import "time"
+
+// ScanResults holds everything produced by a single run of the duplicate finder.
+type ScanResults struct {
+	// Duplicate maps as returned by Phase 5 (filtered) and Phases 3 and 4 (all).
+	// Keys are content hashes for files and folder signatures for folders.
+	FilteredFileDuplicates   map[string][]string
+	FilteredFolderDuplicates map[string][]string
+	AllFileDuplicates        map[string][]string
+	AllFolderDuplicates      map[string][]string
+
+	// ArchiveFolderDuplicates maps an archive path to the folders holding the same content.
+	// Only filled when archive folder detection is enabled.
+	ArchiveFolderDuplicates map[string][]string
+
+	// CompressedEquivalents groups compressed files with files holding the same decompressed bytes.
+	// Keys are "size-hash" of the decompressed content. Only filled when decompressed comparison is enabled.
+	CompressedEquivalents map[string][]string
+
+	// SimilarImages holds clusters of images that look alike, largest resolution first.
+	// Only filled when similar image detection is enabled.
+	SimilarImages [][]ImageFingerprint
+
+	// SimilarTexts holds pairs of text files with largely the same content, most similar first.
+	// Only filled when similar text detection is enabled.
+	SimilarTexts []SimilarTextPair
+
+	// TruncatedCopies lists files that are a strict prefix of a larger file.
+	// Only filled when truncated copy detection is enabled.
+	TruncatedCopies []TruncatedCopy
+
+	// SharedChunks describes content shared at block level between large files.
+	// Only filled when chunk analysis is enabled.
+	SharedChunks *ChunkAnalysis
+
+	// ContentDuplicates groups files that hold the same content once format-specific noise is ignored.
+	// It maps a content category (see ContentCategoryDescriptions) to normalised hashes and their paths.
+	ContentDuplicates map[string]map[string][]string
+
+	// Tree is the directory snapshot recorded in Phase 1.
+	Tree *DirTree
+
+	// StartedAt is when Phase 1 began. Files modified later may differ from what was hashed.
+	StartedAt time.Time
+}
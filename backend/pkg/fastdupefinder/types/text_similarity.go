@@ -0,0 +1,8 @@
+package types
+
+// SimilarTextPair holds two text files whose content is largely the same.
+type SimilarTextPair struct {
+	PathA      string
+	PathB      string
+	Similarity float64 // Estimated Jaccard similarity of the shingle sets (0-1)
+}
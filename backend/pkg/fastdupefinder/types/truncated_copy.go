@@ -0,0 +1,10 @@
+package types
+
+// TruncatedCopy describes a file whose content is a strict prefix of a larger file,
+// such as an interrupted download or a half-finished copy.
+type TruncatedCopy struct {
+	Path         string
+	Size         int64
+	CompletePath string
+	CompleteSize int64
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/actions"
+)
+
+// runQuarantineCommand moves duplicate files listed in a report into a quarantine folder.
+func runQuarantineCommand(args []string) int {
+	var reportPath string
+	var paths []string
+	var options actions.QuarantineOptions
+
+	for _, arg := range args {
+		if name, value, found := strings.Cut(arg, "="); found && strings.HasPrefix(name, "--") {
+			switch name {
+			case "--report":
+				reportPath = value
+			case "--to":
+				options.Directory = value
+			default:
+				fmt.Fprintf(os.Stderr, "Error: unknown option %s\n", name)
+				return 1
+			}
+			continue
+		}
+
+		switch arg {
+		case "--dry-run", "-n":
+			options.DryRun = true
+		case "--help", "-h":
+			printQuarantineUsage()
+			return 0
+		default:
+			if strings.HasPrefix(arg, "-") {
+				fmt.Fprintf(os.Stderr, "Error: unknown option %s\n", arg)
+				return 1
+			}
+			paths = append(paths, arg)
+		}
+	}
+
+	if reportPath == "" || options.Directory == "" {
+		printQuarantineUsage()
+		return 1
+	}
+
+	if !options.DryRun && !enableAuditLog() {
+		return 1
+	}
+	report, err := loadReport(reportPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+		return 1
+	}
+
+	if len(paths) == 0 {
+		paths = actions.PathsToRemove(report)
+	}
+
+	manifestPath, outcomes, err := actions.QuarantineFromReport(report, paths, options)
+	exitCode := printOutcomes(outcomes, options.DryRun, "Would quarantine", "Quarantined")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+		return 1
+	}
+	if manifestPath != "" {
+		fmt.Printf("\nManifest: %s\nUndo with: %s restore %s\n", manifestPath, os.Args[0], manifestPath)
+	}
+	return exitCode
+}
+
+// runRestoreCommand moves the files of a quarantine manifest back to their original paths.
+func runRestoreCommand(args []string) int {
+	var manifestPath string
+	dryRun := false
+
+	for _, arg := range args {
+		switch arg {
+		case "--dry-run", "-n":
+			dryRun = true
+		case "--help", "-h":
+			printRestoreUsage()
+			return 0
+		default:
+			if strings.HasPrefix(arg, "-") || manifestPath != "" {
+				fmt.Fprintf(os.Stderr, "Error: unexpected argument %s\n", arg)
+				return 1
+			}
+			manifestPath = arg
+		}
+	}
+
+	if manifestPath == "" {
+		printRestoreUsage()
+		return 1
+	}
+
+	if !dryRun && !enableAuditLog() {
+		return 1
+	}
+	outcomes, err := actions.Restore(manifestPath, dryRun)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+		return 1
+	}
+	return printOutcomes(outcomes, dryRun, "Would restore", "Restored")
+}
+
+// printOutcomes prints one line per moved path and returns 1 if any path failed.
+func printOutcomes(outcomes []actions.Outcome, dryRun bool, dryRunVerb string, verb string) int {
+	exitCode := 0
+	for _, outcome := range outcomes {
+		switch {
+		case outcome.Err != nil:
+			fmt.Printf("Skipped %s: %s\n", outcome.Path, outcome.Err.Error())
+			exitCode = 1
+		case dryRun:
+			fmt.Printf("%s: %s -> %s\n", dryRunVerb, outcome.Path, outcome.Destination)
+		default:
+			fmt.Printf("%s: %s -> %s\n", verb, outcome.Path, outcome.Destination)
+		}
+	}
+	return exitCode
+}
+
+func printQuarantineUsage() {
+	fmt.Printf(`Usage: %s quarantine --report=FILE --to=DIR [OPTIONS] [<path>...]
+
+Moves duplicate files listed in a report written with --json into DIR, below their
+original absolute path. Before each move, the file's original path, hash, size,
+modification time and mode are added to a manifest in DIR, so the moves can be undone
+with restore even if the command is interrupted. Without paths, the copies the report
+marks for removal (see --keep) are moved, or every copy but the first of sets without
+marks. At least one copy of each set is always kept in place, and sets whose copies
+changed since the scan are skipped.
+
+OPTIONS:
+      --report=FILE  Report of the scan ("-" reads it from standard input)
+      --to=DIR       Quarantine folder
+  -n, --dry-run      Show what would be moved without changing anything
+  -h, --help         Show this help message
+`, os.Args[0])
+}
+
+func printRestoreUsage() {
+	fmt.Printf(`Usage: %s restore [OPTIONS] <manifest>
+
+Moves the files of a quarantine manifest back to their original paths with their
+original mode and modification time. Files whose original path is taken again or
+whose content changed in quarantine are left where they are.
+
+OPTIONS:
+  -n, --dry-run      Show what would be restored without changing anything
+  -h, --help         Show this help message
+`, os.Args[0])
+}
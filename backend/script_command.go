@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/helpers"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/helpers/output"
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/selection"
+)
+
+// runScriptCommand writes a shell script that removes or links the redundant copies of a report.
+func runScriptCommand(args []string) int {
+	var reportPath string
+	var keepPolicy selection.Policy
+	action := output.ScriptRemove
+
+	for _, arg := range args {
+		if name, value, found := strings.Cut(arg, "="); found && strings.HasPrefix(name, "--") {
+			switch name {
+			case "--report":
+				reportPath = value
+			case "--keep":
+				rule, err := selection.ParseRule(value)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+					return 1
+				}
+				keepPolicy = append(keepPolicy, rule)
+			default:
+				fmt.Fprintf(os.Stderr, "Error: unknown option %s\n", name)
+				return 1
+			}
+			continue
+		}
+
+		switch arg {
+		case "--link":
+			action = output.ScriptHardlink
+		case "--symlink":
+			action = output.ScriptSymlink
+		case "--help", "-h":
+			printScriptUsage()
+			return 0
+		default:
+			fmt.Fprintf(os.Stderr, "Error: unexpected argument %s\n", arg)
+			return 1
+		}
+	}
+
+	if reportPath == "" {
+		printScriptUsage()
+		return 1
+	}
+
+	report, err := loadReport(reportPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+		return 1
+	}
+	if len(keepPolicy) > 0 {
+		helpers.MarkKeepers(&report, keepPolicy)
+	}
+
+	fmt.Print(output.ShellScript(report, action))
+	return 0
+}
+
+func printScriptUsage() {
+	fmt.Printf(`Usage: %[1]s script --report=FILE [OPTIONS] > remove-duplicates.sh
+
+Writes a POSIX shell script to review before anything is changed. For every file set of a
+report written with --json, it names the kept copy and has one command per other copy:
+the copies the report marks for removal (see --keep and mark), or every copy but the first
+of sets without marks. Each command first checks that the copy and the kept copy still
+have the size of the report and the same content (cmp), and skips the copy otherwise.
+Run the script with -n to see what it would do.
+
+OPTIONS:
+      --report=FILE  Report of the scan ("-" reads it from standard input)
+      --keep=RULE    Choose the kept copies with a keep policy instead of the report's
+                     marks (repeatable, see "%[1]s --help")
+      --link         Replace copies with hardlinks instead of removing them; copies whose
+                     permissions or owner differ from the kept copy are skipped
+      --symlink      Replace copies with symlinks to the kept copy
+  -h, --help         Show this help message
+`, os.Args[0])
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/maxthedon/fast-dupe-finder/pkg/fastdupefinder/actions"
+)
+
+// runVerifyAuditCommand checks the hash chain of the audit log written by the actions.
+func runVerifyAuditCommand(args []string) int {
+	var logPath, expected string
+	for _, arg := range args {
+		if name, value, found := strings.Cut(arg, "="); found && strings.HasPrefix(name, "--") {
+			switch name {
+			case "--log":
+				logPath = value
+			case "--expect":
+				expected = value
+			default:
+				fmt.Fprintf(os.Stderr, "Error: unknown option %s\n", name)
+				return 1
+			}
+			continue
+		}
+
+		switch arg {
+		case "--help", "-h":
+			printVerifyAuditUsage()
+			return 0
+		default:
+			fmt.Fprintf(os.Stderr, "Error: unexpected argument %s\n", arg)
+			return 1
+		}
+	}
+
+	if logPath == "" {
+		path, err := actions.AuditLogPathFromEnv()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+			return 1
+		}
+		logPath = path
+	}
+
+	count, last, err := actions.VerifyAuditLog(logPath, actions.AuditKeyFromEnv(), expected)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s: %s (%d entries verified)\n", logPath, err.Error(), count)
+		return 1
+	}
+	fmt.Printf("Verified %d entries of %s\n", count, logPath)
+	if count > 0 {
+		fmt.Printf("Last chain value: %s\n", last)
+	}
+	if len(actions.AuditKeyFromEnv()) == 0 {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", actions.UnkeyedAuditWarning)
+	}
+	return 0
+}
+
+func printVerifyAuditUsage() {
+	fmt.Printf(`Usage: %[1]s verify-audit [--log=FILE] [--expect=CHAIN]
+
+Checks the audit log in which delete, link, dedupe, quarantine and restore record every
+operation: time, user, operation, path, hash, size and outcome. Each operation is recorded
+as an attempt before it starts and again with its outcome. Each entry holds a hash of
+itself and of the entry before it, so editing, removing or reordering entries is detected.
+If FDF_AUDIT_KEY was set when the entries were written, the hashes are HMACs with that key
+and it must be set to verify them too. Without a key, anyone who can edit the log can also
+recompute its hashes, so a log that passes only shows it wasn't damaged by accident.
+
+Removing entries from the end keeps the chain intact: note the last chain value printed here
+and pass it with --expect later to check that the log only grew since.
+
+OPTIONS:
+      --log=FILE       Audit log (default: $FDF_AUDIT_LOG, or %[2]s)
+      --expect=CHAIN   Fail unless an entry of the log has this chain value
+  -h, --help           Show this help message
+`, os.Args[0], defaultAuditLogPath())
+}
+
+// defaultAuditLogPath returns the default log location for the usage text.
+func defaultAuditLogPath() string {
+	path, err := actions.DefaultAuditLogPath()
+	if err != nil {
+		return "unavailable, use --log"
+	}
+	return path
+}
@@ -43,6 +43,9 @@ typedef CancelScanCDart = void Function();
 typedef GetLastReportCNative = ffi.Pointer<ffi.Char> Function();
 typedef GetLastReportCDart = ffi.Pointer<ffi.Char> Function();
 
+typedef DeleteDuplicatesCNative = ffi.Pointer<ffi.Char> Function(ffi.Pointer<ffi.Char>, ffi.Int32, ffi.Int32);
+typedef DeleteDuplicatesCDart = ffi.Pointer<ffi.Char> Function(ffi.Pointer<ffi.Char>, int, int);
+
 class DuplicateFinderBindings {
   static DuplicateFinderBindings? _instance;
   late ffi.DynamicLibrary _dylib;
@@ -61,6 +64,7 @@ class DuplicateFinderBindings {
   late RemoveStatusCallbackCDart removeStatusCallback;
   late CancelScanCDart cancelScan;
   late GetLastReportCDart getLastReport;
+  late DeleteDuplicatesCDart deleteDuplicatesC;
 
   DuplicateFinderBindings._internal() {
     _loadLibrary();
@@ -128,6 +132,7 @@ class DuplicateFinderBindings {
     removeStatusCallback = _dylib.lookupFunction<RemoveStatusCallbackCNative, RemoveStatusCallbackCDart>('RemoveStatusCallbackC');
     cancelScan = _dylib.lookupFunction<CancelScanCNative, CancelScanCDart>('CancelScanC');
     getLastReport = _dylib.lookupFunction<GetLastReportCNative, GetLastReportCDart>('GetLastReportC');
+    deleteDuplicatesC = _dylib.lookupFunction<DeleteDuplicatesCNative, DeleteDuplicatesCDart>('DeleteDuplicatesC');
   }
 
   String _convertCString(ffi.Pointer<ffi.Char> ptr) {
@@ -234,4 +239,21 @@ class DuplicateFinderBindings {
     final reportPtr = getLastReport();
     return _convertCString(reportPtr);
   }
+
+  /// Removes copies from the last scan, always keeping at least one copy of every set.
+  Map<String, dynamic> deleteDuplicates(List<String> paths, {bool trash = false, bool dryRun = false}) {
+    final pathsPtr = jsonEncode(paths).toNativeUtf8().cast<ffi.Char>();
+    try {
+      final resultPtr = deleteDuplicatesC(pathsPtr, trash ? 1 : 0, dryRun ? 1 : 0);
+      final jsonResult = _convertCString(resultPtr);
+      if (jsonResult.isEmpty) {
+        return {'success': false, 'error': 'Empty result from delete'};
+      }
+      return jsonDecode(jsonResult);
+    } catch (e) {
+      return {'success': false, 'error': 'Delete failed: $e'};
+    } finally {
+      malloc.free(pathsPtr);
+    }
+  }
 }
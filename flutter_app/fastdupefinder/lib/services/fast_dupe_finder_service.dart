@@ -439,20 +439,24 @@ class FastDupeFinderService {
     }
   }
 
-  /// Delete files/folders 
+  /// Delete files/folders of the last scan; the library refuses to remove the last copy of a set
+  /// and copies that changed since the scan
   Future<bool> deleteItems(List<String> paths) async {
+    // An empty list would make the library remove the copies the report marks
+    if (paths.isEmpty) return true;
     try {
-      for (String path in paths) {
-        final fileEntity = File(path);
-        final dirEntity = Directory(path);
-        
-        if (await fileEntity.exists()) {
-          await fileEntity.delete();
-        } else if (await dirEntity.exists()) {
-          await dirEntity.delete(recursive: true);
+      final result = _bindings.deleteDuplicates(paths);
+      if (result['success'] != true) {
+        if (result['error'] != null) {
+          Logger.log('Error deleting items: ${result['error']}');
+        }
+        for (final outcome in (result['outcomes'] as List<dynamic>? ?? [])) {
+          if (outcome['error'] != null) {
+            Logger.log('Could not delete ${outcome['path']}: ${outcome['error']}');
+          }
         }
+        return false;
       }
-      
       return true;
     } catch (e) {
       Logger.log('Error deleting items: $e');